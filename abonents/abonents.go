@@ -0,0 +1,145 @@
+// Package abonents содержит операции с абонентами облачной АТС Билайн
+package abonents
+
+import (
+	"fmt"
+	"net/url"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Abonent структура для хранения информации об абоненте
+type Abonent struct {
+	UserId     string `json:"userId"`
+	Phone      string `json:"phone"`
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	Email      string `json:"email"`
+	Department string `json:"department"`
+	Extension  string `json:"extension"`
+}
+
+// Service Операции с абонентами
+type Service struct {
+	c *beelineapi.APIClient
+}
+
+// New Возвращает операции с абонентами для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{c: c}
+}
+
+// GetAbonents Возвращает список всех абонентов
+func (s *Service) GetAbonents() ([]Abonent, error) {
+	abonents := []Abonent{}
+	if err := s.c.RequestJSON("GET", "abonents", nil, &abonents); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при получении списка абонентов. ", err)
+	}
+	return abonents, nil
+}
+
+// GetAbonent Ищет абонента по идентификатору, мобильному или добавочному номеру
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) GetAbonent(id string) (Abonent, error) {
+	a := Abonent{}
+	if err := s.c.RequestJSON("GET", "abonents/"+url.PathEscape(id), nil, &a); err != nil {
+		return a, beelineapi.Wrap("Ошибка при поиске абонента. ", err)
+	}
+	return a, nil
+}
+
+// GetAgentStatus Возвращает статус агента call-центра
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) GetAgentStatus(id string) (beelineapi.AgentStatus, error) {
+	var status beelineapi.AgentStatus
+	if err := s.c.RequestJSON("GET", "abonents/"+url.PathEscape(id)+"/agent", nil, &status); err != nil {
+		return 0, beelineapi.Wrap("Ошибка при получении статуса агента. ", err)
+	}
+	return status, nil
+}
+
+// SetAgentStatus Устанавливает статус агента call-центра
+// id - Идентификатор, мобильный или добавочный номер абонента
+// newStatus - Новый статус агента: ONLINE, OFFLINE или BREAK
+func (s *Service) SetAgentStatus(id string, newStatus beelineapi.AgentStatus) error {
+	if !newStatus.Valid() {
+		return beelineapi.WrapError{Msg: fmt.Sprintf("Недопустимый статус агента: %d", newStatus)}
+	}
+	path := fmt.Sprintf("abonents/%s/agent?status=%s", url.PathEscape(id), newStatus)
+	if _, err := s.c.Request("PUT", path, nil); err != nil {
+		return beelineapi.Wrap("Ошибка при установке статуса агента. ", err)
+	}
+	return nil
+}
+
+// GetRecordingStatus Возвращает статус записи разговоров для абонента: ON или OFF
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) GetRecordingStatus(id string) (beelineapi.ServiceStatus, error) {
+	var status beelineapi.ServiceStatus
+	if err := s.c.RequestJSON("GET", "abonents/"+url.PathEscape(id)+"/recording", nil, &status); err != nil {
+		return 0, beelineapi.Wrap("Ошибка при получении статуса записи разговоров. ", err)
+	}
+	return status, nil
+}
+
+// TurnOnRecording Включает запись разговоров для абонента
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOnRecording(id string) error {
+	if _, err := s.c.Request("PUT", "abonents/"+url.PathEscape(id)+"/recording", nil); err != nil {
+		return beelineapi.Wrap("Ошибка при включении записи разговоров. ", err)
+	}
+	return nil
+}
+
+// TurnOffRecording Отключает запись разговоров для абонента
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOffRecording(id string) error {
+	if _, err := s.c.Request("DELETE", "abonents/"+url.PathEscape(id)+"/recording", nil); err != nil {
+		return beelineapi.Wrap("Ошибка при отключении записи разговоров. ", err)
+	}
+	return nil
+}
+
+// DoCall Совершает звонок от имени абонента и возвращает идентификатор звонка
+// id - Идентификатор, мобильный или добавочный номер абонента
+// telNumber -Номер телефона - 10 цифр или номер в российском формате, см. beelineapi.ParsePhone
+func (s *Service) DoCall(id string, telNumber string) (string, error) {
+	var callId string
+	phone, err := beelineapi.NormalizePhone(telNumber)
+	if err != nil {
+		return "", beelineapi.Wrap("Ошибка при совершении звонка. ", err)
+	}
+	path := fmt.Sprintf("abonents/%s/call?phoneNumber=%s", url.PathEscape(id), phone)
+	if err := s.c.RequestJSON("POST", path, nil, &callId); err != nil {
+		return "", beelineapi.Wrap("Ошибка при совершении звонка. ", err)
+	}
+	return callId, nil
+}
+
+// TurnOnNumberToAbonent Подключает дополнительный номер абоненту
+// id - Идентификатор, мобильный или добавочный номер абонента
+// telNumber -Подключаемый номер телефона - 10 цифр или номер в российском формате, см. beelineapi.ParsePhone
+// schedule - Расписание перенаправления на номер
+func (s *Service) TurnOnNumberToAbonent(id string, telNumber string, schedule beelineapi.Schedule) error {
+	if !schedule.Valid() {
+		return beelineapi.WrapError{Msg: fmt.Sprintf("Недопустимое расписание: %s", schedule)}
+	}
+	phone, err := beelineapi.NormalizePhone(telNumber)
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при подключении дополнительного номера. ", err)
+	}
+	path := fmt.Sprintf("abonents/%s/number?phoneNumber=%s&schedule=%s", url.PathEscape(id), phone, schedule)
+	if _, err := s.c.Request("PUT", path, nil); err != nil {
+		return beelineapi.Wrap("Ошибка при подключении дополнительного номера. ", err)
+	}
+	return nil
+}
+
+// TurnOffNumberToAbonent Отключает дополнительный номер абонента
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOffNumberToAbonent(id string) error {
+	if _, err := s.c.Request("DELETE", "abonents/"+url.PathEscape(id)+"/number", nil); err != nil {
+		return beelineapi.Wrap("Ошибка при отключении дополнительного номера. ", err)
+	}
+	return nil
+}
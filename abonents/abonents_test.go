@@ -0,0 +1,109 @@
+package abonents_test
+
+import (
+	"errors"
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+)
+
+// TestAbonents Тест на операции с абонентами
+func TestAbonents(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Phone: "9000000001", Extension: "101", Department: "Продажи"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := abonents.New(client)
+
+	list, err := svc.GetAbonents()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("Не удалось получить список абонентов: %v %+v", err, list)
+	}
+	a, err := svc.GetAbonent("101")
+	if err != nil || a.UserId != "u1" {
+		t.Fatalf("Не удалось найти абонента по добавочному номеру: %v %+v", err, a)
+	}
+	if err := svc.SetAgentStatus("u1", beelineapi.BREAK); err != nil {
+		t.Fatalf("Не удалось установить статус агента: %s", err)
+	}
+	if status, err := svc.GetAgentStatus("u1"); err != nil || status != beelineapi.BREAK {
+		t.Fatalf("Неверный статус агента. Ожидалось %d получено %d %v", beelineapi.BREAK, status, err)
+	}
+	if err := svc.TurnOnRecording("9000000001"); err != nil {
+		t.Fatalf("Не удалось включить запись разговоров: %s", err)
+	}
+	if status, err := svc.GetRecordingStatus("u1"); err != nil || status != beelineapi.ON {
+		t.Fatalf("Запись разговоров не включена: %d %v", status, err)
+	}
+	callId, err := svc.DoCall("u1", "9000000002")
+	if err != nil || callId == "" {
+		t.Fatalf("Не удалось совершить звонок: %v", err)
+	}
+	if calls := s.Calls(); len(calls) != 1 || calls[0].Phone != "9000000002" {
+		t.Fatalf("Звонок не зарегистрирован: %+v", calls)
+	}
+	if _, err := svc.GetAbonent("unknown"); !beelineapi.IsNotFound(err) {
+		t.Fatalf("Ожидалась ошибка 404, получено %v", err)
+	}
+}
+
+// TestDoCallPhone Тест на приведение номера телефона к формату API перед звонком
+func TestDoCallPhone(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := abonents.New(client)
+
+	if _, err := svc.DoCall("u1", "+7 (912) 345-67-89"); err != nil {
+		t.Fatalf("Не удалось совершить звонок: %s", err)
+	}
+	if calls := s.Calls(); len(calls) != 1 || calls[0].Phone != "9123456789" {
+		t.Fatalf("Номер не приведен к формату API: %+v", calls)
+	}
+	_, err = svc.DoCall("u1", "12-34")
+	var pe beelineapi.PhoneError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Ожидалась ошибка PhoneError, получено %v", err)
+	}
+	if len(s.Calls()) != 1 {
+		t.Fatal("Запрос с неверным номером не должен отправляться на сервер")
+	}
+}
+
+// TestEndpoints Тест на успешный ответ и ошибку сервера для каждой операции с абонентами
+func TestEndpoints(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Phone: "9000000001"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := abonents.New(client)
+
+	if err := s.CheckEndpoints([]beelinetest.Endpoint{
+		{Name: "GetAbonents", Method: "GET", Path: "/abonents", Call: func() error { _, err := svc.GetAbonents(); return err }},
+		{Name: "GetAbonent", Method: "GET", Path: "/abonents/u1", Call: func() error { _, err := svc.GetAbonent("u1"); return err }},
+		{Name: "GetAgentStatus", Method: "GET", Path: "/abonents/u1/agent", Call: func() error { _, err := svc.GetAgentStatus("u1"); return err }},
+		{Name: "SetAgentStatus", Method: "PUT", Path: "/abonents/u1/agent", Call: func() error { return svc.SetAgentStatus("u1", beelineapi.ONLINE) }},
+		{Name: "GetRecordingStatus", Method: "GET", Path: "/abonents/u1/recording", Call: func() error { _, err := svc.GetRecordingStatus("u1"); return err }},
+		{Name: "TurnOnRecording", Method: "PUT", Path: "/abonents/u1/recording", Call: func() error { return svc.TurnOnRecording("u1") }},
+		{Name: "TurnOffRecording", Method: "DELETE", Path: "/abonents/u1/recording", Call: func() error { return svc.TurnOffRecording("u1") }},
+		{Name: "DoCall", Method: "POST", Path: "/abonents/u1/call", Call: func() error { _, err := svc.DoCall("u1", "9000000002"); return err }},
+		{Name: "TurnOnNumberToAbonent", Method: "PUT", Path: "/abonents/u1/number", Call: func() error {
+			return svc.TurnOnNumberToAbonent("u1", "9000000003", beelineapi.ROUND_THE_CLOCK)
+		}},
+		{Name: "TurnOffNumberToAbonent", Method: "DELETE", Path: "/abonents/u1/number", Call: func() error { return svc.TurnOffNumberToAbonent("u1") }},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,201 @@
+package abonents
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// AbonentNotFoundError Абонент не найден в справочнике
+type AbonentNotFoundError struct {
+	Key string // Ключ поиска
+}
+
+func (e AbonentNotFoundError) Error() string {
+	return "Абонент " + e.Key + " не найден"
+}
+
+// Resolver Кэш справочника абонентов с поиском по идентификатору, мобильному и добавочному номеру,
+// email и отделу. Справочник загружается через GetAbonents и обновляется после истечения TTL.
+// Resolver безопасен для одновременного использования: загрузка выполняется без блокировки поиска,
+// а одновременные обращения к устаревшему справочнику ожидают одну общую загрузку.
+type Resolver struct {
+	s   *Service
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	dir     *directory // Загруженный справочник, nil - не загружен
+	loading *load      // Выполняющаяся загрузка, nil - загрузки нет
+}
+
+// directory Загруженный справочник с индексами. После построения не изменяется.
+type directory struct {
+	loaded   time.Time
+	list     []Abonent
+	byUserId map[string]int
+	byPhone  map[string]int
+	byExt    map[string]int
+	byEmail  map[string]int
+	byDept   map[string][]int
+}
+
+// load Загрузка справочника, результат которой получают все ожидающие ее обращения
+type load struct {
+	done chan struct{}
+	dir  *directory
+	err  error
+}
+
+// NewResolver Возвращает кэш справочника абонентов
+// s - Операции с абонентами
+// ttl - Время жизни кэша, 0 - справочник загружается один раз
+func NewResolver(s *Service, ttl time.Duration) *Resolver {
+	return &Resolver{s: s, ttl: ttl, now: time.Now}
+}
+
+// Refresh Загружает справочник абонентов заново
+func (r *Resolver) Refresh() error {
+	_, err := r.get(true)
+	return err
+}
+
+// Invalidate Сбрасывает кэш, справочник будет загружен при следующем обращении
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	r.dir = nil
+	r.mu.Unlock()
+}
+
+// Resolve Ищет абонента по идентификатору, добавочному или мобильному номеру в любом формате или email
+// key - Ключ поиска
+func (r *Resolver) Resolve(key string) (Abonent, error) {
+	d, err := r.get(false)
+	if err != nil {
+		return Abonent{}, err
+	}
+	key = strings.TrimSpace(key)
+	for _, idx := range []map[string]int{d.byUserId, d.byExt} {
+		if i, ok := idx[key]; ok {
+			return d.list[i], nil
+		}
+	}
+	if p, err := beelineapi.ParsePhone(key); err == nil {
+		if i, ok := d.byPhone[string(p)]; ok {
+			return d.list[i], nil
+		}
+	}
+	if i, ok := d.byEmail[strings.ToLower(key)]; ok {
+		return d.list[i], nil
+	}
+	return Abonent{}, AbonentNotFoundError{Key: key}
+}
+
+// UserId Возвращает идентификатор абонента по любому ключу поиска Resolve.
+// Результат можно передавать в операции, принимающие идентификатор абонента.
+func (r *Resolver) UserId(key string) (string, error) {
+	a, err := r.Resolve(key)
+	return a.UserId, err
+}
+
+// Department Возвращает абонентов отдела по названию без учета регистра
+// department - Название отдела
+func (r *Resolver) Department(department string) ([]Abonent, error) {
+	d, err := r.get(false)
+	if err != nil {
+		return nil, err
+	}
+	res := []Abonent{}
+	for _, i := range d.byDept[strings.ToLower(strings.TrimSpace(department))] {
+		res = append(res, d.list[i])
+	}
+	return res, nil
+}
+
+// Departments Возвращает названия всех отделов по алфавиту
+func (r *Resolver) Departments() ([]string, error) {
+	d, err := r.get(false)
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, idx := range d.byDept {
+		res = append(res, d.list[idx[0]].Department)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// All Возвращает всех абонентов справочника
+func (r *Resolver) All() ([]Abonent, error) {
+	d, err := r.get(false)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Abonent(nil), d.list...), nil
+}
+
+// get Возвращает справочник, загружая его, если он не загружен, устарел или force.
+// Если загрузка уже выполняется, ожидает ее результат вместо нового запроса.
+func (r *Resolver) get(force bool) (*directory, error) {
+	r.mu.Lock()
+	if d := r.dir; !force && d != nil && (r.ttl <= 0 || r.now().Sub(d.loaded) < r.ttl) {
+		r.mu.Unlock()
+		return d, nil
+	}
+	if l := r.loading; l != nil {
+		r.mu.Unlock()
+		<-l.done
+		return l.dir, l.err
+	}
+	l := &load{done: make(chan struct{})}
+	r.loading = l
+	r.mu.Unlock()
+
+	list, err := r.s.GetAbonents()
+	if err == nil {
+		l.dir = newDirectory(list, r.now())
+	}
+	l.err = err
+	r.mu.Lock()
+	if err == nil {
+		r.dir = l.dir
+	}
+	r.loading = nil
+	r.mu.Unlock()
+	close(l.done)
+	return l.dir, l.err
+}
+
+// newDirectory Строит индексы справочника. Пустой список тоже считается загруженным справочником.
+func newDirectory(list []Abonent, loaded time.Time) *directory {
+	d := &directory{
+		loaded:   loaded,
+		list:     list,
+		byUserId: map[string]int{},
+		byPhone:  map[string]int{},
+		byExt:    map[string]int{},
+		byEmail:  map[string]int{},
+		byDept:   map[string][]int{},
+	}
+	for i, a := range list {
+		d.byUserId[a.UserId] = i
+		if a.Extension != "" {
+			d.byExt[a.Extension] = i
+		}
+		if p, err := beelineapi.ParsePhone(a.Phone); err == nil {
+			d.byPhone[string(p)] = i
+		}
+		if a.Email != "" {
+			d.byEmail[strings.ToLower(a.Email)] = i
+		}
+		if a.Department != "" {
+			dep := strings.ToLower(a.Department)
+			d.byDept[dep] = append(d.byDept[dep], i)
+		}
+	}
+	return d
+}
@@ -0,0 +1,89 @@
+package abonents
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// TestResolver Тест на поиск абонентов в кэше справочника и обновление по TTL
+func TestResolver(t *testing.T) {
+	requests := 0
+	body := `[{"userId":"u1","phone":"9000000001","extension":"101","email":"Ivanov@example.com","department":"Продажи"},
+		{"userId":"u2","phone":"9000000002","extension":"102","department":"продажи"}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	c, err := beelineapi.NewClient("token", beelineapi.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewResolver(New(c), time.Minute)
+	r.now = func() time.Time { return now }
+
+	for key, want := range map[string]string{"u1": "u1", "102": "u2", "+7 (900) 000-00-01": "u1", "ivanov@EXAMPLE.com": "u1"} {
+		if id, err := r.UserId(key); err != nil || id != want {
+			t.Fatalf("Поиск по %q: ожидалось %s получено %q %v", key, want, id, err)
+		}
+	}
+	if _, err := r.Resolve("999"); !errors.As(err, &AbonentNotFoundError{}) {
+		t.Fatalf("Ожидалась ошибка AbonentNotFoundError, получено %v", err)
+	}
+	if list, _ := r.Department("ПРОДАЖИ"); len(list) != 2 {
+		t.Fatalf("Неверный список абонентов отдела: %+v", list)
+	}
+	if requests != 1 {
+		t.Fatalf("Справочник должен загружаться один раз, загружен %d", requests)
+	}
+	now = now.Add(2 * time.Minute)
+	r.Resolve("u1")
+	if requests != 2 {
+		t.Fatal("Справочник не обновлен после истечения TTL")
+	}
+}
+
+// TestResolverLoad Тест на одну загрузку пустого справочника при одновременных обращениях
+func TestResolverLoad(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	c, err := beelineapi.NewClient("token", beelineapi.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	r := NewResolver(New(c), 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if list, err := r.All(); err != nil || len(list) != 0 {
+				t.Errorf("Неверный справочник: %+v %v", list, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if _, err := r.Resolve("u1"); !errors.As(err, &AbonentNotFoundError{}) {
+		t.Fatalf("Ожидалась ошибка AbonentNotFoundError, получено %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Справочник должен загружаться один раз, загружен %d", n)
+	}
+}
@@ -0,0 +1,264 @@
+// Package analytics содержит сводную статистику по записям разговоров облачной АТС Билайн:
+// количество входящих и исходящих звонков, средняя длительность и ее процентили, загрузка по часам.
+// Записи могут поступать из records.Service.ForEachRecord или из архива в формате JSON (см. ReadJSON).
+// Отчет группируется по абоненту, отделу, направлению вызова и интервалу времени
+// и выгружается в CSV или JSON.
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// Dimension Признак группировки записей в отчете
+type Dimension int
+
+const (
+	ABONENT    Dimension = iota // Абонент: идентификатор, или мобильный номер, если идентификатор не указан
+	DEPARTMENT                  // Отдел абонента
+	DIRECTION                   // Направление вызова: INBOUND или OUTBOUND
+	HOUR                        // Час суток 00-23
+	WEEKDAY                     // День недели 1-7, начиная с понедельника
+	DAY                         // Дата в формате 2006-01-02
+	MONTH                       // Месяц в формате 2006-01
+)
+
+var dimensionNames = []string{"abonent", "department", "direction", "hour", "weekday", "day", "month"}
+
+// String Возвращает имя признака группировки, например abonent
+func (d Dimension) String() string {
+	if d < 0 || int(d) >= len(dimensionNames) {
+		return "Dimension(" + strconv.Itoa(int(d)) + ")"
+	}
+	return dimensionNames[d]
+}
+
+// MarshalText Признак группировки передается в JSON по имени
+func (d Dimension) MarshalText() ([]byte, error) {
+	if d < 0 || int(d) >= len(dimensionNames) {
+		return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Недопустимый признак группировки: %d", int(d))}
+	}
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText Разбирает признак группировки по имени
+func (d *Dimension) UnmarshalText(b []byte) error {
+	v, err := ParseDimension(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// ParseDimension Возвращает признак группировки по имени без учета регистра
+func ParseDimension(s string) (Dimension, error) {
+	for i, n := range dimensionNames {
+		if strings.EqualFold(n, s) {
+			return Dimension(i), nil
+		}
+	}
+	return 0, beelineapi.WrapError{Msg: fmt.Sprintf("Неизвестный признак группировки %q. Допустимые значения: %s", s, strings.Join(dimensionNames, ", "))}
+}
+
+// ParseDimensions Разбирает список признаков группировки через запятую, например "abonent,direction"
+func ParseDimensions(s string) ([]Dimension, error) {
+	res := []Dimension{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		d, err := ParseDimension(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// Stats Статистика группы записей. Длительности в миллисекундах, как в records.CallRecord
+type Stats struct {
+	Calls          int `json:"calls"`          // Количество звонков
+	Inbound        int `json:"inbound"`        // Количество входящих звонков
+	Outbound       int `json:"outbound"`       // Количество исходящих звонков. Звонки без направления не входят ни во входящие, ни в исходящие
+	TotalDuration  int `json:"totalDuration"`  // Суммарная длительность
+	AvgDuration    int `json:"avgDuration"`    // Средняя длительность
+	MedianDuration int `json:"medianDuration"` // Медиана длительности
+	P90Duration    int `json:"p90Duration"`    // 90-й процентиль длительности
+	MaxDuration    int `json:"maxDuration"`    // Максимальная длительность
+}
+
+// Row Строка отчета: значения признаков группировки в порядке Report.GroupBy и статистика группы
+type Row struct {
+	Group []string `json:"group"`
+	Stats
+}
+
+// Report Отчет по записям разговоров
+type Report struct {
+	GroupBy []Dimension `json:"groupBy"` // Признаки группировки
+	Total   Stats       `json:"total"`   // Статистика по всем записям
+	Rows    []Row       `json:"rows"`    // Строки, упорядоченные по значениям признаков
+}
+
+// Top Возвращает не более n строк с наибольшим количеством звонков,
+// например самые загруженные часы для отчета по HOUR
+func (r Report) Top(n int) []Row {
+	rows := append([]Row(nil), r.Rows...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Calls > rows[j].Calls })
+	if n >= 0 && n < len(rows) {
+		rows = rows[:n]
+	}
+	return rows
+}
+
+// Aggregator Собирает статистику по записям разговоров. Создается функцией New.
+// Метод Add можно передать в records.Service.ForEachRecord или ReadJSON.
+type Aggregator struct {
+	Location *time.Location // Часовой пояс для группировки по времени, по умолчанию time.Local
+
+	groupBy []Dimension
+	total   []int
+	groups  map[string]*group
+}
+
+// group Записи одной группы
+type group struct {
+	keys      []string
+	durations []int
+	inbound   int
+	outbound  int
+}
+
+// New Возвращает сборщик статистики с группировкой по признакам groupBy.
+// Без признаков отчет содержит только общую статистику
+func New(groupBy ...Dimension) *Aggregator {
+	return &Aggregator{groupBy: groupBy, groups: map[string]*group{}}
+}
+
+// Add Добавляет запись в статистику. Возвращает ошибку для недопустимого признака группировки,
+// поэтому может использоваться как функция обхода записей
+func (a *Aggregator) Add(r records.CallRecord) error {
+	keys := make([]string, len(a.groupBy))
+	for i, d := range a.groupBy {
+		k, err := a.key(d, r)
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+	}
+	id := strings.Join(keys, "\x00")
+	g, ok := a.groups[id]
+	if !ok {
+		g = &group{keys: keys}
+		a.groups[id] = g
+	}
+	g.durations = append(g.durations, r.Duration)
+	a.total = append(a.total, r.Duration)
+	switch r.Direction {
+	case beelineapi.INBOUND:
+		g.inbound++
+	case beelineapi.OUTBOUND:
+		g.outbound++
+	}
+	return nil
+}
+
+// Report Возвращает отчет по добавленным записям
+func (a *Aggregator) Report() Report {
+	rep := Report{GroupBy: a.groupBy, Rows: []Row{}}
+	inbound, outbound := 0, 0
+	for _, g := range a.groups {
+		rep.Rows = append(rep.Rows, Row{Group: g.keys, Stats: stats(g.durations, g.inbound, g.outbound)})
+		inbound += g.inbound
+		outbound += g.outbound
+	}
+	rep.Total = stats(a.total, inbound, outbound)
+	sort.Slice(rep.Rows, func(i, j int) bool {
+		ki, kj := rep.Rows[i].Group, rep.Rows[j].Group
+		for n := range ki {
+			if ki[n] != kj[n] {
+				return ki[n] < kj[n]
+			}
+		}
+		return false
+	})
+	return rep
+}
+
+// Aggregate Возвращает отчет по записям recs с группировкой по признакам groupBy
+func Aggregate(recs []records.CallRecord, groupBy ...Dimension) (Report, error) {
+	a := New(groupBy...)
+	for _, r := range recs {
+		if err := a.Add(r); err != nil {
+			return Report{}, err
+		}
+	}
+	return a.Report(), nil
+}
+
+// key Возвращает значение признака d для записи r
+func (a *Aggregator) key(d Dimension, r records.CallRecord) (string, error) {
+	loc := a.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t := r.Date.In(loc)
+	switch d {
+	case ABONENT:
+		if r.Abonent.UserId != "" {
+			return r.Abonent.UserId, nil
+		}
+		return r.Phone, nil
+	case DEPARTMENT:
+		return r.Abonent.Department, nil
+	case DIRECTION:
+		if !r.Direction.Valid() {
+			return "", nil
+		}
+		return r.Direction.String(), nil
+	case HOUR:
+		return fmt.Sprintf("%02d", t.Hour()), nil
+	case WEEKDAY:
+		return strconv.Itoa((int(t.Weekday())+6)%7 + 1), nil
+	case DAY:
+		return t.Format("2006-01-02"), nil
+	case MONTH:
+		return t.Format("2006-01"), nil
+	}
+	return "", beelineapi.WrapError{Msg: fmt.Sprintf("Недопустимый признак группировки: %d", int(d))}
+}
+
+// stats Считает статистику по длительностям звонков, из которых inbound входящих и outbound исходящих
+func stats(durations []int, inbound, outbound int) Stats {
+	s := Stats{Calls: len(durations), Inbound: inbound, Outbound: outbound}
+	if len(durations) == 0 {
+		return s
+	}
+	sorted := append([]int(nil), durations...)
+	sort.Ints(sorted)
+	for _, d := range sorted {
+		s.TotalDuration += d
+	}
+	s.AvgDuration = s.TotalDuration / len(sorted)
+	s.MedianDuration = percentile(sorted, 50)
+	s.P90Duration = percentile(sorted, 90)
+	s.MaxDuration = sorted[len(sorted)-1]
+	return s
+}
+
+// percentile Возвращает процентиль p упорядоченного списка методом ближайшего ранга
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
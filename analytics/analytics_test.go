@@ -0,0 +1,136 @@
+package analytics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/analytics"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// record Возвращает запись разговора абонента userId отдела dept в час hour по UTC длительностью sec секунд
+func record(userId string, dept string, dir beelineapi.Direction, hour int, sec int) records.CallRecord {
+	return records.CallRecord{
+		Direction: dir,
+		Date:      beelineapi.UnixMilli{Time: time.Date(2024, 3, 4, hour, 15, 0, 0, time.UTC)},
+		Duration:  sec * 1000,
+		Abonent:   abonents.Abonent{UserId: userId, Department: dept},
+	}
+}
+
+var recs = []records.CallRecord{
+	record("u1", "Продажи", beelineapi.INBOUND, 9, 10),
+	record("u1", "Продажи", beelineapi.OUTBOUND, 9, 20),
+	record("u1", "Продажи", beelineapi.INBOUND, 10, 30),
+	record("u2", "Поддержка", beelineapi.INBOUND, 10, 40),
+	record("u2", "Поддержка", beelineapi.INBOUND, 10, 100),
+}
+
+// TestAggregate Тест на группировку записей и расчет статистики
+func TestAggregate(t *testing.T) {
+	rep, err := analytics.Aggregate(recs, analytics.ABONENT)
+	if err != nil {
+		t.Fatalf("Не удалось построить отчет: %s", err)
+	}
+	want := []analytics.Row{
+		{Group: []string{"u1"}, Stats: analytics.Stats{Calls: 3, Inbound: 2, Outbound: 1, TotalDuration: 60000, AvgDuration: 20000, MedianDuration: 20000, P90Duration: 30000, MaxDuration: 30000}},
+		{Group: []string{"u2"}, Stats: analytics.Stats{Calls: 2, Inbound: 2, TotalDuration: 140000, AvgDuration: 70000, MedianDuration: 40000, P90Duration: 100000, MaxDuration: 100000}},
+	}
+	if len(rep.Rows) != len(want) {
+		t.Fatalf("Неверное количество строк отчета: %+v", rep.Rows)
+	}
+	for i, row := range rep.Rows {
+		if row.Group[0] != want[i].Group[0] || row.Stats != want[i].Stats {
+			t.Fatalf("Неверная строка отчета %d. Ожидалось %+v получено %+v", i, want[i], row)
+		}
+	}
+	if rep.Total.Calls != 5 || rep.Total.Inbound != 4 || rep.Total.MaxDuration != 100000 {
+		t.Fatalf("Неверная общая статистика: %+v", rep.Total)
+	}
+}
+
+// TestUnknownDirection Тест на учет записей без направления вызова
+func TestUnknownDirection(t *testing.T) {
+	rep, err := analytics.Aggregate([]records.CallRecord{{Id: "x", Duration: 1000}}, analytics.DIRECTION)
+	if err != nil {
+		t.Fatalf("Не удалось построить отчет: %s", err)
+	}
+	if rep.Total.Calls != 1 || rep.Total.Inbound != 0 || rep.Total.Outbound != 0 || rep.Rows[0].Group[0] != "" {
+		t.Fatalf("Запись без направления не должна считаться входящей или исходящей: %+v", rep)
+	}
+}
+
+// TestTimeBuckets Тест на группировку по времени с учетом часового пояса
+func TestTimeBuckets(t *testing.T) {
+	a := analytics.New(analytics.HOUR)
+	a.Location = time.FixedZone("MSK", 3*60*60)
+	for _, r := range recs {
+		a.Add(r)
+	}
+	top := a.Report().Top(1)
+	if len(top) != 1 || top[0].Group[0] != "13" || top[0].Calls != 3 {
+		t.Fatalf("Неверный самый загруженный час: %+v", top)
+	}
+	dims, err := analytics.ParseDimensions("department, weekday")
+	if err != nil || len(dims) != 2 || dims[0] != analytics.DEPARTMENT || dims[1] != analytics.WEEKDAY {
+		t.Fatalf("Неверно разобраны признаки группировки: %v %v", dims, err)
+	}
+	rep, _ := analytics.Aggregate(recs, dims...)
+	if rep.Rows[0].Group[0] != "Поддержка" || rep.Rows[0].Group[1] != "1" {
+		t.Fatalf("Неверная группировка по отделу и дню недели: %+v", rep.Rows)
+	}
+	if _, err := analytics.ParseDimension("year"); err == nil {
+		t.Fatalf("Ожидалась ошибка для неизвестного признака группировки")
+	}
+}
+
+// TestExport Тест на выгрузку отчета в CSV и JSON
+func TestExport(t *testing.T) {
+	rep, _ := analytics.Aggregate(recs, analytics.DIRECTION)
+	var out bytes.Buffer
+	if err := rep.WriteCSV(&out); err != nil {
+		t.Fatalf("Не удалось выгрузить отчет в CSV: %s", err)
+	}
+	want := "direction,calls,inbound,outbound,totalDuration,avgDuration,medianDuration,p90Duration,maxDuration\n" +
+		"INBOUND,4,4,0,180000,45000,30000,100000,100000\n" +
+		"OUTBOUND,1,0,1,20000,20000,20000,20000,20000\n" +
+		",5,4,1,200000,40000,30000,100000,100000\n"
+	if out.String() != want {
+		t.Fatalf("Неверный CSV. Ожидалось:\n%s\nполучено:\n%s", want, out.String())
+	}
+	out.Reset()
+	if err := rep.WriteJSON(&out); err != nil {
+		t.Fatalf("Не удалось выгрузить отчет в JSON: %s", err)
+	}
+	if !strings.Contains(out.String(), `"groupBy": [`+"\n"+`    "direction"`) || !strings.Contains(out.String(), `"p90Duration": 100000`) {
+		t.Fatalf("Неверный JSON:\n%s", out.String())
+	}
+}
+
+// TestReadJSON Тест на чтение архива записей в виде массива и последовательности объектов JSON
+func TestReadJSON(t *testing.T) {
+	archives := map[string]string{
+		"array": `[{"id":"1","direction":"INBOUND","date":1500000000000,"duration":1000},
+			{"id":"2","direction":"OUTBOUND","date":1500000000000,"duration":2000}]`,
+		"lines": "{\"id\":\"1\",\"direction\":\"INBOUND\",\"date\":1500000000000,\"duration\":1000}\n" +
+			"{\"id\":\"2\",\"direction\":\"OUTBOUND\",\"date\":1500000000000,\"duration\":2000}\n",
+	}
+	for name, data := range archives {
+		t.Run(name, func(t *testing.T) {
+			a := analytics.New()
+			if err := analytics.ReadJSON(strings.NewReader(data), a.Add); err != nil {
+				t.Fatalf("Не удалось прочитать архив: %s", err)
+			}
+			if total := a.Report().Total; total.Calls != 2 || total.Outbound != 1 || total.TotalDuration != 3000 {
+				t.Fatalf("Неверная статистика архива: %+v", total)
+			}
+		})
+	}
+	if err := analytics.ReadJSON(strings.NewReader(`[{"id":`), analytics.New().Add); err == nil {
+		t.Fatalf("Ожидалась ошибка для неверного архива")
+	}
+}
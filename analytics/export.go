@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// statsColumns Столбцы статистики в CSV
+var statsColumns = []string{"calls", "inbound", "outbound", "totalDuration", "avgDuration", "medianDuration", "p90Duration", "maxDuration"}
+
+// WriteCSV Записывает отчет в формате CSV: столбцы признаков группировки, затем столбцы статистики.
+// Последняя строка с пустыми значениями признаков содержит статистику по всем записям
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{}
+	for _, d := range r.GroupBy {
+		header = append(header, d.String())
+	}
+	if err := cw.Write(append(header, statsColumns...)); err != nil {
+		return beelineapi.Wrap("Ошибка при записи отчета в CSV. ", err)
+	}
+	for _, row := range r.Rows {
+		if err := cw.Write(append(append([]string{}, row.Group...), row.Stats.values()...)); err != nil {
+			return beelineapi.Wrap("Ошибка при записи отчета в CSV. ", err)
+		}
+	}
+	if len(r.GroupBy) > 0 {
+		if err := cw.Write(append(make([]string, len(r.GroupBy)), r.Total.values()...)); err != nil {
+			return beelineapi.Wrap("Ошибка при записи отчета в CSV. ", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return beelineapi.Wrap("Ошибка при записи отчета в CSV. ", err)
+	}
+	return nil
+}
+
+// WriteJSON Записывает отчет в формате JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return beelineapi.Wrap("Ошибка при записи отчета в JSON. ", err)
+	}
+	return nil
+}
+
+// values Возвращает значения статистики в порядке statsColumns
+func (s Stats) values() []string {
+	vals := []int{s.Calls, s.Inbound, s.Outbound, s.TotalDuration, s.AvgDuration, s.MedianDuration, s.P90Duration, s.MaxDuration}
+	res := make([]string, len(vals))
+	for i, v := range vals {
+		res[i] = strconv.Itoa(v)
+	}
+	return res
+}
+
+// ReadJSON Читает архив записей разговоров и вызывает fn для каждой записи.
+// Архив может быть массивом JSON, как ответ GetRecords, или последовательностью объектов JSON,
+// например по одному на строку. Чтение прекращается при первой ошибке fn
+func ReadJSON(r io.Reader, fn func(records.CallRecord) error) error {
+	br := bufio.NewReader(r)
+	array, err := startsWithArray(br)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(br)
+	if array {
+		// Открывающая скобка массива
+		if _, err := dec.Token(); err != nil {
+			return beelineapi.Wrap("Ошибка при чтении архива записей. ", err)
+		}
+	}
+	for dec.More() {
+		var rec records.CallRecord
+		if err := dec.Decode(&rec); err != nil {
+			return beelineapi.Wrap("Ошибка при чтении архива записей. ", err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startsWithArray Проверяет, что первый непробельный символ потока - начало массива JSON
+func startsWithArray(br *bufio.Reader) (bool, error) {
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, beelineapi.Wrap("Ошибка при чтении архива записей. ", err)
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '[', br.UnreadByte()
+	}
+}
@@ -1,194 +1,114 @@
+// Package beelineapi содержит общее ядро клиента API портала облачной АТС Билайн:
+// настройки клиента, отправку запросов, ошибки и общие типы.
+// Операции API разделены по подпакетам:
+//
+//	abonents   - операции с абонентами
+//	forwarding - простая и выборочная переадресация вызовов
+//	bwl        - выборочный прием звонков
+//	records    - операции с записями разговоров
+//	icr        - входящие номера и индивидуальная переадресация
+//	xsi        - подписка на Xsi-Events
+//
+// Пакет reconcile приводит настройки абонентов к конфигурации в YAML или JSON,
+// пакет bulk выполняет операции над всеми абонентами отдела, пакет pool содержит пул клиентов API
+// для многих клиентов облачной АТС, пакет analytics считает статистику по записям разговоров,
+// пакет retention удаляет устаревшие записи разговоров по политике хранения,
+// а команда cmd/beeline позволяет выполнять операции API из командной строки.
 package beelineapi
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
-	"strconv"
 )
 
-const (
-	// Статусы агента call-центра
-	ONLINE  = 0
-	OFFLINE = 1
-	BREAK   = 2
-	// CONTENTTYPE Тип ответа
-	CONTENTTYPE string = "application/json"
-	// Статус записи разговоров для абонента
-	OFF = 0
-	ON  = 1
-)
+// CONTENTTYPE Тип ответа
+const CONTENTTYPE string = "application/json"
 
-// APIClient структура для хранения информации об абоненте
+// APIClient Клиент API портала. Создается функцией NewClient.
 type APIClient struct {
 	Token      string
 	Params     []string
 	Provider   string
 	BaseApiUrl string
+
+	httpClient *http.Client
+	userAgent  string
+	logger     *log.Logger
+	retry      RetryPolicy
+	limiter    *rateLimiter
+	timeout    time.Duration
+	slog       *slog.Logger
+	metrics    Metrics
+	tracer     Tracer
+	tokens     TokenSource
+	auth       *tokenState
 }
 
-//APIError Структура для хранения ошибок от сервера
+// APIError Структура для хранения ошибок от сервера
 type APIError struct {
 	ErrorCode   string `json:"errorCode"`   // Код ошибки
 	Description string `json:"description"` // Текст ошибки
 }
 
-//WrapErrorr Тип хранения ошибок
+// WrapErrorr Тип хранения ошибок
 type WrapError struct {
 	Msg string
+	Err error // Исходная ошибка, если есть
 }
 
 func (d WrapError) Error() string {
 	return d.Msg
 }
 
-// Abonent структура для хранения информации об абоненте
-type Abonent struct {
-	UserId     string `json:"userId"`
-	Phone      string `json:"phone"`
-	FirstName  string `json:"firstName"`
-	LastName   string `json:"lastName"`
-	Email      string `json:"email"`
-	Department string `json:"department"`
-	Extension  string `json:"extension"`
-}
-
-// Abonents структура для хранения информации об абоненте
-type Abonents struct {
-	Abnts []Abonent
+// Unwrap Возвращает исходную ошибку для errors.Is и errors.As
+func (d WrapError) Unwrap() error {
+	return d.Err
 }
 
-// CfsStatusResponse
-type CfsStatusResponse struct {
-	IsCfsServiceEnabled bool
-	RuleList            []CfsRule
+// Wrap Оборачивает ошибку err сообщением msg, сохраняя исходную ошибку
+func Wrap(msg string, err error) error {
+	return WrapError{Msg: msg + err.Error(), Err: err}
 }
 
-// CfsRule
-type CfsRule struct {
-	Id             int      `json:""`
-	Name           string   `json:""`
-	ForwardToPhone string   `json:""`
-	Schedule       int      `json:""` //[ROUND_THE_CLOCK (Круглосуточно), WORKING_TIME (Рабочее время), NON_WORKING_TIME_AND_HOLIDAYS (Нерабочие часы и выходные)]
-	PhoneList      []string `json:""`
+// StatusError Ошибка, возвращаемая, если сервер ответил HTTP кодом, отличным от 200
+type StatusError struct {
+	StatusCode int    // HTTP код ответа
+	Status     string // HTTP статус ответа
+	APIError          // Описание ошибки, если сервер его передал
 }
 
-// CfsRuleUpdate Запрос для добавления правила
-type CfsRuleUpdate struct {
-	Name           string
-	ForwardToPhone string
-	Schedule       int // [ROUND_THE_CLOCK (Круглосуточно), WORKING_TIME (Рабочее время), NON_WORKING_TIME_AND_HOLIDAYS (Нерабочие часы и выходные)]
-	PhoneList      []string
-}
-
-// BasicRedirect Номера для переадресации
-type BasicRedirect struct {
-	ForwardAllCallsPhone    string `json:"forwardAllCallsPhone"`
-	ForwardBusyPhone        string `json:"forwardBusyPhone"`
-	ForwardUnavailablePhone string `json:"forwardUnavailablePhone"`
-	ForwardNotAnswerPhone   string `json:"forwardNotAnswerPhone"`   //Номер, на который будет выполнена переадресация если номер не отвечает
-	ForwardNotAnswerTimeout int    `json:"forwardNotAnswerTimeout"` //Колличество гудков, которые необходимо подождать для ответа номера
-}
-
-// BasicRedirectResponse Возвращаемое значение:
-type BasicRedirectResponse struct {
-	Status  int           `json:"status"`  // Статус переадресации = [ON (Переадресация включена), OFF (Переадресация выключена)]
-	Forward BasicRedirect `json:"forward"` // Номера для переадресации
-}
-
-// BwlStatusResponse
-type BwlStatusResponse struct {
-	Status    int       `json:"status"` // [BLACK_LIST_ON (Не принимать звонки с указанных в списке правил номеров), WHITE_LIST_ON (Принимать звонки только с указанных в списке правил номеров), OFF (Услуга отключена)]
-	BlackList []BwlRule `json:"blackList"`
-	WhiteList []BwlRule `json:"whiteList"`
-}
-
-// BwlRule
-type BwlRule struct {
-	Id        int      `json:"id"`
-	Name      string   `json:"name"`
-	Schedule  int      `json:"schedule"` // [ROUND_THE_CLOCK (Круглосуточно), WORKING_TIME (Рабочее время), NON_WORKING_TIME_AND_HOLIDAYS (Нерабочие часы и выходные)]
-	PhoneList []string `json:"phoneList"`
-}
-
-// BwlRuleAdd
-type BwlRuleAdd struct {
-	Type int           `json:"type"` // [BLACK_LIST (Не принимать звонки с указанных в списке правил номеров), WHITE_LIST (Принимать звонки только с указанных в списке правил номеров)]
-	Rule BwlRuleUpdate `json:"rule"`
-}
-
-// BwlRuleUpdate Запрос для обновления правила
-type BwlRuleUpdate struct {
-	Name      string   `json:"name"`
-	Schedule  int      `json:"schedule"` // [ROUND_THE_CLOCK (Круглосуточно), WORKING_TIME (Рабочее время), NON_WORKING_TIME_AND_HOLIDAYS (Нерабочие часы и выходные)]
-	PhoneList []string `json:"phoneList"`
-}
-type NumberInfo struct {
-	NumberId string `json:"numberId"` // Идентификатор входящего номера
-	Phone    string `json:"phone"`    //Номер телефона
-}
-type SubscriptionRequest struct {
-	Pattern          string `json:"pattern"`          //Идентификатор, входящий или добавочный номер абонента или номера
-	Expires          int    `json:"expires"`          //Длительность подписки
-	SubscriptionType int    `json:"subscriptionType"` // Тип подписки = [BASIC_CALL (Базовая информация о вызове), ADVANCED_CALL (Расширеная информация о вызове)]
-	Url              string `json:"url"`
-}
-type SubscriptionResult struct {
-	SubscriptionId string `json:"subscriptionId"` //Идентификатор подписки
-	Expires        int    `json:"expires"`        //Длительность подписки
-}
-type SubscriptionInfo struct {
-	SubscriptionId   string `json:"subscriptionId"`   //Идентификатор подписки
-	TargetType       int    `json:"targetType"`       //Тип объекта, для которого сформирована подписка = [GROUP (События всей группы), ABONENT (События абонента), NUMBER (События номера)]
-	TargetId         string `json:"targetId"`         //Идентификатор объекта, для которого сформирована подписка
-	SubscriptionType int    `json:"subscriptionType"` //Тип подписки = [BASIC_CALL (Базовая информация о вызове), ADVANCED_CALL (Расширеная информация о вызове)]
-	Expires          int    `json:"expires "`         //Длительность подписки
-	Url              string `json:"url"`              //URL приложения
-}
-type IcrNumbersResult struct {
-	PhoneNumber string            `json:"phoneNumber"` //Номер телефона
-	Status      int               `json:"status"`      //Результат выполнения операции = [SUCCESS (Успешно), FAULT (Ошибка)]
-	Error       IcrOperationError `json:"error"`       //Описание ошибки
-}
-type IcrOperationError struct {
-	ErrorCode   string `json:"errorCode"`   //Код ошибки
-	Description string `json:"description"` //Сообщение об ошибке
-}
-
-// IcrRouteRule структура хранения правила переадресации
-type IcrRouteRule struct {
-	InboundNumber string `json:"inboundNumber"` //Входящий номер клиента
-	Extension     string `json:"extension"`     //Внутренний номер
+func (e StatusError) Error() string {
+	msg := fmt.Sprintf("Ошибка при запросе к серверу Beeline. Получен HTTP код ответа %d. %s", e.StatusCode, e.Status)
+	if e.Description != "" {
+		msg += ". " + e.ErrorCode + ": " + e.Description
+	}
+	return msg
 }
 
-// IcrRouteResult структура хранения статуса удаления правил переадресации
-type IcrRouteResult struct {
-	Rule   IcrRouteRule      `json:"rule"`   //Правило переадресации
-	Status int               `json:"status"` //Результат выполнения операции = [SUCCESS (Успешно), FAULT (Ошибка)]
-	Error  IcrOperationError `json:"error"`  //Описание ошибки
+// IsNotFound Проверяет, что сервер ответил, что запрошенный объект не найден
+func IsNotFound(err error) bool {
+	var se StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
 }
 
+// UnixNano Дата и время в наносекундах от начала эпохи Unix.
+//
+// Deprecated: сервер передает даты в миллисекундах, используйте UnixMilli.
 type UnixNano struct {
 	time.Time
 }
-// CallRecord структура хранения подробной информации об отдельной записи
-type CallRecord struct {
-	Id         string        `json:"id"`         //Идентификатор записи
-	ExternalId string        `json:"externalId"` //Внешний идентификатор записи
-	Phone      string        `json:"phone"`      //Мобильный номер абонента
-	Direction  string        `json:"direction"`  //Тип вызова = [INBOUND (Входящий вызов), OUTBOUND (Исходящий вызов)]
-	Date       UnixNano 	 `json:"date"`       //Дата и время разговора
-	Duration   int           `json:"duration"`   //Длительность разговора в миллисекундах
-	FileSize   int           `json:"fileSize"`   //Размер файла записи разговора
-	Comment    string        `json:"comment"`    //Комментарий к записи разговора
-	Abonent    Abonent       `json:"abonent"`    //Абонент
-}
 
 func (t *UnixNano) MarshalJSON() ([]byte, error) {
 	ts := t.Time.UnixNano()
@@ -203,377 +123,303 @@ func (t *UnixNano) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	t.Time = time.Unix(int64(ts) / int64(time.Microsecond), int64(ts) % int64(time.Microsecond))
+	t.Time = time.Unix(int64(ts)/int64(time.Microsecond), int64(ts)%int64(time.Microsecond))
 
 	return nil
 }
 
-func (t *UnixNano) ToTime() (time.Time) {
+func (t *UnixNano) ToTime() time.Time {
 	return t.Time
 }
-//  ------------------------------------- Операции с абонентами -------------------------------------
-
-//  ------------------------------------- Простая переадресация вызовов -------------------------------------
-// GetAbonent Возвращает список всех абонентов
-// func (c APIClient) GetAbonents() Abonents {
-
-// }
-
-// // GetAbonents Ищет абонента по идентификатору, мобильному или добавочному номеру
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) GetAbonent(id string) Abonent {
-
-// }
-
-// // GetAgentStatus Возвращает статус агента call-центра
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) GetAgentStatus(id string) int {
-
-// }
-
-// // SetAgentStatus Устанавливает статус агента call-центра
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // newStatus - Новый статус агента
-// func (c APIClient) SetAgentStatus(id string, newStatus string) {
-
-// }
-
-// // GetRecordingStatus Возвращает статус записи разговоров для абонента
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) GetRecordingStatus(id string) int {
-
-// }
-
-// // TurnOnRecording Включает запись разговоров для абонента
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) TurnOnRecording(id string) error {
-
-// }
-
-// // TurnOffRecording Отключает запись разговоров для абонента
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) TurnOffRecording(id string) error {
 
-// }
-
-// //DoCall Совершает звонок от имени абонента
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // telNumber -Номер телефона - 10 цифр
-// func (c APIClient) DoCall(id string, telNumber string) string {
-
-// }
-
-// // TurnOnNumberToAbonent Подключает дополнительный номер абоненту
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // telNumber -Подключаемый номер телефона - 10 цифр
-// // schedule - Расписание перенаправления на номер
-// func (c APIClient) TurnOnNumberToAbonent(id string, telNumber string, schedule int) error {
-
-// }
-
-// // TurnOffNumberToAbonent Отключает дополнительный номер абонента
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) TurnOffNumberToAbonent(id string) error {
-
-// }
-
-// //  ------------------------------------- Простая переадресация вызовов -------------------------------------
-
-// // GetBasicRedirectStatus Возвращает статус базовой переадресации
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) GetBasicRedirectStatus(id string) int {
-
-// }
-
-// // TurnOnBasicRedirect Включает базовую переадресацию
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // br - Номера для переадресации
-// func (c APIClient) TurnOnBasicRedirect(id string, br BasicRedirect) error {
-
-// }
-
-// // TurnOffBasicRedirect Отключает базовую переадресацию
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func TurnOffBasicRedirect(id string) error {
-
-// }
-
-// //  ------------------------------------- Выборочная переадресация вызовов -------------------------------------
-
-// // Возвращает список правил выборочной переадресации
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) GetSelectiveCallRules(id string) (CfsStatusResponse, error) {
-
-// }
-
-// // Добавляет правило для выборочной переадресации
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // rule -Запрос для добавления правила
-// func (c APIClient) AddSelectiveCallRule(id string, rule CfsRuleUpdate) error {
-
-// }
-
-// // Включает выборочную переадресацию
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) TurnOnSelectiveRedirect(id string) error {
-
-// }
-
-// // UpdateSelectiveCallRule Обновляет правило
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // ruleID -Идентификатор правила
-// // rule - Запрос для обновления правила
-// func (c APIClient) UpdateSelectiveCallRule(id string, ruleID int, rule CfsRuleUpdate) error {
-
-// }
-
-// // TurnOffSelectiveRedirect Отключает выборочную переадресацию
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) TurnOffSelectiveRedirect(id string) error {
-
-// }
-
-// // DeleteSelectiveRedirect Удаляет правило
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // ruleID - Идентификатор правила
-// func (c APIClient) DeleteSelectiveCallRule(id string, ruleID int) error {
-
-// }
-
-// //  ------------------------------------- Выборочный прием звонков -------------------------------------
-
-// // Статус и список правил для выборочного приема звонков
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) IncCallRules(id string) (BwlStatusResponse, error) {
-
-// }
-
-// // AddIncCallRule Добавляет правило для выборочного приема звонков
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // ruleUpdate - Запрос для добавления правила
-// func (c APIClient) AddIncCallRule(id string, rule BwlRuleAdd, ruleUpdate BwlRuleUpdate) int {
-
-// }
-
-// // TurnOnSelectiveCallReceive Включает выборочный прием звонков
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // t - Тип правила
-// func (c APIClient) TurnOnSelectiveCallReceive(id string, t int) error {
-// }
-
-// // UpdateSelectiveReceiveRule Обновляет правило для выборочного приема звонков
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // ruleID - Идентификатор правила
-// // ruleUpdate -Запрос для обновления правила
-// func (c APIClient) UpdateSelectiveReceiveRule(id string, ruleID int, ruleUpdate BwlRuleUpdate) error {
-
-// }
-
-// // TurnOffSelectiveReceiveRule Отключает выборочный прием звонков
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// func (c APIClient) TurnOffSelectiveReceiveRule(id string) error {
-
-// }
-
-// // DeleteSelectiveReceiveRule Удаляет правило для выборочного приема звонков
-// // id - Идентификатор, мобильный или добавочный номер абонента
-// // ruleID - Идентификатор правила
-// func DeleteSelectiveReceiveRule(id string, ruleId int) error {
-
-// }
-
-//  ------------------------------------- Операции с записями разговоров  -------------------------------------
+// UnixMilli Дата и время, которые сервер передает в миллисекундах от начала эпохи Unix
+type UnixMilli struct {
+	time.Time
+}
 
-// GetRecords Записи разговоров передаются по порядку начиная со следующей после переданного
-// ID или с первой записи, если ID не передан. За один запрос передаётся не более чем 100 записей.
-// id - Начальный ID записи
-func (c APIClient) GetRecords(id int64) ([]CallRecord, error) {
-	url := c.BaseApiUrl + "records"
-	if id > 0 {
-		url = fmt.Sprintf("%sv2/records/%d", c.BaseApiUrl, id)
-	}
-	recs := []CallRecord{}
-	body, err := createRequest("GET", url, c.Token, "")
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(body, &recs); err != nil {
-		return nil, err
-	}
-	return recs, nil
+// MarshalJSON Записывает дату в миллисекундах от начала эпохи Unix
+func (t UnixMilli) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(t.Time.UnixMilli(), 10)), nil
 }
 
-// DeleteRecord Удаляет запись разговора по уникальному идентификатору записи recordId.
-// id - Идентификатор записи разговора
-func (c APIClient) DeleteRecord(id string) error {
-	url := fmt.Sprintf("%sv2/records/%s", c.BaseApiUrl, id)
-	_, err := createRequest("DELETE", url, c.Token, "")
+// UnmarshalJSON Разбирает дату в миллисекундах от начала эпохи Unix
+func (t *UnixMilli) UnmarshalJSON(b []byte) error {
+	ts, err := strconv.ParseInt(string(b), 10, 64)
 	if err != nil {
-		return WrapError{Msg: "Ошибка при удалении записи с сервера Билайн. " + err.Error()}
+		return err
 	}
+	t.Time = time.UnixMilli(ts)
 	return nil
 }
 
-// // GetRecordInfo Возвращает запись разговора по уникальному идентификатору записи recordId.
-// // id - Идентификатор записи разговора
-// func (c APIClient) GetRecordInfo(id string) (CallRecord, error) {
-
-// }
+// Request Отправляет запрос к API портала и возвращает тело ответа
+// method - тип HTTP запроса
+// path - путь относительно BaseApiUrl
+// body - тело запроса, которое будет передано в формате JSON, или nil
+func (c *APIClient) Request(method string, path string, body interface{}) ([]byte, error) {
+	return c.RequestContext(context.Background(), method, path, body)
+}
 
-// // GetRecordInfoFromEvent Возвращает запись разговора по ID разговора из события и ID пользователя из того же события.
-// // id - Идентификатор разговора из события
-// // userId - Идентификатор пользователя из события
-// func (c APIClient) GetRecordInfoFromEvent(id string, userId string) (CallRecord, error) {
+// RequestContext Отправляет запрос к API портала с контекстом ctx и возвращает тело ответа.
+// При отмене ctx запрос и ожидание перед повтором прерываются.
+func (c *APIClient) RequestContext(ctx context.Context, method string, path string, body interface{}) ([]byte, error) {
+	resp, _, err := c.RequestWithHeaderContext(ctx, method, path, body)
+	return resp, err
+}
 
-// }
+// RequestWithHeader Отправляет запрос к API портала и возвращает тело и заголовки ответа,
+// например Content-Type файла записи разговора
+// method - тип HTTP запроса
+// path - путь относительно BaseApiUrl
+// body - тело запроса, которое будет передано в формате JSON, или nil
+func (c *APIClient) RequestWithHeader(method string, path string, body interface{}) ([]byte, http.Header, error) {
+	return c.RequestWithHeaderContext(context.Background(), method, path, body)
+}
 
-// // GetRecordFile Возвращает файл записи разговора по уникальному идентификатору записи recordId
-// // id - Идентификатор разговора из события
+// RequestWithHeaderContext Отправляет запрос к API портала с контекстом ctx и возвращает тело и заголовки ответа
+func (c *APIClient) RequestWithHeaderContext(ctx context.Context, method string, path string, body interface{}) ([]byte, http.Header, error) {
+	b := ""
+	if body != nil {
+		j, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, Wrap("Ошибка при подготовке тела запроса к серверу Beeline. ", err)
+		}
+		b = string(j)
+	}
+	resp, err := c.createRequest(ctx, method, path, b, false)
+	return resp.body, resp.header, err
+}
 
-func (c APIClient) GetRecordFile(id string) (io.Reader, error) {
-	var r io.Reader
-	url := fmt.Sprintf("%sv2/records/%s/download", c.BaseApiUrl, id)
-	body, err := createRequest("GET", url, c.Token, "")
+// RequestStream Отправляет запрос к API портала и возвращает тело ответа потоком без чтения в память,
+// например для загрузки файла записи разговора. Поток нужно закрыть после чтения.
+// Время чтения потока ограничено тем же таймаутом, что и запрос.
+// method - тип HTTP запроса
+// path - путь относительно BaseApiUrl
+func (c *APIClient) RequestStream(ctx context.Context, method string, path string) (io.ReadCloser, http.Header, error) {
+	resp, err := c.createRequest(ctx, method, path, "", true)
 	if err != nil {
-		return nil, WrapError{Msg: "Ошибка при подготовке запроса на получение информации о записях разговоров. " + err.Error()}
+		return nil, nil, err
 	}
-	r = bytes.NewReader(body)
-	return r, nil
+	return resp.stream, resp.header, nil
 }
 
-// // GetRecordFileFromEvent Возвращает запись разговора по ID разговора  из события и ID пользователя из того же события.
-// // id - Идентификатор разговора из события
-// // userId - Идентификатор пользователя из события
-
-// func (c APIClient) GetRecordFileFromEvent(id string, userId string) (Reader, error) {
-
-// }
-
-// //  ------------------------------------- Операции со входящими номерами  -------------------------------------
-
-// // GetAllIncNumbers Возвращает список всех входящих номеров
-// // id - Идентификатор разговора из события
-// // userId - Идентификатор пользователя из события
-
-// func (c APIClient) GetAllIncNumbers() ([]NumberInfo, error) {
-
-// }
-
-// // FindIncNumberById Ищет входящий номер по идентификатору, номеру или добавочному номеру
-// // id - Идентификатор, номер или добавочный номер
-
-// func (c APIClient) FindIncNumberById(id string) (NumberInfo, error) {
-
-// }
-
-// //  ------------------------------------- Подписка на Xsi-Events  -------------------------------------
-
-// // XSIEventSubscribtion Формирует подписку на Xsi-Events
-// // Подписка может быть использована для интеграции со сторонними системами, которым необходим контроль над звонками абонентов облачной АТС в реальном времени.
-// // API использует механизм подписки на события, ассоциированные с тем или иным абонентом, номером или всем клиентом.
-// // Например, Абонент облачной АТС принимает вызов, сторонняя CRM система получает обновления о текущем статусе вызова (ringing, established, completed).
-// // req - Запрос для подписки на события
-
-// func (c APIClient) XSIEventSubscription(reg SubscriptionRequest) (SubscriptionResult, error) {
-
-// }
-
-// // GetXSIEventSubscriptionInfo Возвращает информацию о подписке на Xsi-Events
-// // id - Идентификатор подписки
-
-// func (c APIClient) GetXSIEventSubscriptionInfo(id string) (SubscriptionInfo, error) {
-
-// }
-
-// // TurnOffXSIEventSubscription Отключает подписку на Xsi-Events
-// // id - Идентификатор отключаемой подписки
-
-// func (c APIClient) TurnOffXSIEventSubscription(id string) error {
-
-// }
-
-// //  ------------------------------------- Индивидуальная переадресация  -------------------------------------
-
-// // GetIncNumWithRedirect Возвращает список входящих номеров, для которых включена переадресация
-
-// func (c APIClient) GetIncNumWithRedirect() ([]NumberInfo, error) {
-
-// }
-
-// // TurnOnCustomIncNumRedirect Включает индивидуальную переадресацию для входящих номеров
-// //  numberList - Список входящих номеров, для которых должна быть включена переадресация
-// func (c APIClient) TurnOnCustomIncNumRedirect(numberList []string) ([]IcrNumbersResult, error) {
-
-// }
-
-// // TurnOffCustomIncNumRedirect Отключает индивидуальную переадресацию для входящих номеров
-// //  numberList - Список входящих номеров, для которых должна быть отключена переадресация
-// func (c APIClient) TurnOffCustomIncNumRedirect(numberList []string) ([]IcrNumbersResult, error) {
-
-// }
-
-// // GetRedirectRulesList Возвращает список правил переадресации
-// func (c APIClient) GetRedirectRulesList() ([]IcrRouteRule, error) {
-
-// }
-
-// // DeleteRedirectRulesList Удаляет список правил переадресации
-// // rules - Список правил переадресации
-// func (c APIClient) DeleteRedirectRulesList(rules []IcrRouteRule) ([]IcrRouteResult, error) {
-
-// }
-
-// // ReplaceRedirectRulesList Замещает правила переадресации
-// // rules - Список правил переадресации
-// func (c APIClient) ReplaceRedirectRulesList(rules []IcrRouteRule) ([]IcrRouteResult, error) {
-
-// }
-
-// // UnionRedirectRulesList Объединяет существующие правила переадресации с переданным списком правил.
-// // rules - Список правил переадресации
-// func (c APIClient) UnionRedirectRulesList(rules []IcrRouteRule) ([]IcrRouteResult, error) {
+// RequestJSON Отправляет запрос к API портала и разбирает ответ в формате JSON в out
+// method - тип HTTP запроса
+// path - путь относительно BaseApiUrl
+// body - тело запроса, которое будет передано в формате JSON, или nil
+// out - указатель на значение для ответа или nil, если ответ не нужен
+func (c *APIClient) RequestJSON(method string, path string, body interface{}, out interface{}) error {
+	return c.RequestJSONContext(context.Background(), method, path, body, out)
+}
 
-// }
+// RequestJSONContext Отправляет запрос к API портала с контекстом ctx и разбирает ответ в формате JSON в out
+func (c *APIClient) RequestJSONContext(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	resp, err := c.RequestContext(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp, out); err != nil {
+		return Wrap("Ошибка при разборе ответа сервера Beeline. ", err)
+	}
+	return nil
+}
 
-// createRequest Функция отправки запроса
+// createRequest Функция отправки запроса с повтором при временных ошибках
+// ctx - контекст запроса
 // reqType - тип HTTP запроса
-// url - адрес
+// path - путь относительно BaseApiUrl
 // body - тело запроса
-func createRequest(reqType string, url string, token string, b string) ([]byte, error) {
+// stream - вернуть тело успешного ответа потоком, не читая его
+func (c *APIClient) createRequest(ctx context.Context, reqType string, path string, b string, stream bool) (response, error) {
+	if c.tracer != nil {
+		var span Span
+		ctx, span = c.tracer.Start(ctx, "beeline.request", map[string]string{
+			"http.method": reqType,
+			"endpoint":    endpoint(path),
+		})
+		defer span.End()
+		resp, err := c.attempts(ctx, reqType, path, b, stream, span)
+		if err != nil {
+			span.SetError(err)
+		}
+		return resp, err
+	}
+	return c.attempts(ctx, reqType, path, b, stream, nil)
+}
+
+// attempts Отправляет запрос, повторяя его согласно RetryPolicy
+func (c *APIClient) attempts(ctx context.Context, reqType string, path string, b string, stream bool, span Span) (response, error) {
+	backoff := c.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.authorized(ctx, reqType, path, b, stream)
+		if span != nil {
+			span.SetAttribute("http.status_code", strconv.Itoa(resp.status))
+			span.SetAttribute("attempts", strconv.Itoa(attempt+1))
+		}
+		if err == nil || attempt >= c.retry.Attempts || !retryable(reqType, err) {
+			return resp, err
+		}
+		c.logf("Повтор запроса %s %s через %s после ошибки: %s", reqType, path, backoff, err)
+		if c.slog != nil {
+			c.slog.LogAttrs(ctx, slog.LevelWarn, "Повтор запроса к API Beeline",
+				slog.String("method", reqType), slog.String("path", path), slog.Duration("backoff", backoff), slog.Int("attempt", attempt+1))
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+// authorized Отправляет запрос с текущим ключом безопасности. Если сервер ответил 401,
+// а источник ключа вернул новый ключ, запрос один раз повторяется с ним.
+func (c *APIClient) authorized(ctx context.Context, reqType string, path string, b string, stream bool) (response, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return response{}, err
+	}
+	start := time.Now()
+	resp, err := c.send(ctx, reqType, c.BaseApiUrl+path, b, token, stream)
+	c.observe(ctx, reqType, path, resp.status, time.Since(start), err)
+	if resp.status != http.StatusUnauthorized {
+		return resp, err
+	}
+	fresh, ok := c.refreshToken(ctx, token)
+	if !ok {
+		return resp, err
+	}
+	c.logf("Повтор запроса %s %s с обновленным ключом безопасности", reqType, path)
+	start = time.Now()
+	resp, err = c.send(ctx, reqType, c.BaseApiUrl+path, b, fresh, stream)
+	c.observe(ctx, reqType, path, resp.status, time.Since(start), err)
+	return resp, err
+}
+
+// response Ответ сервера на один запрос
+type response struct {
+	body   []byte
+	stream io.ReadCloser // Непрочитанное тело успешного ответа, если запрошено потоком
+	header http.Header
+	status int // HTTP код ответа, 0 - если ответ не получен
+}
+
+// send Отправляет один запрос к серверу с ключом безопасности token и возвращает ответ
+func (c *APIClient) send(ctx context.Context, reqType string, url string, b string, token string, stream bool) (response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return response{}, err
+		}
+	}
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	streaming := false
+	defer func() {
+		if !streaming {
+			cancel()
+		}
+	}()
 	body := strings.NewReader(b)
-	recordReq, err := http.NewRequest(reqType, url, body)
+	recordReq, err := http.NewRequestWithContext(ctx, reqType, url, body)
 	if err != nil {
-		return nil, WrapError{Msg: "Ошибка при подготовке запроса к серверу Beeline. " + err.Error()}
+		return response{}, Wrap("Ошибка при подготовке запроса к серверу Beeline. ", err)
 	}
 	// Устанавливаем HTTP заголовок билайновский для ключа безопасности
 	recordReq.Header.Set("X-MPBX-API-AUTH-TOKEN", token)
-	// Установка времени ожидания ответа от сервера равной 10 секундам
-	timeout := time.Duration(60 * time.Second)
-	cl := &http.Client{Timeout: timeout}
-	recordReq.Close = true
+	if b != "" {
+		recordReq.Header.Set("Content-Type", CONTENTTYPE)
+	}
+	if c.userAgent != "" {
+		recordReq.Header.Set("User-Agent", c.userAgent)
+	}
+	cl := c.httpClient
+	if cl == nil {
+		cl = http.DefaultClient
+	}
 	resp, err := cl.Do(recordReq)
 	if err != nil {
-		return nil, WrapError{Msg: "Ошибка при отправке запроса к серверу Beeline. " + err.Error()}
+		return response{}, Wrap("Ошибка при отправке запроса к серверу Beeline. ", err)
+	}
+	res := response{header: resp.Header, status: resp.StatusCode}
+	if stream && resp.StatusCode == http.StatusOK {
+		// Таймаут отменяется только после закрытия потока
+		streaming = true
+		res.stream = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		return res, nil
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, WrapError{Msg: fmt.Sprintf("Ошибка при запросе к серверу Beeline. Получен HTTP код ответа %d. %s", resp.StatusCode, resp.Status)}
+		se := StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		// Тело ответа с описанием ошибки необязательно, поэтому ошибки его разбора игнорируются
+		if b, err := ioutil.ReadAll(resp.Body); err == nil {
+			json.Unmarshal(b, &se.APIError)
+		}
+		return res, se
 	}
 	responseBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, WrapError{Msg: "Ошибка при чтении ответа после отправке запроса к серверу Beeline. " + err.Error()}
+		return res, Wrap("Ошибка при чтении ответа после отправке запроса к серверу Beeline. ", err)
 	}
-	return responseBody, nil
+	res.body = responseBody
+	return res, nil
 }
-func fireError(err error, msg string) {
-	if err != nil {
-		log.Fatalln(msg + err.Error())
+
+// cancelBody Тело ответа, при закрытии которого отменяется контекст запроса
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryable Проверяет, можно ли повторить запрос после ошибки err.
+// Повторяются только идемпотентные запросы при сетевых ошибках и ответах 429 и 5xx.
+// Ошибки источника ключа, подготовки запроса и отмена контекста не повторяются.
+func retryable(method string, err error) bool {
+	if method != "GET" && method != "PUT" && method != "DELETE" {
+		return false
+	}
+	var se StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
 	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var ue *url.Error
+	var ne net.Error
+	return errors.As(err, &ue) || errors.As(err, &ne)
 }
+
+// logf Записывает сообщение в журнал клиента, если он задан
+func (c *APIClient) logf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, args...)
+	}
+}
+
+// NewApiClient Возвращает клиента API с настройками по умолчанию.
+//
+// Deprecated: используйте NewClient, который проверяет параметры и позволяет их задать.
 func NewApiClient(token string) (c APIClient) {
 	c = APIClient{}
 	c.Token = token
 	c.Provider = "Beeline"
-	c.BaseApiUrl = "https://cloudpbx.beeline.ru/apis/portal/"
+	c.BaseApiUrl = DefaultBaseURL
+	c.httpClient = &http.Client{}
+	c.timeout = DefaultTimeout
 	return c
 }
@@ -0,0 +1,43 @@
+package beelinetest
+
+import (
+	"errors"
+	"fmt"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Endpoint Операция клиента API для проверки методом CheckEndpoints
+type Endpoint struct {
+	Name   string       // Имя операции для сообщения об ошибке
+	Method string       // HTTP метод запроса операции
+	Path   string       // Путь запроса операции без параметров, например /abonents/u1/agent
+	Call   func() error // Вызов операции
+}
+
+// CheckEndpoints Проверяет каждую операцию: сначала успешный ответ имитатора,
+// затем ответ с ошибкой 500, которую операция должна вернуть как beelineapi.StatusError.
+// Операции вызываются по порядку, поэтому следующая может использовать данные, созданные предыдущей.
+// Возвращает ошибку с именем первой операции, не прошедшей проверку, или nil
+func (s *Server) CheckEndpoints(endpoints []Endpoint) error {
+	for _, e := range endpoints {
+		if err := s.checkEndpoint(e); err != nil {
+			return fmt.Errorf("%s: %w", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkEndpoint Проверяет операцию e
+func (s *Server) checkEndpoint(e Endpoint) error {
+	if err := e.Call(); err != nil {
+		return fmt.Errorf("ошибка при успешном ответе сервера: %w", err)
+	}
+	s.InjectError(e.Method, e.Path, 500, beelineapi.APIError{ErrorCode: "Internal", Description: "Internal error"}, 1)
+	defer s.ResetErrors()
+	var se beelineapi.StatusError
+	if err := e.Call(); !errors.As(err, &se) || se.StatusCode != 500 || se.ErrorCode != "Internal" {
+		return fmt.Errorf("ожидалась ошибка сервера 500, получено %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,693 @@
+package beelinetest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/icr"
+	"github.com/taigasys/beeline-portal-api/records"
+	"github.com/taigasys/beeline-portal-api/xsi"
+)
+
+// maxRecords Максимальное количество записей, передаваемых за один запрос
+const maxRecords = 100
+
+// routes Регистрирует обработчики запросов
+func (s *Server) routes() {
+	// Операции с абонентами
+	s.mux.HandleFunc("GET /abonents", s.getAbonents)
+	s.mux.HandleFunc("GET /abonents/{pattern}", s.getAbonent)
+	s.mux.HandleFunc("GET /abonents/{pattern}/agent", s.getAgentStatus)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/agent", s.setAgentStatus)
+	s.mux.HandleFunc("GET /abonents/{pattern}/recording", s.getRecording)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/recording", s.setRecording(beelineapi.ON))
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/recording", s.setRecording(beelineapi.OFF))
+	s.mux.HandleFunc("POST /abonents/{pattern}/call", s.doCall)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/number", s.setNumber)
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/number", s.deleteNumber)
+	// Простая переадресация вызовов
+	s.mux.HandleFunc("GET /abonents/{pattern}/bfs", s.getBfs)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/bfs", s.setBfs)
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/bfs", s.deleteBfs)
+	// Выборочная переадресация вызовов
+	s.mux.HandleFunc("GET /abonents/{pattern}/cfs", s.getCfs)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/cfs", s.setCfsEnabled(true))
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/cfs", s.setCfsEnabled(false))
+	s.mux.HandleFunc("POST /abonents/{pattern}/cfs/rule", s.addCfsRule)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/cfs/rule/{id}", s.updateCfsRule)
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/cfs/rule/{id}", s.deleteCfsRule)
+	// Выборочный прием звонков
+	s.mux.HandleFunc("GET /abonents/{pattern}/bwl", s.getBwl)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/bwl", s.setBwl)
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/bwl", s.deleteBwl)
+	s.mux.HandleFunc("POST /abonents/{pattern}/bwl/rule", s.addBwlRule)
+	s.mux.HandleFunc("PUT /abonents/{pattern}/bwl/rule/{id}", s.updateBwlRule)
+	s.mux.HandleFunc("DELETE /abonents/{pattern}/bwl/rule/{id}", s.deleteBwlRule)
+	// Операции с записями разговоров
+	s.mux.HandleFunc("GET /records", s.getRecords)
+	s.mux.HandleFunc("GET /records/{callId}/{userId}", s.getRecordFromEvent)
+	s.mux.HandleFunc("GET /records/{callId}/{userId}/download", s.downloadRecordFromEvent)
+	s.mux.HandleFunc("GET /v2/records/{id}", s.getRecordsAfter)
+	s.mux.HandleFunc("PUT /v2/records/{id}", s.updateRecord)
+	s.mux.HandleFunc("DELETE /v2/records/{id}", s.deleteRecord)
+	s.mux.HandleFunc("GET /v2/records/{id}/download", s.downloadRecord)
+	// Операции со входящими номерами
+	s.mux.HandleFunc("GET /numbers", s.getNumbers)
+	s.mux.HandleFunc("GET /numbers/{pattern}", s.getNumber)
+	// Подписка на Xsi-Events
+	s.mux.HandleFunc("POST /subscription", s.subscribe)
+	s.mux.HandleFunc("GET /subscription/{id}", s.getSubscription)
+	s.mux.HandleFunc("DELETE /subscription/{id}", s.unsubscribe)
+	// Индивидуальная переадресация
+	s.mux.HandleFunc("GET /icr/numbers", s.getIcrNumbers)
+	s.mux.HandleFunc("PUT /icr/numbers", s.setIcrNumbers(true))
+	s.mux.HandleFunc("DELETE /icr/numbers", s.setIcrNumbers(false))
+	s.mux.HandleFunc("GET /icr/route", s.getIcrRoutes)
+	s.mux.HandleFunc("PUT /icr/route", s.replaceIcrRoutes)
+	s.mux.HandleFunc("POST /icr/route", s.unionIcrRoutes)
+	s.mux.HandleFunc("DELETE /icr/route", s.deleteIcrRoutes)
+}
+
+//  ------------------------------------- Операции с абонентами -------------------------------------
+
+// findAbonent Ищет абонента по идентификатору, мобильному или добавочному номеру из пути запроса.
+// Если абонент не найден, отправляет ответ 404 и возвращает false.
+func (s *Server) findAbonent(w http.ResponseWriter, r *http.Request) (abonents.Abonent, bool) {
+	pattern := r.PathValue("pattern")
+	for _, a := range s.abonents {
+		if a.UserId == pattern || a.Phone == pattern || a.Extension == pattern {
+			return a, true
+		}
+	}
+	notFound(w, "Абонент", pattern)
+	return abonents.Abonent{}, false
+}
+
+func (s *Server) getAbonents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, append([]abonents.Abonent{}, s.abonents...))
+}
+
+func (s *Server) getAbonent(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		writeJSON(w, a)
+	}
+}
+
+func (s *Server) getAgentStatus(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		writeJSON(w, s.agents[a.UserId])
+	}
+}
+
+func (s *Server) setAgentStatus(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	status, err := beelineapi.ParseAgentStatus(r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Неверный статус агента: "+r.URL.Query().Get("status"))
+		return
+	}
+	s.agents[a.UserId] = status
+}
+
+func (s *Server) getRecording(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		writeJSON(w, s.recording[a.UserId])
+	}
+}
+
+func (s *Server) setRecording(status beelineapi.ServiceStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a, ok := s.findAbonent(w, r); ok {
+			s.recording[a.UserId] = status
+		}
+	}
+}
+
+func (s *Server) doCall(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	phone := r.URL.Query().Get("phoneNumber")
+	if phone == "" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Не указан номер телефона")
+		return
+	}
+	call := Call{Id: fmt.Sprintf("call-%d", s.newId()), UserId: a.UserId, Phone: phone}
+	s.calls = append(s.calls, call)
+	writeJSON(w, call.Id)
+}
+
+func (s *Server) setNumber(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	if _, err := beelineapi.ParseSchedule(q.Get("schedule")); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.extraNumbers[a.UserId] = q.Get("phoneNumber")
+}
+
+func (s *Server) deleteNumber(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		delete(s.extraNumbers, a.UserId)
+	}
+}
+
+//  ------------------------------------- Простая переадресация вызовов -------------------------------------
+
+func (s *Server) getBfs(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	bfs := s.bfs[a.UserId]
+	if bfs == nil {
+		bfs = &forwarding.BasicRedirectResponse{Status: beelineapi.OFF}
+	}
+	writeJSON(w, bfs)
+}
+
+func (s *Server) setBfs(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	var br forwarding.BasicRedirect
+	if readJSON(w, r, &br) {
+		s.bfs[a.UserId] = &forwarding.BasicRedirectResponse{Status: beelineapi.ON, Forward: br}
+	}
+}
+
+func (s *Server) deleteBfs(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		if bfs := s.bfs[a.UserId]; bfs != nil {
+			bfs.Status = beelineapi.OFF
+		}
+	}
+}
+
+//  ------------------------------------- Выборочная переадресация вызовов -------------------------------------
+
+// abonentCfs Возвращает настройки выборочной переадресации абонента, создавая их при необходимости
+func (s *Server) abonentCfs(userId string) *forwarding.CfsStatusResponse {
+	cfs := s.cfs[userId]
+	if cfs == nil {
+		cfs = &forwarding.CfsStatusResponse{RuleList: []forwarding.CfsRule{}}
+		s.cfs[userId] = cfs
+	}
+	return cfs
+}
+
+func (s *Server) getCfs(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		writeJSON(w, s.abonentCfs(a.UserId))
+	}
+}
+
+func (s *Server) setCfsEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a, ok := s.findAbonent(w, r); ok {
+			s.abonentCfs(a.UserId).IsCfsServiceEnabled = enabled
+		}
+	}
+}
+
+func (s *Server) addCfsRule(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	var upd forwarding.CfsRuleUpdate
+	if !readJSON(w, r, &upd) {
+		return
+	}
+	cfs := s.abonentCfs(a.UserId)
+	rule := forwarding.CfsRule{Id: s.newId(), Name: upd.Name, ForwardToPhone: upd.ForwardToPhone, Schedule: upd.Schedule, PhoneList: upd.PhoneList}
+	cfs.RuleList = append(cfs.RuleList, rule)
+	writeJSON(w, rule.Id)
+}
+
+// findCfsRule Возвращает индекс правила выборочной переадресации из пути запроса.
+// Если правило не найдено, отправляет ответ 404 и возвращает false.
+func (s *Server) findCfsRule(w http.ResponseWriter, r *http.Request) (*forwarding.CfsStatusResponse, int, bool) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return nil, 0, false
+	}
+	cfs := s.abonentCfs(a.UserId)
+	for i, rule := range cfs.RuleList {
+		if rule.Id == id {
+			return cfs, i, true
+		}
+	}
+	notFound(w, "Правило", strconv.Itoa(id))
+	return nil, 0, false
+}
+
+func (s *Server) updateCfsRule(w http.ResponseWriter, r *http.Request) {
+	cfs, i, ok := s.findCfsRule(w, r)
+	if !ok {
+		return
+	}
+	var upd forwarding.CfsRuleUpdate
+	if readJSON(w, r, &upd) {
+		rule := &cfs.RuleList[i]
+		rule.Name, rule.ForwardToPhone, rule.Schedule, rule.PhoneList = upd.Name, upd.ForwardToPhone, upd.Schedule, upd.PhoneList
+	}
+}
+
+func (s *Server) deleteCfsRule(w http.ResponseWriter, r *http.Request) {
+	if cfs, i, ok := s.findCfsRule(w, r); ok {
+		cfs.RuleList = append(cfs.RuleList[:i], cfs.RuleList[i+1:]...)
+	}
+}
+
+//  ------------------------------------- Выборочный прием звонков -------------------------------------
+
+// abonentBwl Возвращает настройки выборочного приема звонков абонента, создавая их при необходимости
+func (s *Server) abonentBwl(userId string) *bwl.BwlStatusResponse {
+	st := s.bwl[userId]
+	if st == nil {
+		st = &bwl.BwlStatusResponse{Status: bwl.OFF, BlackList: []bwl.BwlRule{}, WhiteList: []bwl.BwlRule{}}
+		s.bwl[userId] = st
+	}
+	return st
+}
+
+func (s *Server) getBwl(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		writeJSON(w, s.abonentBwl(a.UserId))
+	}
+}
+
+func (s *Server) setBwl(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	t, err := beelineapi.ParseBwlListType(r.URL.Query().Get("type"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	st := s.abonentBwl(a.UserId)
+	st.Status = bwl.BLACK_LIST_ON
+	if t == beelineapi.WHITE_LIST {
+		st.Status = bwl.WHITE_LIST_ON
+	}
+}
+
+func (s *Server) deleteBwl(w http.ResponseWriter, r *http.Request) {
+	if a, ok := s.findAbonent(w, r); ok {
+		s.abonentBwl(a.UserId).Status = bwl.OFF
+	}
+}
+
+func (s *Server) addBwlRule(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return
+	}
+	var add bwl.BwlRuleAdd
+	if !readJSON(w, r, &add) {
+		return
+	}
+	st := s.abonentBwl(a.UserId)
+	rule := bwl.BwlRule{Id: s.newId(), Name: add.Rule.Name, Schedule: add.Rule.Schedule, PhoneList: add.Rule.PhoneList}
+	if add.Type == beelineapi.WHITE_LIST {
+		st.WhiteList = append(st.WhiteList, rule)
+	} else {
+		st.BlackList = append(st.BlackList, rule)
+	}
+	writeJSON(w, rule.Id)
+}
+
+// findBwlRule Возвращает список, содержащий правило выборочного приема звонков из пути запроса, и индекс правила в нем.
+// Если правило не найдено, отправляет ответ 404 и возвращает false.
+func (s *Server) findBwlRule(w http.ResponseWriter, r *http.Request) (*[]bwl.BwlRule, int, bool) {
+	a, ok := s.findAbonent(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return nil, 0, false
+	}
+	st := s.abonentBwl(a.UserId)
+	for _, list := range []*[]bwl.BwlRule{&st.BlackList, &st.WhiteList} {
+		for i, rule := range *list {
+			if rule.Id == id {
+				return list, i, true
+			}
+		}
+	}
+	notFound(w, "Правило", strconv.Itoa(id))
+	return nil, 0, false
+}
+
+func (s *Server) updateBwlRule(w http.ResponseWriter, r *http.Request) {
+	list, i, ok := s.findBwlRule(w, r)
+	if !ok {
+		return
+	}
+	var upd bwl.BwlRuleUpdate
+	if readJSON(w, r, &upd) {
+		rule := &(*list)[i]
+		rule.Name, rule.Schedule, rule.PhoneList = upd.Name, upd.Schedule, upd.PhoneList
+	}
+}
+
+func (s *Server) deleteBwlRule(w http.ResponseWriter, r *http.Request) {
+	if list, i, ok := s.findBwlRule(w, r); ok {
+		*list = append((*list)[:i], (*list)[i+1:]...)
+	}
+}
+
+//  ------------------------------------- Операции с записями разговоров  -------------------------------------
+
+// getRecords Возвращает не более 100 записей, начиная со следующей после переданного ID
+func (s *Server) getRecords(w http.ResponseWriter, r *http.Request) {
+	s.writeRecords(w, r.URL.Query().Get("id"))
+}
+
+// getRecordsAfter Отвечает на запрос страницы записей после записи {id}, как его выполняет records.GetRecords.
+// Имитатор всегда отвечает на этот путь страницей записей, поэтому records.GetRecordInfo находит запись
+// по странице, следующей после предыдущего ID.
+func (s *Server) getRecordsAfter(w http.ResponseWriter, r *http.Request) {
+	s.writeRecords(w, r.PathValue("id"))
+}
+
+// writeRecords Отправляет не более maxRecords записей, следующих после записи с ID after, или с первой записи.
+// Если записи с ID after нет, отправляются записи с числовым ID больше after.
+func (s *Server) writeRecords(w http.ResponseWriter, after string) {
+	for i, rec := range s.records {
+		if after != "" && rec.Id == after {
+			recs := s.records[i+1:]
+			if len(recs) > maxRecords {
+				recs = recs[:maxRecords]
+			}
+			writeJSON(w, append([]records.CallRecord{}, recs...))
+			return
+		}
+	}
+	var from int64
+	if after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "Неверный ID записи: "+after)
+			return
+		}
+		from = id
+	}
+	recs := []records.CallRecord{}
+	for _, rec := range s.records {
+		if id, err := strconv.ParseInt(rec.Id, 10, 64); err == nil && id <= from {
+			continue
+		}
+		if len(recs) == maxRecords {
+			break
+		}
+		recs = append(recs, rec)
+	}
+	writeJSON(w, recs)
+}
+
+// findRecord Возвращает индекс записи разговора по идентификатору.
+// Если запись не найдена, отправляет ответ 404 и возвращает false.
+func (s *Server) findRecord(w http.ResponseWriter, id string) (int, bool) {
+	for i, rec := range s.records {
+		if rec.Id == id {
+			return i, true
+		}
+	}
+	notFound(w, "Запись", id)
+	return 0, false
+}
+
+// findRecordFromEvent Возвращает индекс записи разговора по ID разговора и ID пользователя из пути запроса.
+// Если запись не найдена, отправляет ответ 404 и возвращает false.
+func (s *Server) findRecordFromEvent(w http.ResponseWriter, r *http.Request) (int, bool) {
+	key := r.PathValue("callId") + "/" + r.PathValue("userId")
+	id, ok := s.recordEvents[key]
+	if !ok {
+		notFound(w, "Запись", key)
+		return 0, false
+	}
+	return s.findRecord(w, id)
+}
+
+func (s *Server) getRecordFromEvent(w http.ResponseWriter, r *http.Request) {
+	if i, ok := s.findRecordFromEvent(w, r); ok {
+		writeJSON(w, s.records[i])
+	}
+}
+
+func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findRecord(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	var upd records.RecordUpdate
+	if !readJSON(w, r, &upd) {
+		return
+	}
+	if upd.Comment != nil {
+		s.records[i].Comment = *upd.Comment
+	}
+	if upd.ExternalId != nil {
+		s.records[i].ExternalId = *upd.ExternalId
+	}
+}
+
+func (s *Server) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findRecord(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	delete(s.files, s.records[i].Id)
+	s.records = append(s.records[:i], s.records[i+1:]...)
+}
+
+// writeFile Отправляет файл записи разговора
+func (s *Server) writeFile(w http.ResponseWriter, i int) {
+	file := s.files[s.records[i].Id]
+	w.Header().Set("Content-Type", http.DetectContentType(file))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file)))
+	w.Write(file)
+}
+
+func (s *Server) downloadRecord(w http.ResponseWriter, r *http.Request) {
+	if i, ok := s.findRecord(w, r.PathValue("id")); ok {
+		s.writeFile(w, i)
+	}
+}
+
+func (s *Server) downloadRecordFromEvent(w http.ResponseWriter, r *http.Request) {
+	if i, ok := s.findRecordFromEvent(w, r); ok {
+		s.writeFile(w, i)
+	}
+}
+
+//  ------------------------------------- Операции со входящими номерами  -------------------------------------
+
+// findNumber Ищет входящий номер по идентификатору или номеру телефона
+func (s *Server) findNumber(pattern string) (icr.NumberInfo, bool) {
+	for _, n := range s.numbers {
+		if n.NumberId == pattern || n.Phone == pattern {
+			return n, true
+		}
+	}
+	return icr.NumberInfo{}, false
+}
+
+func (s *Server) getNumbers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, append([]icr.NumberInfo{}, s.numbers...))
+}
+
+func (s *Server) getNumber(w http.ResponseWriter, r *http.Request) {
+	n, ok := s.findNumber(r.PathValue("pattern"))
+	if !ok {
+		notFound(w, "Номер", r.PathValue("pattern"))
+		return
+	}
+	writeJSON(w, n)
+}
+
+//  ------------------------------------- Подписка на Xsi-Events  -------------------------------------
+
+func (s *Server) subscribe(w http.ResponseWriter, r *http.Request) {
+	var req xsi.SubscriptionRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	info := xsi.SubscriptionInfo{
+		SubscriptionId:   fmt.Sprintf("sub-%d", s.newId()),
+		TargetType:       beelineapi.GROUP,
+		SubscriptionType: req.SubscriptionType,
+		Expires:          req.Expires,
+		Url:              req.Url,
+	}
+	if req.Pattern != "" {
+		info.TargetId = req.Pattern
+		info.TargetType = beelineapi.NUMBER
+		if _, ok := s.findNumber(req.Pattern); !ok {
+			r.SetPathValue("pattern", req.Pattern)
+			a, ok := s.findAbonent(w, r)
+			if !ok {
+				return
+			}
+			info.TargetType, info.TargetId = beelineapi.ABONENT, a.UserId
+		}
+	}
+	s.subscriptions[info.SubscriptionId] = info
+	writeJSON(w, xsi.SubscriptionResult{SubscriptionId: info.SubscriptionId, Expires: info.Expires})
+}
+
+func (s *Server) getSubscription(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.subscriptions[r.PathValue("id")]
+	if !ok {
+		notFound(w, "Подписка", r.PathValue("id"))
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *Server) unsubscribe(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.subscriptions[r.PathValue("id")]; !ok {
+		notFound(w, "Подписка", r.PathValue("id"))
+		return
+	}
+	delete(s.subscriptions, r.PathValue("id"))
+}
+
+//  ------------------------------------- Индивидуальная переадресация  -------------------------------------
+
+// icrFault Возвращает описание ошибки операции индивидуальной переадресации
+func icrFault(code string, description string) icr.IcrOperationError {
+	return icr.IcrOperationError{ErrorCode: code, Description: description}
+}
+
+func (s *Server) getIcrNumbers(w http.ResponseWriter, r *http.Request) {
+	nums := []icr.NumberInfo{}
+	for _, n := range s.numbers {
+		if s.icrNumbers[n.Phone] {
+			nums = append(nums, n)
+		}
+	}
+	writeJSON(w, nums)
+}
+
+func (s *Server) setIcrNumbers(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var phones []string
+		if !readJSON(w, r, &phones) {
+			return
+		}
+		res := []icr.IcrNumbersResult{}
+		for _, p := range phones {
+			n, ok := s.findNumber(p)
+			if !ok {
+				res = append(res, icr.IcrNumbersResult{PhoneNumber: p, Status: beelineapi.FAULT, Error: icrFault("NumberNotFound", "Номер не найден")})
+				continue
+			}
+			s.icrNumbers[n.Phone] = enabled
+			res = append(res, icr.IcrNumbersResult{PhoneNumber: p, Status: beelineapi.SUCCESS})
+		}
+		writeJSON(w, res)
+	}
+}
+
+func (s *Server) getIcrRoutes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, append([]icr.IcrRouteRule{}, s.icrRoutes...))
+}
+
+// checkIcrRule Проверяет, что для входящего номера правила включена индивидуальная переадресация
+func (s *Server) checkIcrRule(rule icr.IcrRouteRule) icr.IcrRouteResult {
+	if !s.icrNumbers[rule.InboundNumber] {
+		return icr.IcrRouteResult{Rule: rule, Status: beelineapi.FAULT, Error: icrFault("IcrNotEnabled", "Для номера не включена индивидуальная переадресация")}
+	}
+	return icr.IcrRouteResult{Rule: rule, Status: beelineapi.SUCCESS}
+}
+
+func (s *Server) replaceIcrRoutes(w http.ResponseWriter, r *http.Request) {
+	var rules []icr.IcrRouteRule
+	if !readJSON(w, r, &rules) {
+		return
+	}
+	res := []icr.IcrRouteResult{}
+	routes := []icr.IcrRouteRule{}
+	for _, rule := range rules {
+		rr := s.checkIcrRule(rule)
+		if rr.Status == beelineapi.SUCCESS {
+			routes = append(routes, rule)
+		}
+		res = append(res, rr)
+	}
+	s.icrRoutes = routes
+	writeJSON(w, res)
+}
+
+func (s *Server) unionIcrRoutes(w http.ResponseWriter, r *http.Request) {
+	var rules []icr.IcrRouteRule
+	if !readJSON(w, r, &rules) {
+		return
+	}
+	res := []icr.IcrRouteResult{}
+	for _, rule := range rules {
+		rr := s.checkIcrRule(rule)
+		res = append(res, rr)
+		if rr.Status != beelineapi.SUCCESS {
+			continue
+		}
+		replaced := false
+		for i := range s.icrRoutes {
+			if s.icrRoutes[i].InboundNumber == rule.InboundNumber {
+				s.icrRoutes[i], replaced = rule, true
+			}
+		}
+		if !replaced {
+			s.icrRoutes = append(s.icrRoutes, rule)
+		}
+	}
+	writeJSON(w, res)
+}
+
+func (s *Server) deleteIcrRoutes(w http.ResponseWriter, r *http.Request) {
+	var rules []icr.IcrRouteRule
+	if !readJSON(w, r, &rules) {
+		return
+	}
+	res := []icr.IcrRouteResult{}
+	for _, rule := range rules {
+		found := false
+		for i, existing := range s.icrRoutes {
+			if existing == rule {
+				s.icrRoutes = append(s.icrRoutes[:i], s.icrRoutes[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, icr.IcrRouteResult{Rule: rule, Status: beelineapi.FAULT, Error: icrFault("RuleNotFound", "Правило не найдено")})
+			continue
+		}
+		res = append(res, icr.IcrRouteResult{Rule: rule, Status: beelineapi.SUCCESS})
+	}
+	writeJSON(w, res)
+}
@@ -0,0 +1,229 @@
+// Package beelinetest содержит имитатор портала облачной АТС Билайн для тестов.
+// Server хранит состояние в памяти, проверяет ключ безопасности и позволяет
+// внедрять ошибки, поэтому с ним можно тестировать интеграцию без доступа к серверу Билайн.
+package beelinetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/icr"
+	"github.com/taigasys/beeline-portal-api/records"
+	"github.com/taigasys/beeline-portal-api/xsi"
+)
+
+// Server Имитатор портала Билайн
+type Server struct {
+	*httptest.Server
+	Token string // Ключ безопасности, который должен передаваться в заголовке X-MPBX-API-AUTH-TOKEN
+
+	mu            sync.Mutex
+	mux           *http.ServeMux
+	nextId        int
+	abonents      []abonents.Abonent
+	agents        map[string]beelineapi.AgentStatus   // Статус агента call-центра по идентификатору абонента
+	recording     map[string]beelineapi.ServiceStatus // Статус записи разговоров по идентификатору абонента
+	extraNumbers  map[string]string                   // Дополнительный номер по идентификатору абонента
+	bfs           map[string]*forwarding.BasicRedirectResponse
+	cfs           map[string]*forwarding.CfsStatusResponse
+	bwl           map[string]*bwl.BwlStatusResponse
+	records       []records.CallRecord
+	recordEvents  map[string]string // Идентификатор записи по ключу "ID разговора/ID пользователя"
+	files         map[string][]byte
+	numbers       []icr.NumberInfo
+	icrNumbers    map[string]bool
+	icrRoutes     []icr.IcrRouteRule
+	subscriptions map[string]xsi.SubscriptionInfo
+	calls         []Call
+	failures      []*failure
+}
+
+// Call Звонок, совершенный через имитатор от имени абонента
+type Call struct {
+	Id     string // Идентификатор звонка
+	UserId string // Идентификатор абонента
+	Phone  string // Номер телефона
+}
+
+// failure Внедренная ошибка
+type failure struct {
+	method string
+	path   string
+	status int
+	err    beelineapi.APIError
+	times  int // Оставшееся количество срабатываний, 0 - без ограничений
+}
+
+// NewServer Запускает имитатор портала с ключом безопасности token.
+// После использования сервер необходимо остановить методом Close.
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:         token,
+		mux:           http.NewServeMux(),
+		agents:        map[string]beelineapi.AgentStatus{},
+		recording:     map[string]beelineapi.ServiceStatus{},
+		extraNumbers:  map[string]string{},
+		bfs:           map[string]*forwarding.BasicRedirectResponse{},
+		cfs:           map[string]*forwarding.CfsStatusResponse{},
+		bwl:           map[string]*bwl.BwlStatusResponse{},
+		recordEvents:  map[string]string{},
+		files:         map[string][]byte{},
+		icrNumbers:    map[string]bool{},
+		subscriptions: map[string]xsi.SubscriptionInfo{},
+	}
+	s.routes()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client Возвращает клиента API, настроенного на имитатор, с дополнительными параметрами opts
+func (s *Server) Client(opts ...beelineapi.Option) (*beelineapi.APIClient, error) {
+	return beelineapi.NewClient(s.Token, append([]beelineapi.Option{beelineapi.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// AddAbonent Добавляет абонента. Абонент создается со статусом агента OFFLINE и выключенной записью разговоров.
+func (s *Server) AddAbonent(a abonents.Abonent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.abonents = append(s.abonents, a)
+	s.agents[a.UserId] = beelineapi.OFFLINE
+	s.recording[a.UserId] = beelineapi.OFF
+}
+
+// AddRecord Добавляет запись разговора с содержимым файла file.
+// callId - Идентификатор разговора из события, по которому запись можно найти вместе с rec.Abonent.UserId
+func (s *Server) AddRecord(rec records.CallRecord, callId string, file []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	s.files[rec.Id] = file
+	if callId != "" {
+		s.recordEvents[callId+"/"+rec.Abonent.UserId] = rec.Id
+	}
+}
+
+// AddNumber Добавляет входящий номер
+func (s *Server) AddNumber(n icr.NumberInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.numbers = append(s.numbers, n)
+}
+
+// Records Возвращает копию текущего списка записей разговоров
+func (s *Server) Records() []records.CallRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]records.CallRecord(nil), s.records...)
+}
+
+// Calls Возвращает звонки, совершенные через имитатор
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// InjectError Заставляет сервер отвечать HTTP кодом status на запросы method к path.
+// times - Количество ответов с ошибкой, 0 - пока ошибка не будет сброшена методом ResetErrors
+func (s *Server) InjectError(method string, path string, status int, apiErr beelineapi.APIError, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{method: method, path: path, status: status, err: apiErr, times: times})
+}
+
+// ResetErrors Сбрасывает все внедренные ошибки
+func (s *Server) ResetErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// serve Проверяет ключ безопасности и внедренные ошибки, затем передает запрос обработчику
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-MPBX-API-AUTH-TOKEN") != s.Token {
+		writeError(w, http.StatusUnauthorized, "AuthenticationFailed", "Неверный ключ безопасности")
+		return
+	}
+	if f := s.takeFailure(r); f != nil {
+		writeError(w, f.status, f.err.ErrorCode, f.err.Description)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.ServeHTTP(w, r)
+}
+
+// takeFailure Возвращает внедренную ошибку для запроса, если она есть
+func (s *Server) takeFailure(r *http.Request) *failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.failures {
+		if f.method != r.Method || f.path != r.URL.Path {
+			continue
+		}
+		if f.times > 0 {
+			f.times--
+			if f.times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// newId Возвращает новый уникальный числовой идентификатор
+func (s *Server) newId() int {
+	s.nextId++
+	return s.nextId
+}
+
+// writeJSON Отправляет v в формате JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", beelineapi.CONTENTTYPE)
+	w.Write(b)
+}
+
+// writeError Отправляет ошибку в формате портала
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", beelineapi.CONTENTTYPE)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(beelineapi.APIError{ErrorCode: code, Description: description})
+}
+
+// readJSON Разбирает тело запроса в v. При ошибке отправляет ответ 400 и возвращает false.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return false
+	}
+	return true
+}
+
+// notFound Отправляет ответ 404
+func notFound(w http.ResponseWriter, what string, id string) {
+	writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s не найден", what, id))
+}
+
+// pathInt Возвращает числовой параметр пути name. При ошибке отправляет ответ 400 и возвращает false.
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", fmt.Sprintf("Неверный параметр %s: %s", name, r.PathValue(name)))
+		return 0, false
+	}
+	return v, true
+}
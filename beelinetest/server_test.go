@@ -0,0 +1,151 @@
+package beelinetest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/icr"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// do Отправляет запрос к имитатору и разбирает ответ в v
+func do(t *testing.T, s *Server, method string, path string, body string, v interface{}) int {
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Не удалось подготовить запрос: %s", err)
+	}
+	req.Header.Set("X-MPBX-API-AUTH-TOKEN", s.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Не удалось отправить запрос: %s", err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Не удалось разобрать ответ на %s %s: %s", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// TestRecords Тест на операции с записями разговоров через клиента API
+func TestRecords(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	s.AddRecord(records.CallRecord{Id: "1", Direction: beelineapi.INBOUND, Abonent: abonents.Abonent{UserId: "u1"}}, "call1", []byte("RIFF...."))
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	c := records.New(client)
+
+	rec, err := c.GetRecordInfoFromEvent("call1", "u1")
+	if err != nil || rec.Id != "1" {
+		t.Fatalf("Не удалось найти запись по событию: %v %+v", err, rec)
+	}
+	if _, err := c.GetRecordInfoFromEvent("call2", "u1"); err == nil {
+		t.Fatal("Ожидалась ошибка RecordNotFoundError")
+	} else if _, ok := err.(records.RecordNotFoundError); !ok {
+		t.Fatalf("Ожидалась ошибка RecordNotFoundError, получено %v", err)
+	}
+	r, err := c.GetRecordFile("1")
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл записи: %s", err)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != "RIFF...." {
+		t.Fatalf("Неверное содержимое файла записи: %q", b)
+	}
+	if err := c.SetRecordComment("1", "TICKET-1"); err != nil {
+		t.Fatalf("Не удалось изменить комментарий: %s", err)
+	}
+	if got := s.Records()[0].Comment; got != "TICKET-1" {
+		t.Fatalf("Комментарий не сохранен. Получено %q", got)
+	}
+	if err := c.DeleteRecord("1"); err != nil {
+		t.Fatalf("Не удалось удалить запись: %s", err)
+	}
+	if len(s.Records()) != 0 {
+		t.Fatal("Запись не удалена")
+	}
+}
+
+// TestToken Тест на проверку ключа безопасности
+func TestToken(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	c, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	c.Token = "wrong"
+	_, err = records.New(c).GetRecords(0)
+	se, ok := err.(beelineapi.StatusError)
+	if !ok || se.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Ожидалась ошибка 401, получено %v", err)
+	}
+}
+
+// TestInjectError Тест на внедрение ошибок
+func TestInjectError(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	s.AddRecord(records.CallRecord{Id: "1"}, "", nil)
+	s.InjectError("GET", "/v2/records/1", http.StatusServiceUnavailable, beelineapi.APIError{ErrorCode: "Unavailable"}, 1)
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	c := records.New(client)
+	if _, err := c.GetRecords(1); err == nil {
+		t.Fatal("Ожидалась внедренная ошибка")
+	}
+	if _, err := c.GetRecords(1); err != nil {
+		t.Fatalf("Ошибка должна срабатывать один раз, получено %s", err)
+	}
+}
+
+// TestStatefulSettings Тест на сохранение настроек абонента между запросами
+func TestStatefulSettings(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Phone: "9000000001", Extension: "101"})
+	s.AddNumber(icr.NumberInfo{NumberId: "n1", Phone: "4950000001"})
+
+	if code := do(t, s, "PUT", "/abonents/101/agent?status=BREAK", "", nil); code != http.StatusOK {
+		t.Fatalf("Не удалось установить статус агента: %d", code)
+	}
+	var status string
+	do(t, s, "GET", "/abonents/u1/agent", "", &status)
+	if status != "BREAK" {
+		t.Fatalf("Неверный статус агента. Ожидалось BREAK получено %s", status)
+	}
+
+	var id int
+	do(t, s, "POST", "/abonents/u1/bwl/rule", `{"type":"WHITE_LIST","rule":{"name":"vip","schedule":"WORKING_TIME","phoneList":["9000000002"]}}`, &id)
+	var st bwl.BwlStatusResponse
+	do(t, s, "GET", "/abonents/9000000001/bwl", "", &st)
+	if len(st.WhiteList) != 1 || st.WhiteList[0].Id != id || st.WhiteList[0].Schedule != beelineapi.WORKING_TIME {
+		t.Fatalf("Правило выборочного приема звонков не сохранено: %+v", st)
+	}
+
+	var res []icr.IcrRouteResult
+	do(t, s, "POST", "/icr/route", `[{"inboundNumber":"4950000001","extension":"101"}]`, &res)
+	if len(res) != 1 || res[0].Status != beelineapi.FAULT {
+		t.Fatalf("Правило для номера без индивидуальной переадресации должно отклоняться: %+v", res)
+	}
+	do(t, s, "PUT", "/icr/numbers", `["4950000001"]`, nil)
+	do(t, s, "POST", "/icr/route", `[{"inboundNumber":"4950000001","extension":"101"}]`, &res)
+	if len(res) != 1 || res[0].Status != beelineapi.SUCCESS {
+		t.Fatalf("Не удалось добавить правило индивидуальной переадресации: %+v", res)
+	}
+
+	if code := do(t, s, "GET", "/abonents/unknown", "", nil); code != http.StatusNotFound {
+		t.Fatalf("Ожидался ответ 404 для неизвестного абонента, получено %d", code)
+	}
+}
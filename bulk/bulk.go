@@ -0,0 +1,167 @@
+// Package bulk выполняет операции над всеми абонентами отдела облачной АТС Билайн одновременно
+// и возвращает отчет с результатом по каждому абоненту.
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+// DefaultConcurrency Количество одновременных запросов по умолчанию
+const DefaultConcurrency = 8
+
+// Result Результат операции для абонента
+type Result struct {
+	Abonent abonents.Abonent `json:"abonent"`
+	Err     error            `json:"-"`
+	Error   string           `json:"error,omitempty"` // Текст ошибки Err
+}
+
+// NewResult Возвращает результат операции для абонента a
+// err - ошибка операции или nil
+func NewResult(a abonents.Abonent, err error) Result {
+	res := Result{Abonent: a, Err: err}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Report Отчет о выполнении операции в порядке абонентов
+type Report struct {
+	Department string   `json:"department,omitempty"` // Отдел, если операция выполнялась над отделом
+	Results    []Result `json:"results"`
+}
+
+// Failed Возвращает результаты с ошибками
+func (r Report) Failed() []Result {
+	res := []Result{}
+	for _, x := range r.Results {
+		if x.Err != nil {
+			res = append(res, x)
+		}
+	}
+	return res
+}
+
+// Err Возвращает ошибку со списком абонентов, для которых операция не выполнена, или nil
+func (r Report) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	lines := []string{}
+	for _, x := range failed {
+		lines = append(lines, x.Abonent.UserId+": "+x.Err.Error())
+	}
+	msg := fmt.Sprintf("Операция не выполнена для %d из %d абонентов", len(failed), len(r.Results))
+	if r.Department != "" {
+		msg += " отдела " + r.Department
+	}
+	return beelineapi.WrapError{Msg: msg + ":\n" + strings.Join(lines, "\n"), Err: failed[0].Err}
+}
+
+// Runner Выполняет операции над абонентами отдела
+type Runner struct {
+	Concurrency int // Количество одновременных запросов, по умолчанию DefaultConcurrency
+
+	resolver   *abonents.Resolver
+	abonents   *abonents.Service
+	forwarding *forwarding.Service
+}
+
+// New Возвращает Runner для клиента c. Абоненты отдела ищутся в справочнике r.
+func New(c *beelineapi.APIClient, r *abonents.Resolver) *Runner {
+	return &Runner{resolver: r, abonents: abonents.New(c), forwarding: forwarding.New(c)}
+}
+
+// Department Выполняет операцию op для всех абонентов отдела одновременно.
+// Ошибка возвращается, только если не удалось получить список абонентов;
+// ошибки операции для отдельных абонентов содержатся в отчете.
+// При отмене ctx операции для оставшихся абонентов не выполняются и завершаются ошибкой ctx.
+// department - Название отдела без учета регистра
+// op - Операция для абонента
+func (b *Runner) Department(ctx context.Context, department string, op func(a abonents.Abonent) error) (Report, error) {
+	list, err := b.resolver.Department(department)
+	if err != nil {
+		return Report{}, err
+	}
+	if len(list) == 0 {
+		return Report{}, beelineapi.WrapError{Msg: "В отделе " + department + " нет абонентов"}
+	}
+	rep := Report{Department: list[0].Department, Results: make([]Result, len(list))}
+	n := b.Concurrency
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, a := range list {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			rep.Results[i] = NewResult(a, ctx.Err())
+			continue
+		}
+		wg.Add(1)
+		go func(i int, a abonents.Abonent) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := ctx.Err(); err != nil {
+				rep.Results[i] = NewResult(a, err)
+				return
+			}
+			rep.Results[i] = NewResult(a, op(a))
+		}(i, a)
+	}
+	wg.Wait()
+	return rep, nil
+}
+
+// SetAgentStatus Устанавливает статус агента call-центра всем абонентам отдела
+// status - Новый статус агента: ONLINE, OFFLINE или BREAK
+func (b *Runner) SetAgentStatus(ctx context.Context, department string, status beelineapi.AgentStatus) (Report, error) {
+	return b.Department(ctx, department, func(a abonents.Abonent) error {
+		return b.abonents.SetAgentStatus(a.UserId, status)
+	})
+}
+
+// TurnOnRecording Включает запись разговоров всем абонентам отдела
+func (b *Runner) TurnOnRecording(ctx context.Context, department string) (Report, error) {
+	return b.Department(ctx, department, func(a abonents.Abonent) error {
+		return b.abonents.TurnOnRecording(a.UserId)
+	})
+}
+
+// TurnOffRecording Отключает запись разговоров всем абонентам отдела
+func (b *Runner) TurnOffRecording(ctx context.Context, department string) (Report, error) {
+	return b.Department(ctx, department, func(a abonents.Abonent) error {
+		return b.abonents.TurnOffRecording(a.UserId)
+	})
+}
+
+// TurnOnBasicRedirect Включает базовую переадресацию всем абонентам отдела
+// br - Номера для переадресации
+func (b *Runner) TurnOnBasicRedirect(ctx context.Context, department string, br forwarding.BasicRedirect) (Report, error) {
+	// Номера проверяются заранее, чтобы не получить одну и ту же ошибку для каждого абонента
+	br, err := br.Normalize()
+	if err != nil {
+		return Report{}, err
+	}
+	return b.Department(ctx, department, func(a abonents.Abonent) error {
+		return b.forwarding.TurnOnBasicRedirect(a.UserId, br)
+	})
+}
+
+// TurnOffBasicRedirect Отключает базовую переадресацию всем абонентам отдела
+func (b *Runner) TurnOffBasicRedirect(ctx context.Context, department string) (Report, error) {
+	return b.Department(ctx, department, func(a abonents.Abonent) error {
+		return b.forwarding.TurnOffBasicRedirect(a.UserId)
+	})
+}
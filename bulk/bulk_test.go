@@ -0,0 +1,57 @@
+package bulk_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bulk"
+)
+
+// TestSetAgentStatus Тест на установку статуса агента всему отделу с отчетом по абонентам
+func TestSetAgentStatus(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	for _, id := range []string{"u1", "u2", "u3"} {
+		s.AddAbonent(abonents.Abonent{UserId: id, Department: "Смена 1"})
+	}
+	s.AddAbonent(abonents.Abonent{UserId: "u4", Department: "Смена 2"})
+	s.InjectError("PUT", "/abonents/u2/agent", http.StatusInternalServerError, beelineapi.APIError{}, 0)
+	c, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := abonents.New(c)
+	b := bulk.New(c, abonents.NewResolver(svc, 0))
+
+	rep, err := b.SetAgentStatus(context.Background(), "смена 1", beelineapi.BREAK)
+	if err != nil {
+		t.Fatalf("Не удалось выполнить операцию: %s", err)
+	}
+	if len(rep.Results) != 3 || len(rep.Failed()) != 1 || rep.Failed()[0].Abonent.UserId != "u2" || rep.Err() == nil {
+		t.Fatalf("Неверный отчет: %+v", rep)
+	}
+	data, _ := json.Marshal(rep)
+	var decoded bulk.Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Results[1].Error != rep.Failed()[0].Err.Error() {
+		t.Fatalf("Ошибка не выводится в JSON: %s", data)
+	}
+	for id, want := range map[string]beelineapi.AgentStatus{"u1": beelineapi.BREAK, "u3": beelineapi.BREAK, "u4": beelineapi.OFFLINE} {
+		if st, _ := svc.GetAgentStatus(id); st != want {
+			t.Fatalf("Неверный статус агента %s: %d", id, st)
+		}
+	}
+	if _, err := b.TurnOnRecording(context.Background(), "Склад"); err == nil {
+		t.Fatal("Ожидалась ошибка для отдела без абонентов")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rep, _ = b.TurnOnRecording(ctx, "Смена 1")
+	if len(rep.Failed()) != 3 {
+		t.Fatalf("После отмены операции не должны выполняться: %+v", rep)
+	}
+}
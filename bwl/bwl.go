@@ -0,0 +1,143 @@
+// Package bwl содержит операции с выборочным приемом звонков (черные и белые списки) облачной АТС Билайн
+package bwl
+
+import (
+	"fmt"
+	"net/url"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Статусы выборочного приема звонков
+const (
+	BLACK_LIST_ON = beelineapi.BLACK_LIST_ON // Не принимать звонки с указанных в списке правил номеров
+	WHITE_LIST_ON = beelineapi.WHITE_LIST_ON // Принимать звонки только с указанных в списке правил номеров
+	OFF           = beelineapi.BWL_OFF       // Услуга отключена
+)
+
+// BwlStatusResponse
+type BwlStatusResponse struct {
+	Status    beelineapi.BwlStatus `json:"status"` // BLACK_LIST_ON, WHITE_LIST_ON или OFF
+	BlackList []BwlRule            `json:"blackList"`
+	WhiteList []BwlRule            `json:"whiteList"`
+}
+
+// BwlRule
+type BwlRule struct {
+	Id        int                 `json:"id"`
+	Name      string              `json:"name"`
+	Schedule  beelineapi.Schedule `json:"schedule"`
+	PhoneList []string            `json:"phoneList"`
+}
+
+// BwlRuleAdd
+type BwlRuleAdd struct {
+	Type beelineapi.BwlListType `json:"type"`
+	Rule BwlRuleUpdate          `json:"rule"`
+}
+
+// BwlRuleUpdate Запрос для обновления правила
+type BwlRuleUpdate struct {
+	Name      string              `json:"name"`
+	Schedule  beelineapi.Schedule `json:"schedule"`
+	PhoneList []string            `json:"phoneList"`
+}
+
+// Service Операции с выборочным приемом звонков
+type Service struct {
+	c *beelineapi.APIClient
+}
+
+// New Возвращает операции с выборочным приемом звонков для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{c: c}
+}
+
+// IncCallRules Статус и список правил для выборочного приема звонков
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) IncCallRules(id string) (BwlStatusResponse, error) {
+	bwl := BwlStatusResponse{}
+	if err := s.c.RequestJSON("GET", abonentPath(id, "bwl"), nil, &bwl); err != nil {
+		return bwl, beelineapi.Wrap("Ошибка при получении правил выборочного приема звонков. ", err)
+	}
+	return bwl, nil
+}
+
+// AddIncCallRule Добавляет правило для выборочного приема звонков и возвращает его идентификатор
+// id - Идентификатор, мобильный или добавочный номер абонента
+// rule - Запрос для добавления правила
+func (s *Service) AddIncCallRule(id string, rule BwlRuleAdd) (int, error) {
+	var ruleID int
+	var err error
+	if rule.Rule, err = rule.Rule.Normalize(); err != nil {
+		return 0, beelineapi.Wrap("Ошибка при добавлении правила выборочного приема звонков. ", err)
+	}
+	if err := s.c.RequestJSON("POST", abonentPath(id, "bwl/rule"), rule, &ruleID); err != nil {
+		return 0, beelineapi.Wrap("Ошибка при добавлении правила выборочного приема звонков. ", err)
+	}
+	return ruleID, nil
+}
+
+// TurnOnSelectiveCallReceive Включает выборочный прием звонков
+// id - Идентификатор, мобильный или добавочный номер абонента
+// t - Тип правила
+func (s *Service) TurnOnSelectiveCallReceive(id string, t beelineapi.BwlListType) error {
+	if !t.Valid() {
+		return beelineapi.WrapError{Msg: fmt.Sprintf("Недопустимый тип списка правил: %s", t)}
+	}
+	if _, err := s.c.Request("PUT", abonentPath(id, "bwl?type="+t.String()), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при включении выборочного приема звонков. ", err)
+	}
+	return nil
+}
+
+// UpdateSelectiveReceiveRule Обновляет правило для выборочного приема звонков
+// id - Идентификатор, мобильный или добавочный номер абонента
+// ruleID - Идентификатор правила
+// ruleUpdate -Запрос для обновления правила
+func (s *Service) UpdateSelectiveReceiveRule(id string, ruleID int, ruleUpdate BwlRuleUpdate) error {
+	ruleUpdate, err := ruleUpdate.Normalize()
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при обновлении правила выборочного приема звонков. ", err)
+	}
+	if _, err := s.c.Request("PUT", abonentPath(id, fmt.Sprintf("bwl/rule/%d", ruleID)), ruleUpdate); err != nil {
+		return beelineapi.Wrap("Ошибка при обновлении правила выборочного приема звонков. ", err)
+	}
+	return nil
+}
+
+// TurnOffSelectiveReceiveRule Отключает выборочный прием звонков
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOffSelectiveReceiveRule(id string) error {
+	if _, err := s.c.Request("DELETE", abonentPath(id, "bwl"), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при отключении выборочного приема звонков. ", err)
+	}
+	return nil
+}
+
+// DeleteSelectiveReceiveRule Удаляет правило для выборочного приема звонков
+// id - Идентификатор, мобильный или добавочный номер абонента
+// ruleID - Идентификатор правила
+func (s *Service) DeleteSelectiveReceiveRule(id string, ruleID int) error {
+	if _, err := s.c.Request("DELETE", abonentPath(id, fmt.Sprintf("bwl/rule/%d", ruleID)), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при удалении правила выборочного приема звонков. ", err)
+	}
+	return nil
+}
+
+// Normalize Возвращает правило с номерами, приведенными к формату API.
+// Незаданное расписание заменяется на ROUND_THE_CLOCK.
+func (r BwlRuleUpdate) Normalize() (BwlRuleUpdate, error) {
+	list, err := beelineapi.NormalizePhones(r.PhoneList)
+	if err != nil {
+		return r, err
+	}
+	r.PhoneList = list
+	r.Schedule = r.Schedule.Effective()
+	return r, nil
+}
+
+// abonentPath Возвращает путь к настройке абонента
+func abonentPath(id string, setting string) string {
+	return "abonents/" + url.PathEscape(id) + "/" + setting
+}
@@ -0,0 +1,75 @@
+package bwl_test
+
+import (
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bwl"
+)
+
+// TestIncCallRules Тест на операции с правилами выборочного приема звонков
+func TestIncCallRules(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := bwl.New(client)
+
+	rule := bwl.BwlRuleAdd{Type: beelineapi.BLACK_LIST, Rule: bwl.BwlRuleUpdate{Name: "spam", PhoneList: []string{"9000000009"}}}
+	id, err := svc.AddIncCallRule("u1", rule)
+	if err != nil {
+		t.Fatalf("Не удалось добавить правило: %s", err)
+	}
+	if err := svc.TurnOnSelectiveCallReceive("u1", beelineapi.BLACK_LIST); err != nil {
+		t.Fatalf("Не удалось включить выборочный прием звонков: %s", err)
+	}
+	st, err := svc.IncCallRules("u1")
+	if err != nil || st.Status != bwl.BLACK_LIST_ON || len(st.BlackList) != 1 || st.BlackList[0].Id != id {
+		t.Fatalf("Неверные правила выборочного приема звонков: %v %+v", err, st)
+	}
+	if err := svc.DeleteSelectiveReceiveRule("u1", id); err != nil {
+		t.Fatalf("Не удалось удалить правило: %s", err)
+	}
+	if err := svc.TurnOffSelectiveReceiveRule("u1"); err != nil {
+		t.Fatalf("Не удалось отключить выборочный прием звонков: %s", err)
+	}
+	if st, _ := svc.IncCallRules("u1"); st.Status != bwl.OFF || len(st.BlackList) != 0 {
+		t.Fatalf("Выборочный прием звонков не отключен: %+v", st)
+	}
+	if err := svc.TurnOnSelectiveCallReceive("u1", beelineapi.BwlListType(9)); err == nil {
+		t.Fatal("Ожидалась ошибка для недопустимого типа списка")
+	}
+}
+
+// TestEndpoints Тест на успешный ответ и ошибку сервера для каждой операции с выборочным приемом звонков
+func TestEndpoints(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := bwl.New(client)
+	rule := bwl.BwlRuleUpdate{Name: "spam", PhoneList: []string{"9000000009"}}
+	ruleId := 1 // Первый идентификатор, выдаваемый имитатором
+
+	if err := s.CheckEndpoints([]beelinetest.Endpoint{
+		{Name: "AddIncCallRule", Method: "POST", Path: "/abonents/u1/bwl/rule", Call: func() error {
+			_, err := svc.AddIncCallRule("u1", bwl.BwlRuleAdd{Type: beelineapi.BLACK_LIST, Rule: rule})
+			return err
+		}},
+		{Name: "IncCallRules", Method: "GET", Path: "/abonents/u1/bwl", Call: func() error { _, err := svc.IncCallRules("u1"); return err }},
+		{Name: "UpdateSelectiveReceiveRule", Method: "PUT", Path: "/abonents/u1/bwl/rule/1", Call: func() error { return svc.UpdateSelectiveReceiveRule("u1", ruleId, rule) }},
+		{Name: "TurnOnSelectiveCallReceive", Method: "PUT", Path: "/abonents/u1/bwl", Call: func() error { return svc.TurnOnSelectiveCallReceive("u1", beelineapi.BLACK_LIST) }},
+		{Name: "TurnOffSelectiveReceiveRule", Method: "DELETE", Path: "/abonents/u1/bwl", Call: func() error { return svc.TurnOffSelectiveReceiveRule("u1") }},
+		{Name: "DeleteSelectiveReceiveRule", Method: "DELETE", Path: "/abonents/u1/bwl/rule/1", Call: func() error { return svc.DeleteSelectiveReceiveRule("u1", ruleId) }},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+package bwl
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/internal/csvutil"
+)
+
+// Столбцы CSV с правилами выборочного приема звонков
+var csvHeader = []string{"type", "name", "schedule", "phones"}
+
+// ListRule Правило выборочного приема звонков вместе с типом списка, в котором оно находится
+type ListRule struct {
+	Type beelineapi.BwlListType
+	BwlRule
+}
+
+// Rules Возвращает правила обоих списков по порядку: сначала черный, затем белый
+func (st BwlStatusResponse) Rules() []ListRule {
+	rules := []ListRule{}
+	for _, r := range st.BlackList {
+		rules = append(rules, ListRule{Type: beelineapi.BLACK_LIST, BwlRule: r})
+	}
+	for _, r := range st.WhiteList {
+		rules = append(rules, ListRule{Type: beelineapi.WHITE_LIST, BwlRule: r})
+	}
+	return rules
+}
+
+// WriteCSV Записывает правила в формате CSV со столбцами type, name, schedule, phones.
+// Номера в столбце phones разделяются пробелами.
+func WriteCSV(w io.Writer, rules []ListRule) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, r := range rules {
+		cw.Write([]string{r.Type.String(), r.Name, r.Schedule.Effective().String(), strings.Join(r.PhoneList, " ")})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV Читает правила из CSV со столбцами type, name, schedule, phones.
+// Пустое расписание означает ROUND_THE_CLOCK. Номера разделяются пробелами, запятыми или точками с запятой.
+func ReadCSV(r io.Reader) ([]ListRule, error) {
+	t, err := csvutil.Read(r, "type", "name", "phones")
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при чтении правил выборочного приема звонков. ", err)
+	}
+	rules := []ListRule{}
+	seen := map[string]bool{}
+	for i, row := range t.Rows {
+		line := i + 2
+		lt, err := beelineapi.ParseBwlListType(strings.ToUpper(t.Get(row, "type")))
+		if err != nil {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: неверный тип списка %q", line, t.Get(row, "type")), Err: err}
+		}
+		sch := beelineapi.ROUND_THE_CLOCK
+		if s := t.Get(row, "schedule"); s != "" {
+			if sch, err = beelineapi.ParseSchedule(strings.ToUpper(s)); err != nil {
+				return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: неверное расписание %q", line, s), Err: err}
+			}
+		}
+		name := t.Get(row, "name")
+		if name == "" {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: не указано название правила", line)}
+		}
+		key := ruleKey(lt, name)
+		if seen[key] {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: правило %q повторяется в списке %s", line, name, lt)}
+		}
+		seen[key] = true
+		phones, err := beelineapi.NormalizePhones(csvutil.SplitList(t.Get(row, "phones")))
+		if err != nil {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: %s", line, err), Err: err}
+		}
+		rules = append(rules, ListRule{Type: lt, BwlRule: BwlRule{Name: name, Schedule: sch, PhoneList: phones}})
+	}
+	return rules, nil
+}
+
+// Plan Изменения, необходимые для приведения правил абонента к желаемому списку.
+// Правила сопоставляются по типу списка и названию.
+type Plan struct {
+	Add    []ListRule // Новые правила
+	Update []ListRule // Измененные правила с идентификаторами существующих
+	Delete []ListRule // Правила, которых нет в желаемом списке
+}
+
+// Empty Проверяет, что изменений нет
+func (p Plan) Empty() bool {
+	return len(p.Add) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// Diff Сравнивает текущие правила current с желаемыми desired и возвращает план изменений
+func Diff(current []ListRule, desired []ListRule) Plan {
+	p := Plan{}
+	existing := map[string]ListRule{}
+	for _, r := range current {
+		existing[ruleKey(r.Type, r.Name)] = r
+	}
+	for _, r := range desired {
+		key := ruleKey(r.Type, r.Name)
+		cur, ok := existing[key]
+		delete(existing, key)
+		switch {
+		case !ok:
+			p.Add = append(p.Add, r)
+		case cur.Schedule.Effective() != r.Schedule.Effective() || !samePhones(cur.PhoneList, r.PhoneList):
+			r.Id = cur.Id
+			p.Update = append(p.Update, r)
+		}
+	}
+	for _, r := range current {
+		if _, ok := existing[ruleKey(r.Type, r.Name)]; ok {
+			p.Delete = append(p.Delete, r)
+		}
+	}
+	return p
+}
+
+// ApplyPlan Применяет план изменений к правилам абонента: удаляет, обновляет и добавляет правила
+// id - Идентификатор, мобильный или добавочный номер абонента
+// p - План изменений
+func (s *Service) ApplyPlan(id string, p Plan) error {
+	for _, r := range p.Delete {
+		if err := s.DeleteSelectiveReceiveRule(id, r.Id); err != nil {
+			return err
+		}
+	}
+	for _, r := range p.Update {
+		if err := s.UpdateSelectiveReceiveRule(id, r.Id, BwlRuleUpdate{Name: r.Name, Schedule: r.Schedule, PhoneList: r.PhoneList}); err != nil {
+			return err
+		}
+	}
+	for _, r := range p.Add {
+		if _, err := s.AddIncCallRule(id, BwlRuleAdd{Type: r.Type, Rule: BwlRuleUpdate{Name: r.Name, Schedule: r.Schedule, PhoneList: r.PhoneList}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleKey Возвращает ключ для сопоставления правил
+func ruleKey(t beelineapi.BwlListType, name string) string {
+	return t.String() + "/" + name
+}
+
+// samePhones Сравнивает списки номеров без учета порядка
+func samePhones(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
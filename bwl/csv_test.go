@@ -0,0 +1,79 @@
+package bwl_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bwl"
+)
+
+// TestReadCSV Тест на чтение правил из CSV с разделителем ";" из электронной таблицы
+func TestReadCSV(t *testing.T) {
+	in := "\xef\xbb\xbfType;Name;Schedule;Phones\nblack_list;spam;;9000000001, 9000000002\nWHITE_LIST;vip;WORKING_TIME;9000000003\n"
+	rules, err := bwl.ReadCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Не удалось прочитать CSV: %s", err)
+	}
+	if len(rules) != 2 || rules[0].Type != beelineapi.BLACK_LIST || rules[0].Schedule != beelineapi.ROUND_THE_CLOCK ||
+		len(rules[0].PhoneList) != 2 || rules[1].Schedule != beelineapi.WORKING_TIME {
+		t.Fatalf("Неверно прочитаны правила: %+v", rules)
+	}
+	var out bytes.Buffer
+	if err := bwl.WriteCSV(&out, rules); err != nil {
+		t.Fatalf("Не удалось записать CSV: %s", err)
+	}
+	want := "type,name,schedule,phones\nBLACK_LIST,spam,ROUND_THE_CLOCK,9000000001 9000000002\nWHITE_LIST,vip,WORKING_TIME,9000000003\n"
+	if out.String() != want {
+		t.Fatalf("Неверный CSV. Ожидалось:\n%s\nполучено:\n%s", want, out.String())
+	}
+	for _, bad := range []string{"type,name,phones\nGREY_LIST,x,1\n", "type,name,phones\nBLACK_LIST,,1\n", "name,phones\nx,1\n", "type,name,phones\nBLACK_LIST,x,1\nBLACK_LIST,x,2\n"} {
+		if _, err := bwl.ReadCSV(strings.NewReader(bad)); err == nil {
+			t.Fatalf("Ожидалась ошибка для CSV:\n%s", bad)
+		}
+	}
+	// Ошибка разбора CSV сохраняется как исходная
+	_, err = bwl.ReadCSV(strings.NewReader("type,name,phones\n\"x,1\n"))
+	var pe *csv.ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Ожидалась ошибка csv.ParseError, получено %v", err)
+	}
+}
+
+// TestApplyPlan Тест на сравнение правил с желаемым списком и применение изменений
+func TestApplyPlan(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := bwl.New(client)
+	for _, name := range []string{"spam", "old"} {
+		if _, err := svc.AddIncCallRule("u1", bwl.BwlRuleAdd{Type: beelineapi.BLACK_LIST, Rule: bwl.BwlRuleUpdate{Name: name, PhoneList: []string{"9000000001"}}}); err != nil {
+			t.Fatalf("Не удалось добавить правило: %s", err)
+		}
+	}
+	desired := []bwl.ListRule{
+		{Type: beelineapi.BLACK_LIST, BwlRule: bwl.BwlRule{Name: "spam", PhoneList: []string{"9000000001", "9000000002"}}},
+		{Type: beelineapi.WHITE_LIST, BwlRule: bwl.BwlRule{Name: "vip", PhoneList: []string{"9000000003"}}},
+	}
+	st, _ := svc.IncCallRules("u1")
+	plan := bwl.Diff(st.Rules(), desired)
+	if len(plan.Add) != 1 || len(plan.Update) != 1 || len(plan.Delete) != 1 || plan.Delete[0].Name != "old" {
+		t.Fatalf("Неверный план изменений: %+v", plan)
+	}
+	if err := svc.ApplyPlan("u1", plan); err != nil {
+		t.Fatalf("Не удалось применить план: %s", err)
+	}
+	st, _ = svc.IncCallRules("u1")
+	if plan := bwl.Diff(st.Rules(), desired); !plan.Empty() {
+		t.Fatalf("После применения остались изменения: %+v", plan)
+	}
+}
@@ -0,0 +1,230 @@
+// Package calendar содержит календарь рабочего времени организации, от которого зависят
+// расписания WORKING_TIME и NON_WORKING_TIME_AND_HOLIDAYS правил выборочной переадресации
+// и выборочного приема звонков облачной АТС Билайн.
+// Календарь задает рабочие часы по дням недели, часовой пояс, нерабочие праздничные дни по ТК РФ,
+// а также переносы выходных и сокращенные предпраздничные дни из производственного календаря.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// DateFormat Формат даты особых дней календаря
+const DateFormat = "2006-01-02"
+
+// DayType Тип особого дня календаря
+type DayType int
+
+const (
+	HOLIDAY   DayType = iota + 1 // Нерабочий праздничный или выходной день
+	SHORT_DAY                    // Предпраздничный день, рабочее время сокращается на час
+	WORKDAY                      // Рабочий день, перенесенный с выходного
+)
+
+var dayTypeNames = []string{"", "holiday", "short", "workday"}
+
+func (d DayType) String() string {
+	if d < HOLIDAY || d > WORKDAY {
+		return fmt.Sprintf("DayType(%d)", int(d))
+	}
+	return dayTypeNames[d]
+}
+
+// Interval Интервал рабочего времени внутри дня, смещения от полуночи
+type Interval struct {
+	Start time.Duration // Начало
+	End   time.Duration // Окончание, не входит в интервал
+}
+
+// ParseInterval Разбирает интервал в формате 09:00-18:00
+func ParseInterval(s string) (Interval, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 2 {
+		return Interval{}, beelineapi.WrapError{Msg: fmt.Sprintf("Неверный интервал рабочего времени %q, ожидался формат 09:00-18:00", s)}
+	}
+	var iv Interval
+	for i, p := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(p))
+		if err != nil {
+			return Interval{}, beelineapi.WrapError{Msg: fmt.Sprintf("Неверный интервал рабочего времени %q, ожидался формат 09:00-18:00", s)}
+		}
+		d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if i == 0 {
+			iv.Start = d
+		} else {
+			iv.End = d
+		}
+	}
+	// 24:00 не разбирается time.Parse, поэтому конец дня задается как 00:00
+	if iv.End == 0 {
+		iv.End = 24 * time.Hour
+	}
+	if iv.End <= iv.Start {
+		return Interval{}, beelineapi.WrapError{Msg: fmt.Sprintf("Неверный интервал рабочего времени %q: окончание раньше начала", s)}
+	}
+	return iv, nil
+}
+
+func (iv Interval) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", int(iv.Start.Hours()), int(iv.Start.Minutes())%60, int(iv.End.Hours())%24, int(iv.End.Minutes())%60)
+}
+
+// Calendar Календарь рабочего времени
+type Calendar struct {
+	Location  *time.Location     // Часовой пояс. По умолчанию Europe/Moscow
+	Week      [7][]Interval      // Рабочие часы по дням недели, индекс - time.Weekday. Пустой список - выходной
+	Days      map[string]DayType // Особые дни по дате в формате DateFormat
+	Statutory bool               // Учитывать нерабочие праздничные дни по статье 112 ТК РФ
+}
+
+// statutoryHolidays Нерабочие праздничные дни по статье 112 ТК РФ в формате 01-02
+var statutoryHolidays = map[string]bool{
+	"01-01": true, "01-02": true, "01-03": true, "01-04": true, "01-05": true, "01-06": true, "01-07": true, "01-08": true,
+	"02-23": true, "03-08": true, "05-01": true, "05-09": true, "06-12": true, "11-04": true,
+}
+
+// New Возвращает календарь пятидневной рабочей недели с 9:00 до 18:00 в часовом поясе loc
+// с нерабочими праздничными днями по ТК РФ. Если loc не указан, используется Europe/Moscow.
+func New(loc *time.Location) *Calendar {
+	c := &Calendar{Location: loc, Days: map[string]DayType{}, Statutory: true}
+	for d := time.Monday; d <= time.Friday; d++ {
+		c.Week[d] = []Interval{{Start: 9 * time.Hour, End: 18 * time.Hour}}
+	}
+	return c
+}
+
+// Moscow Возвращает часовой пояс Europe/Moscow или UTC+3, если база часовых поясов недоступна
+func Moscow() *time.Location {
+	if loc, err := time.LoadLocation("Europe/Moscow"); err == nil {
+		return loc
+	}
+	return time.FixedZone("MSK", 3*60*60)
+}
+
+// location Возвращает часовой пояс календаря
+func (c *Calendar) location() *time.Location {
+	if c.Location == nil {
+		return Moscow()
+	}
+	return c.Location
+}
+
+// Set Задает тип особого дня date
+func (c *Calendar) Set(date time.Time, t DayType) {
+	if c.Days == nil {
+		c.Days = map[string]DayType{}
+	}
+	c.Days[date.Format(DateFormat)] = t
+}
+
+// DayType Возвращает тип дня, в который приходится t, или 0 для обычного дня
+func (c *Calendar) DayType(t time.Time) DayType {
+	t = t.In(c.location())
+	if d, ok := c.Days[t.Format(DateFormat)]; ok {
+		return d
+	}
+	if c.Statutory && statutoryHolidays[t.Format("01-02")] {
+		return HOLIDAY
+	}
+	return 0
+}
+
+// Hours Возвращает интервалы рабочего времени дня, в который приходится t.
+// Для перенесенного рабочего дня используются часы понедельника,
+// а в предпраздничный день окончание последнего интервала сдвигается на час раньше.
+func (c *Calendar) Hours(t time.Time) []Interval {
+	t = t.In(c.location())
+	hours := c.Week[t.Weekday()]
+	switch c.DayType(t) {
+	case HOLIDAY:
+		return nil
+	case WORKDAY:
+		if len(hours) == 0 {
+			hours = c.Week[time.Monday]
+		}
+	case SHORT_DAY:
+		if len(hours) > 0 {
+			hours = append([]Interval(nil), hours...)
+			last := &hours[len(hours)-1]
+			if last.End -= time.Hour; last.End <= last.Start {
+				hours = hours[:len(hours)-1]
+			}
+		}
+	}
+	return hours
+}
+
+// IsWorkingDay Проверяет, что t приходится на рабочий день
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	return len(c.Hours(t)) > 0
+}
+
+// IsWorkingTime Проверяет, что t - рабочее время
+func (c *Calendar) IsWorkingTime(t time.Time) bool {
+	t = t.In(c.location())
+	offset := sinceMidnight(t)
+	for _, iv := range c.Hours(t) {
+		if offset >= iv.Start && offset < iv.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Active Проверяет, что расписание s действует в момент t
+func (c *Calendar) Active(s beelineapi.Schedule, t time.Time) bool {
+	switch s {
+	case beelineapi.WORKING_TIME:
+		return c.IsWorkingTime(t)
+	case beelineapi.NON_WORKING_TIME_AND_HOLIDAYS:
+		return !c.IsWorkingTime(t)
+	}
+	return true
+}
+
+// Schedules Возвращает расписания, действующие в момент t
+func (c *Calendar) Schedules(t time.Time) []beelineapi.Schedule {
+	if c.IsWorkingTime(t) {
+		return []beelineapi.Schedule{beelineapi.ROUND_THE_CLOCK, beelineapi.WORKING_TIME}
+	}
+	return []beelineapi.Schedule{beelineapi.ROUND_THE_CLOCK, beelineapi.NON_WORKING_TIME_AND_HOLIDAYS}
+}
+
+// Next Возвращает ближайший после t момент смены рабочего времени на нерабочее или наоборот.
+// Если в течение года смены нет, возвращается нулевое время.
+func (c *Calendar) Next(t time.Time) time.Time {
+	t = t.In(c.location())
+	working := c.IsWorkingTime(t)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i <= 366; i++ {
+		// Дата считается через AddDate, а время - от полуночи, чтобы переход на летнее время не сдвигал дни
+		d := day.AddDate(0, 0, i)
+		for _, b := range boundaries(c.Hours(d)) {
+			at := d.Add(b)
+			if at.After(t) && c.IsWorkingTime(at) != working {
+				return at
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// boundaries Возвращает начала и окончания интервалов по возрастанию
+func boundaries(hours []Interval) []time.Duration {
+	res := []time.Duration{}
+	for _, iv := range hours {
+		res = append(res, iv.Start, iv.End)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// sinceMidnight Возвращает время, прошедшее с начала дня t
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
@@ -0,0 +1,150 @@
+package calendar_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/calendar"
+)
+
+var msk = time.FixedZone("MSK", 3*60*60)
+
+// at Возвращает момент времени по московскому времени
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, msk)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TestWorkingTime Тест на рабочее время календаря по умолчанию
+func TestWorkingTime(t *testing.T) {
+	c := calendar.New(msk)
+	tests := []struct {
+		at      string
+		working bool
+	}{
+		{"2024-03-04 08:59", false}, // Понедельник до начала рабочего дня
+		{"2024-03-04 09:00", true},
+		{"2024-03-04 17:59", true},
+		{"2024-03-04 18:00", false},
+		{"2024-03-09 12:00", false}, // Суббота
+		{"2024-03-08 12:00", false}, // Праздничный день по ТК РФ
+		{"2024-01-03 12:00", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsWorkingTime(at(tt.at)); got != tt.working {
+			t.Fatalf("%s: рабочее время %v, ожидалось %v", tt.at, got, tt.working)
+		}
+	}
+	// Время в другом часовом поясе переводится в часовой пояс календаря
+	if !c.IsWorkingTime(time.Date(2024, 3, 4, 6, 30, 0, 0, time.UTC)) {
+		t.Fatal("Не учтен часовой пояс календаря")
+	}
+	if !c.Active(beelineapi.NON_WORKING_TIME_AND_HOLIDAYS, at("2024-03-08 12:00")) || c.Active(beelineapi.WORKING_TIME, at("2024-03-08 12:00")) {
+		t.Fatal("Неверное расписание в праздничный день")
+	}
+	if s := c.Schedules(at("2024-03-04 10:00")); len(s) != 2 || s[1] != beelineapi.WORKING_TIME {
+		t.Fatalf("Неверные действующие расписания: %v", s)
+	}
+}
+
+// TestNext Тест на поиск ближайшей смены рабочего и нерабочего времени
+func TestNext(t *testing.T) {
+	c := calendar.New(msk)
+	tests := []struct{ from, next string }{
+		{"2024-03-04 10:00", "2024-03-04 18:00"},
+		{"2024-03-04 19:00", "2024-03-05 09:00"},
+		{"2024-03-07 18:30", "2024-03-11 09:00"}, // Праздник и выходные
+	}
+	for _, tt := range tests {
+		if got := c.Next(at(tt.from)); !got.Equal(at(tt.next)) {
+			t.Fatalf("%s: следующая смена %s, ожидалось %s", tt.from, got, tt.next)
+		}
+	}
+	if got := (&calendar.Calendar{Location: msk}).Next(at("2024-03-04 10:00")); !got.IsZero() {
+		t.Fatalf("Без рабочих часов смены быть не должно: %s", got)
+	}
+}
+
+// TestParse Тест на разбор описания календаря
+func TestParse(t *testing.T) {
+	c, err := calendar.Parse([]byte(`
+timezone: Asia/Yekaterinburg
+hours:
+  mon: 09:00-13:00, 14:00-18:00
+  sat: 10:00-14:00
+holidays: [2024-03-11]
+workdays: ["2024-03-10"]
+shortDays: [2024-03-16]
+noStatutory: true
+`))
+	if err != nil {
+		t.Fatalf("Не удалось разобрать календарь: %s", err)
+	}
+	if c.Location.String() != "Asia/Yekaterinburg" {
+		t.Fatalf("Неверный часовой пояс: %s", c.Location)
+	}
+	ekb := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, c.Location)
+		return t
+	}
+	tests := []struct {
+		at      string
+		working bool
+	}{
+		{"2024-03-04 13:30", false}, // Перерыв
+		{"2024-03-04 14:30", true},
+		{"2024-03-05 10:00", false}, // Вторник не задан
+		{"2024-03-08 10:00", false}, // Праздники ТК РФ не учитываются, но пятница не задана
+		{"2024-03-09 11:00", true},  // Суббота
+		{"2024-03-10 10:00", true},  // Перенесенный рабочий день с часами понедельника
+		{"2024-03-11 10:00", false}, // Нерабочий день
+		{"2024-03-16 13:30", false}, // Сокращенный день
+		{"2024-03-16 12:30", true},
+	}
+	for _, tt := range tests {
+		if got := c.IsWorkingTime(ekb(tt.at)); got != tt.working {
+			t.Fatalf("%s: рабочее время %v, ожидалось %v", tt.at, got, tt.working)
+		}
+	}
+	for _, doc := range []string{"hours: {mon: 18:00-09:00}", "hours: {xyz: 09:00-18:00}", "holidays: [03.08]", "timezone: Mars/Base", "unknown: 1"} {
+		if _, err := calendar.Parse([]byte(doc)); err == nil {
+			t.Fatalf("Ожидалась ошибка для %q", doc)
+		}
+	}
+}
+
+// TestProductionCalendar Тест на загрузку производственного календаря
+func TestProductionCalendar(t *testing.T) {
+	c, err := calendar.Parse([]byte("productionCalendar: [" + filepath.Join("testdata", "calendar-2024.xml") + "]\nholidays: [2024-12-28]"))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить производственный календарь: %s", err)
+	}
+	loc := c.Location
+	day := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return t
+	}
+	tests := []struct {
+		at      string
+		working bool
+	}{
+		{"2024-03-07 17:30", false}, // Предпраздничный день короче на час
+		{"2024-03-07 16:30", true},
+		{"2024-04-27 10:00", true},  // Рабочая суббота
+		{"2024-04-29 10:00", false}, // Перенесенный выходной
+		{"2024-12-28 10:00", false}, // Дата из описания важнее производственного календаря
+	}
+	for _, tt := range tests {
+		if got := c.IsWorkingTime(day(tt.at)); got != tt.working {
+			t.Fatalf("%s: рабочее время %v, ожидалось %v", tt.at, got, tt.working)
+		}
+	}
+	if c.DayType(day("2024-04-27 10:00")) != calendar.WORKDAY {
+		t.Fatalf("Неверный тип дня: %s", c.DayType(day("2024-04-27 10:00")))
+	}
+}
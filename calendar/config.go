@@ -0,0 +1,186 @@
+package calendar
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/internal/yamljson"
+)
+
+// weekdayNames Имена дней недели в конфигурации, индекс - time.Weekday
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Config Описание календаря в формате YAML или JSON, например:
+//
+//	timezone: Asia/Yekaterinburg
+//	hours:
+//	  mon: 09:00-13:00, 14:00-18:00
+//	  fri: 09:00-16:45
+//	holidays: [2024-12-31]
+//	workdays: [2024-12-28]
+//	shortDays: [2024-11-02]
+//	productionCalendar: [calendar-2024.xml]
+type Config struct {
+	Timezone           string            `json:"timezone"`                     // Часовой пояс, по умолчанию Europe/Moscow
+	Hours              map[string]string `json:"hours,omitempty"`              // Рабочие часы по дням недели mon-sun через запятую. Если не заданы, с понедельника по пятницу 09:00-18:00
+	Holidays           []string          `json:"holidays,omitempty"`           // Нерабочие дни в формате 2006-01-02
+	Workdays           []string          `json:"workdays,omitempty"`           // Рабочие дни, перенесенные с выходных
+	ShortDays          []string          `json:"shortDays,omitempty"`          // Предпраздничные дни с сокращенным на час рабочим временем
+	NoStatutory        bool              `json:"noStatutory,omitempty"`        // Не учитывать нерабочие праздничные дни по ТК РФ
+	ProductionCalendar []string          `json:"productionCalendar,omitempty"` // Файлы производственного календаря в формате xmlcalendar.ru
+}
+
+// Parse Возвращает календарь по описанию в формате YAML или JSON.
+// Пути к файлам производственного календаря разрешаются относительно текущего каталога.
+func Parse(data []byte) (*Calendar, error) {
+	return parse(data, "")
+}
+
+// Load Возвращает календарь по описанию из файла path.
+// Пути к файлам производственного календаря разрешаются относительно каталога файла.
+func Load(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при чтении календаря. ", err)
+	}
+	return parse(data, filepath.Dir(path))
+}
+
+func parse(data []byte, dir string) (*Calendar, error) {
+	cfg := Config{}
+	if err := yamljson.Decode(data, &cfg, true); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при разборе календаря. ", err)
+	}
+	c, err := cfg.calendar(dir)
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка в описании календаря. ", err)
+	}
+	return c, nil
+}
+
+// calendar Возвращает календарь по описанию. Файлы производственного календаря ищутся в каталоге dir.
+func (cfg Config) calendar(dir string) (*Calendar, error) {
+	loc := Moscow()
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	c := New(loc)
+	c.Statutory = !cfg.NoStatutory
+	if cfg.Hours != nil {
+		c.Week = [7][]Interval{}
+		for name, s := range cfg.Hours {
+			day := indexOf(weekdayNames, strings.ToLower(name))
+			if day < 0 {
+				return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Неверный день недели %q. Допустимые значения: %s", name, strings.Join(weekdayNames, ", "))}
+			}
+			for _, p := range strings.Split(s, ",") {
+				if strings.TrimSpace(p) == "" {
+					continue
+				}
+				iv, err := ParseInterval(p)
+				if err != nil {
+					return nil, err
+				}
+				c.Week[day] = append(c.Week[day], iv)
+			}
+		}
+	}
+	// Файлы производственного календаря применяются первыми, чтобы даты из описания их уточняли
+	for _, f := range cfg.ProductionCalendar {
+		if dir != "" && !filepath.IsAbs(f) {
+			f = filepath.Join(dir, f)
+		}
+		if err := c.loadProduction(f); err != nil {
+			return nil, err
+		}
+	}
+	for _, g := range []struct {
+		dates []string
+		t     DayType
+	}{{cfg.Holidays, HOLIDAY}, {cfg.Workdays, WORKDAY}, {cfg.ShortDays, SHORT_DAY}} {
+		for _, s := range g.dates {
+			d, err := parseDate(s, loc)
+			if err != nil {
+				return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Неверная дата %q, ожидался формат %s", s, DateFormat)}
+			}
+			c.Set(d, g.t)
+		}
+	}
+	return c, nil
+}
+
+func (c *Calendar) loadProduction(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.ReadProductionCalendar(f)
+}
+
+// productionXML Производственный календарь в формате xmlcalendar.ru
+type productionXML struct {
+	Year string `xml:"year,attr"`
+	Days []struct {
+		Date string `xml:"d,attr"` // Дата в формате ММ.ДД
+		Type int    `xml:"t,attr"` // 1 - выходной, 2 - сокращенный рабочий день, 3 - рабочий день
+	} `xml:"days>day"`
+}
+
+// ReadProductionCalendar Добавляет в календарь особые дни из производственного календаря
+// в формате xmlcalendar.ru: выходные и праздничные дни, сокращенные и перенесенные рабочие дни
+func (c *Calendar) ReadProductionCalendar(r io.Reader) error {
+	pc := productionXML{}
+	if err := xml.NewDecoder(r).Decode(&pc); err != nil {
+		return beelineapi.Wrap("Ошибка при чтении производственного календаря. ", err)
+	}
+	year, err := strconv.Atoi(pc.Year)
+	if err != nil {
+		return beelineapi.WrapError{Msg: fmt.Sprintf("Неверный год производственного календаря %q", pc.Year)}
+	}
+	for _, d := range pc.Days {
+		date, err := time.Parse("2006.01.02", fmt.Sprintf("%04d.%s", year, d.Date))
+		if err != nil {
+			return beelineapi.WrapError{Msg: fmt.Sprintf("Неверная дата %q в производственном календаре", d.Date)}
+		}
+		switch d.Type {
+		case 1:
+			c.Set(date, HOLIDAY)
+		case 2:
+			c.Set(date, SHORT_DAY)
+		case 3:
+			c.Set(date, WORKDAY)
+		default:
+			return beelineapi.WrapError{Msg: fmt.Sprintf("Неверный тип дня %d для %s в производственном календаре", d.Type, d.Date)}
+		}
+	}
+	return nil
+}
+
+// parseDate Разбирает дату в формате DateFormat. Даты без кавычек приходят из yamljson.Decode
+// в формате RFC 3339, поэтому время отбрасывается
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	if len(s) > len(DateFormat) && s[len(DateFormat)] == 'T' {
+		s = s[:len(DateFormat)]
+	}
+	return time.ParseInLocation(DateFormat, s, loc)
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
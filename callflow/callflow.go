@@ -0,0 +1,257 @@
+// Package callflow предсказывает, куда попадет входящий вызов абонента облачной АТС Билайн.
+// Simulator без обращения к API проходит по снимку настроек абонента в том же порядке, что и АТС:
+// выборочный прием звонков (черный или белый список), выборочная переадресация,
+// переадресация всех вызовов и, наконец, переадресация, если абонент занят, недоступен или не отвечает.
+// Результат содержит итоговое действие и пошаговое объяснение, почему оно выбрано.
+package callflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+// Action Итоговое действие с входящим вызовом
+type Action string
+
+const (
+	REJECT  Action = "reject"  // Вызов отклонен
+	ACCEPT  Action = "accept"  // Вызов поступает абоненту
+	FORWARD Action = "forward" // Вызов переадресован
+)
+
+// State Состояние абонента в момент вызова
+type State string
+
+const (
+	FREE        State = ""            // Абонент свободен
+	BUSY        State = "busy"        // Абонент занят
+	UNAVAILABLE State = "unavailable" // Абонент недоступен
+)
+
+// ParseState Возвращает состояние абонента по имени: free, busy или unavailable
+func ParseState(s string) (State, error) {
+	switch st := State(strings.ToLower(s)); st {
+	case "free":
+		return FREE, nil
+	case FREE, BUSY, UNAVAILABLE:
+		return st, nil
+	}
+	return FREE, beelineapi.WrapError{Msg: fmt.Sprintf("Неверное состояние абонента %q. Допустимые значения: free, busy, unavailable", s)}
+}
+
+// Snapshot Снимок настроек абонента, влияющих на маршрут входящего вызова
+type Snapshot struct {
+	Bwl   bwl.BwlStatusResponse            `json:"bwl"`   // Выборочный прием звонков
+	Cfs   forwarding.CfsStatusResponse     `json:"cfs"`   // Выборочная переадресация
+	Basic forwarding.BasicRedirectResponse `json:"basic"` // Базовая переадресация
+}
+
+// Fetch Возвращает снимок настроек абонента
+// id - Идентификатор, мобильный или добавочный номер абонента
+func Fetch(c *beelineapi.APIClient, id string) (Snapshot, error) {
+	snap := Snapshot{}
+	var err error
+	if snap.Bwl, err = bwl.New(c).IncCallRules(id); err != nil {
+		return snap, err
+	}
+	f := forwarding.New(c)
+	if snap.Cfs, err = f.GetSelectiveCallRules(id); err != nil {
+		return snap, err
+	}
+	if snap.Basic, err = f.GetBasicRedirectStatus(id); err != nil {
+		return snap, err
+	}
+	return snap, nil
+}
+
+// Call Входящий вызов
+type Call struct {
+	From  string    // Номер вызывающего абонента
+	Time  time.Time // Время вызова
+	State State     // Состояние абонента в момент вызова
+}
+
+// Result Итог обработки входящего вызова
+type Result struct {
+	Action Action   `json:"action"`          // Итоговое действие
+	Phone  string   `json:"phone,omitempty"` // Номер, на который переадресован вызов
+	Rings  int      `json:"rings"`           // Количество гудков абоненту до переадресации
+	Rule   string   `json:"rule,omitempty"`  // Имя правила, определившего действие
+	Trace  []string `json:"trace"`           // Пошаговое объяснение
+}
+
+// String Возвращает краткое описание итога, например "переадресация на 9000000002 после 5 гудков"
+func (r Result) String() string {
+	switch r.Action {
+	case REJECT:
+		return "вызов отклонен"
+	case FORWARD:
+		if r.Rings > 0 {
+			return fmt.Sprintf("переадресация на %s после %d гудков", r.Phone, r.Rings)
+		}
+		return "переадресация на " + r.Phone
+	}
+	return "вызов поступает абоненту"
+}
+
+// Simulator Определяет маршрут входящего вызова по снимку настроек абонента
+type Simulator struct {
+	WorkingTime func(t time.Time) bool // Проверяет, что t - рабочее время, например (*calendar.Calendar).IsWorkingTime. По умолчанию DefaultWorkingTime
+}
+
+// DefaultWorkingTime Рабочее время по умолчанию: с понедельника по пятницу с 9:00 до 18:00 в часовом поясе t
+func DefaultWorkingTime(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return t.Hour() >= 9 && t.Hour() < 18
+}
+
+// Evaluate Определяет маршрут вызова call по снимку настроек snap с рабочим временем по умолчанию
+func Evaluate(snap Snapshot, call Call) Result {
+	return Simulator{}.Evaluate(snap, call)
+}
+
+// Evaluate Определяет маршрут вызова call по снимку настроек snap
+func (s Simulator) Evaluate(snap Snapshot, call Call) Result {
+	res := Result{}
+	trace := func(format string, a ...interface{}) { res.Trace = append(res.Trace, fmt.Sprintf(format, a...)) }
+	working := s.WorkingTime
+	if working == nil {
+		working = DefaultWorkingTime
+	}
+	active := func(sch beelineapi.Schedule) bool {
+		switch sch {
+		case beelineapi.WORKING_TIME:
+			return working(call.Time)
+		case beelineapi.NON_WORKING_TIME_AND_HOLIDAYS:
+			return !working(call.Time)
+		}
+		return true
+	}
+	period := "нерабочее время"
+	if working(call.Time) {
+		period = "рабочее время"
+	}
+	trace("Вызов с номера %s в %s (%s)", call.From, call.Time.Format("2006-01-02 15:04"), period)
+
+	// Выборочный прием звонков
+	switch snap.Bwl.Status {
+	case bwl.BLACK_LIST_ON:
+		if rule, ok := matchBwl(snap.Bwl.BlackList, call.From, active); ok {
+			trace("Черный список: номер входит в правило %q (%s), вызов отклонен", rule.Name, rule.Schedule.Effective())
+			res.Action, res.Rule = REJECT, rule.Name
+			return res
+		}
+		trace("Черный список: номер не входит в действующие правила")
+	case bwl.WHITE_LIST_ON:
+		rule, ok := matchBwl(snap.Bwl.WhiteList, call.From, active)
+		if !ok {
+			trace("Белый список: номер не входит в действующие правила, вызов отклонен")
+			res.Action = REJECT
+			return res
+		}
+		trace("Белый список: номер входит в правило %q (%s)", rule.Name, rule.Schedule.Effective())
+	default:
+		trace("Выборочный прием звонков отключен")
+	}
+
+	// Выборочная переадресация
+	if snap.Cfs.IsCfsServiceEnabled {
+		for _, rule := range snap.Cfs.RuleList {
+			switch {
+			case !active(rule.Schedule):
+				trace("Выборочная переадресация: правило %q не действует (%s)", rule.Name, rule.Schedule.Effective())
+			case len(rule.PhoneList) > 0 && !containsPhone(rule.PhoneList, call.From):
+				trace("Выборочная переадресация: номер не входит в правило %q", rule.Name)
+			default:
+				trace("Выборочная переадресация: правило %q (%s), переадресация на %s", rule.Name, rule.Schedule.Effective(), rule.ForwardToPhone)
+				res.Action, res.Phone, res.Rule = FORWARD, rule.ForwardToPhone, rule.Name
+				return res
+			}
+		}
+		if len(snap.Cfs.RuleList) == 0 {
+			trace("Выборочная переадресация включена, но правил нет")
+		}
+	} else {
+		trace("Выборочная переадресация отключена")
+	}
+
+	// Базовая переадресация
+	if snap.Basic.Status != beelineapi.ON {
+		trace("Базовая переадресация отключена")
+		return s.unforwarded(res, call, trace)
+	}
+	f := snap.Basic.Forward
+	if f.ForwardAllCallsPhone != "" {
+		trace("Переадресация всех вызовов на %s", f.ForwardAllCallsPhone)
+		res.Action, res.Phone = FORWARD, f.ForwardAllCallsPhone
+		return res
+	}
+	switch {
+	case call.State == BUSY && f.ForwardBusyPhone != "":
+		trace("Абонент занят, переадресация на %s", f.ForwardBusyPhone)
+		res.Action, res.Phone = FORWARD, f.ForwardBusyPhone
+		return res
+	case call.State == UNAVAILABLE && f.ForwardUnavailablePhone != "":
+		trace("Абонент недоступен, переадресация на %s", f.ForwardUnavailablePhone)
+		res.Action, res.Phone = FORWARD, f.ForwardUnavailablePhone
+		return res
+	case call.State == FREE && f.ForwardNotAnswerPhone != "":
+		trace("Вызов поступает абоненту, если он не ответит за %d гудков - переадресация на %s", f.ForwardNotAnswerTimeout, f.ForwardNotAnswerPhone)
+		res.Action, res.Phone, res.Rings = FORWARD, f.ForwardNotAnswerPhone, f.ForwardNotAnswerTimeout
+		return res
+	}
+	trace("Базовая переадресация для этого состояния абонента не задана")
+	return s.unforwarded(res, call, trace)
+}
+
+// unforwarded Завершает обработку вызова, который не был переадресован
+func (s Simulator) unforwarded(res Result, call Call, trace func(string, ...interface{})) Result {
+	switch call.State {
+	case BUSY:
+		trace("Абонент занят, вызов отклонен")
+		res.Action = REJECT
+	case UNAVAILABLE:
+		trace("Абонент недоступен, вызов отклонен")
+		res.Action = REJECT
+	default:
+		trace("Вызов поступает абоненту")
+		res.Action = ACCEPT
+	}
+	return res
+}
+
+// matchBwl Возвращает первое действующее правило списка, в которое входит номер phone
+func matchBwl(rules []bwl.BwlRule, phone string, active func(beelineapi.Schedule) bool) (bwl.BwlRule, bool) {
+	for _, rule := range rules {
+		if active(rule.Schedule) && containsPhone(rule.PhoneList, phone) {
+			return rule, true
+		}
+	}
+	return bwl.BwlRule{}, false
+}
+
+// containsPhone Проверяет, что номер phone входит в список. Номера сравниваются в формате API,
+// а номера, которые не удалось к нему привести (например, добавочные), - без изменений
+func containsPhone(list []string, phone string) bool {
+	phone = normalize(phone)
+	for _, p := range list {
+		if normalize(p) == phone {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(phone string) string {
+	if p, err := beelineapi.NormalizePhone(phone); err == nil {
+		return p
+	}
+	return strings.TrimSpace(phone)
+}
@@ -0,0 +1,114 @@
+package callflow_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/callflow"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+var (
+	monday   = time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC) // Рабочее время
+	saturday = time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC) // Выходной
+)
+
+// snapshot Снимок настроек: черный список, ночная выборочная переадресация и базовая переадресация
+func snapshot() callflow.Snapshot {
+	return callflow.Snapshot{
+		Bwl: bwl.BwlStatusResponse{Status: bwl.BLACK_LIST_ON, BlackList: []bwl.BwlRule{
+			{Name: "spam", Schedule: beelineapi.ROUND_THE_CLOCK, PhoneList: []string{"9000000009"}},
+		}},
+		Cfs: forwarding.CfsStatusResponse{IsCfsServiceEnabled: true, RuleList: []forwarding.CfsRule{
+			{Name: "night", ForwardToPhone: "9000000005", Schedule: beelineapi.NON_WORKING_TIME_AND_HOLIDAYS},
+			{Name: "boss", ForwardToPhone: "9000000006", Schedule: beelineapi.WORKING_TIME, PhoneList: []string{"9000000001"}},
+		}},
+		Basic: forwarding.BasicRedirectResponse{Status: beelineapi.ON, Forward: forwarding.BasicRedirect{
+			ForwardBusyPhone: "9000000002", ForwardNotAnswerPhone: "9000000003", ForwardNotAnswerTimeout: 5,
+		}},
+	}
+}
+
+// TestEvaluate Тест на определение маршрута входящего вызова
+func TestEvaluate(t *testing.T) {
+	whiteList := snapshot()
+	whiteList.Bwl.Status = bwl.WHITE_LIST_ON
+	whiteList.Bwl.WhiteList = []bwl.BwlRule{{Name: "office", Schedule: beelineapi.WORKING_TIME, PhoneList: []string{"9000000001"}}}
+	forwardAll := snapshot()
+	forwardAll.Basic.Forward.ForwardAllCallsPhone = "9000000007"
+	off := callflow.Snapshot{Bwl: bwl.BwlStatusResponse{Status: bwl.OFF}}
+
+	tests := []struct {
+		name   string
+		snap   callflow.Snapshot
+		call   callflow.Call
+		action callflow.Action
+		phone  string
+		rings  int
+		rule   string
+	}{
+		{name: "black list", snap: snapshot(), call: callflow.Call{From: "+7 900 000-00-09", Time: monday}, action: callflow.REJECT, rule: "spam"},
+		{name: "night rule", snap: snapshot(), call: callflow.Call{From: "9000000001", Time: saturday}, action: callflow.FORWARD, phone: "9000000005", rule: "night"},
+		{name: "phone rule", snap: snapshot(), call: callflow.Call{From: "89000000001", Time: monday}, action: callflow.FORWARD, phone: "9000000006", rule: "boss"},
+		{name: "no answer", snap: snapshot(), call: callflow.Call{From: "9000000004", Time: monday}, action: callflow.FORWARD, phone: "9000000003", rings: 5},
+		{name: "busy", snap: snapshot(), call: callflow.Call{From: "9000000004", Time: monday, State: callflow.BUSY}, action: callflow.FORWARD, phone: "9000000002"},
+		{name: "unavailable", snap: snapshot(), call: callflow.Call{From: "9000000004", Time: monday, State: callflow.UNAVAILABLE}, action: callflow.REJECT},
+		{name: "forward all", snap: forwardAll, call: callflow.Call{From: "9000000004", Time: monday}, action: callflow.FORWARD, phone: "9000000007"},
+		{name: "white list", snap: whiteList, call: callflow.Call{From: "9000000001", Time: monday}, action: callflow.FORWARD, phone: "9000000006", rule: "boss"},
+		{name: "white list closed", snap: whiteList, call: callflow.Call{From: "9000000001", Time: saturday}, action: callflow.REJECT},
+		{name: "not in white list", snap: whiteList, call: callflow.Call{From: "9000000004", Time: monday}, action: callflow.REJECT},
+		{name: "nothing enabled", snap: off, call: callflow.Call{From: "9000000004", Time: monday}, action: callflow.ACCEPT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := callflow.Evaluate(tt.snap, tt.call)
+			if res.Action != tt.action || res.Phone != tt.phone || res.Rings != tt.rings || res.Rule != tt.rule {
+				t.Fatalf("Неверный маршрут вызова: %+v\n%s", res, strings.Join(res.Trace, "\n"))
+			}
+			if len(res.Trace) < 2 {
+				t.Fatalf("Нет объяснения маршрута: %+v", res)
+			}
+		})
+	}
+}
+
+// TestWorkingTime Тест на рабочее время, заданное функцией
+func TestWorkingTime(t *testing.T) {
+	sim := callflow.Simulator{WorkingTime: func(time.Time) bool { return false }}
+	res := sim.Evaluate(snapshot(), callflow.Call{From: "9000000001", Time: monday})
+	if res.Rule != "night" || !strings.Contains(res.Trace[0], "нерабочее время") {
+		t.Fatalf("Не учтено рабочее время: %+v", res)
+	}
+	if res.String() != "переадресация на 9000000005" {
+		t.Fatalf("Неверное описание итога: %s", res)
+	}
+	if _, err := callflow.ParseState("away"); err == nil {
+		t.Fatal("Ожидалась ошибка для неизвестного состояния абонента")
+	}
+}
+
+// TestFetch Тест на получение снимка настроек абонента
+func TestFetch(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	c, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if err := forwarding.New(c).TurnOnBasicRedirect("u1", forwarding.BasicRedirect{ForwardAllCallsPhone: "9000000007"}); err != nil {
+		t.Fatalf("Не удалось включить переадресацию: %s", err)
+	}
+	snap, err := callflow.Fetch(c, "u1")
+	if err != nil {
+		t.Fatalf("Не удалось получить снимок настроек: %s", err)
+	}
+	if res := callflow.Evaluate(snap, callflow.Call{From: "9000000004", Time: monday}); res.Phone != "9000000007" {
+		t.Fatalf("Неверный маршрут вызова: %+v", res)
+	}
+}
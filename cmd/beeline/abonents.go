@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+)
+
+func init() {
+	commands["abonents"] = command{
+		usage: "  abonents list | get <абонент>\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"list": abonentsList,
+			"get":  abonentsGet,
+		},
+	}
+	commands["agent"] = command{
+		usage: "  agent get <абонент> | set <абонент> ONLINE|OFFLINE|BREAK\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"get": agentGet,
+			"set": agentSet,
+		},
+	}
+	commands["recording"] = command{
+		usage: "  recording get|on|off <абонент>\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"get": recordingGet,
+			"on":  recordingToggle(true),
+			"off": recordingToggle(false),
+		},
+	}
+}
+
+// abonentRows Возвращает строки таблицы абонентов
+func abonentRows(list []abonents.Abonent) ([]string, [][]string) {
+	rows := make([][]string, 0, len(list))
+	for _, a := range list {
+		rows = append(rows, []string{a.UserId, a.Phone, a.Extension, strings.TrimSpace(a.LastName + " " + a.FirstName), a.Email, a.Department})
+	}
+	return []string{"ID", "ТЕЛЕФОН", "ДОБАВОЧНЫЙ", "ИМЯ", "EMAIL", "ОТДЕЛ"}, rows
+}
+
+func abonentsList(c *cli, args []string) error {
+	if _, err := parse(flags("abonents list"), args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	list, err := abonents.New(client).GetAbonents()
+	if err != nil {
+		return err
+	}
+	header, rows := abonentRows(list)
+	return c.print(list, header, rows)
+}
+
+func abonentsGet(c *cli, args []string) error {
+	a, err := parse(flags("abonents get"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	ab, err := abonents.New(client).GetAbonent(a[0])
+	if err != nil {
+		return err
+	}
+	header, rows := abonentRows([]abonents.Abonent{ab})
+	return c.print(ab, header, rows)
+}
+
+func agentGet(c *cli, args []string) error {
+	a, err := parse(flags("agent get"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	status, err := abonents.New(client).GetAgentStatus(a[0])
+	if err != nil {
+		return err
+	}
+	name := status.String()
+	return c.print(map[string]string{"status": name}, []string{"СТАТУС"}, [][]string{{name}})
+}
+
+func agentSet(c *cli, args []string) error {
+	a, err := parse(flags("agent set"), args, 2, "<абонент> ONLINE|OFFLINE|BREAK")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	status, err := agentStatus(a[1])
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := abonents.New(client).SetAgentStatus(a[0], status); err != nil {
+		return err
+	}
+	return c.done("Статус агента установлен")
+}
+
+func recordingGet(c *cli, args []string) error {
+	a, err := parse(flags("recording get"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	status, err := abonents.New(client).GetRecordingStatus(a[0])
+	if err != nil {
+		return err
+	}
+	name := status.String()
+	return c.print(map[string]string{"status": name}, []string{"ЗАПИСЬ"}, [][]string{{name}})
+}
+
+// recordingToggle Возвращает действие включения или отключения записи разговоров
+func recordingToggle(on bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		a, err := parse(flags("recording"), args, 1, "<абонент>")
+		if err != nil {
+			return err
+		}
+		if a[0], err = c.abonentId(a[0]); err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := abonents.New(client)
+		if on {
+			err = s.TurnOnRecording(a[0])
+		} else {
+			err = s.TurnOffRecording(a[0])
+		}
+		if err != nil {
+			return err
+		}
+		return c.done("Запись разговоров " + map[bool]string{true: "включена", false: "отключена"}[on])
+	}
+}
+
+// agentStatus Возвращает статус агента по имени без учета регистра
+func agentStatus(name string) (beelineapi.AgentStatus, error) {
+	status, err := beelineapi.ParseAgentStatus(strings.ToUpper(name))
+	if err != nil {
+		return 0, beelineapi.WrapError{Msg: fmt.Sprintf("Неизвестный статус %q. Допустимые значения: ONLINE, OFFLINE, BREAK", name)}
+	}
+	return status, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/bwl"
+)
+
+func init() {
+	commands["bwl"] = command{
+		usage: "  bwl list|off <абонент> | on <абонент> BLACK_LIST|WHITE_LIST | add -type -name -schedule -phones <абонент> | delete <абонент> <правило>\n" +
+			"      export <абонент> | diff -f файл.csv <абонент> | apply [-dry-run] -f файл.csv <абонент>\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"list":   bwlList,
+			"on":     bwlOn,
+			"off":    bwlOff,
+			"add":    bwlAdd,
+			"delete": bwlDelete,
+			"export": bwlExport,
+			"diff":   bwlApply(true),
+			"apply":  bwlApply(false),
+		},
+	}
+}
+
+func bwlList(c *cli, args []string) error {
+	a, err := parse(flags("bwl list"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	st, err := bwl.New(client).IncCallRules(a[0])
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	add := func(t beelineapi.BwlListType, list []bwl.BwlRule) {
+		for _, r := range list {
+			rows = append(rows, []string{t.String(), strconv.Itoa(r.Id), r.Name, r.Schedule.String(), strings.Join(r.PhoneList, ",")})
+		}
+	}
+	add(beelineapi.BLACK_LIST, st.BlackList)
+	add(beelineapi.WHITE_LIST, st.WhiteList)
+	if !c.json && st.Status.Valid() {
+		if err := c.done("Статус: " + st.Status.String()); err != nil {
+			return err
+		}
+	}
+	return c.print(st, []string{"СПИСОК", "ID", "НАЗВАНИЕ", "РАСПИСАНИЕ", "НОМЕРА"}, rows)
+}
+
+func bwlOn(c *cli, args []string) error {
+	a, err := parse(flags("bwl on"), args, 2, "<абонент> BLACK_LIST|WHITE_LIST")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	t, err := beelineapi.ParseBwlListType(strings.ToUpper(a[1]))
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := bwl.New(client).TurnOnSelectiveCallReceive(a[0], t); err != nil {
+		return err
+	}
+	return c.done("Выборочный прием звонков включен")
+}
+
+func bwlOff(c *cli, args []string) error {
+	a, err := parse(flags("bwl off"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := bwl.New(client).TurnOffSelectiveReceiveRule(a[0]); err != nil {
+		return err
+	}
+	return c.done("Выборочный прием звонков отключен")
+}
+
+func bwlAdd(c *cli, args []string) error {
+	fs := flags("bwl add")
+	typ := fs.String("type", beelineapi.BLACK_LIST.String(), "тип списка BLACK_LIST или WHITE_LIST")
+	name := fs.String("name", "", "название правила")
+	schedule := fs.String("schedule", beelineapi.ROUND_THE_CLOCK.String(), "расписание")
+	phones := fs.String("phones", "", "номера через запятую")
+	a, err := parse(fs, args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	t, err := beelineapi.ParseBwlListType(strings.ToUpper(*typ))
+	if err != nil {
+		return err
+	}
+	sch, err := beelineapi.ParseSchedule(strings.ToUpper(*schedule))
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	id, err := bwl.New(client).AddIncCallRule(a[0], bwl.BwlRuleAdd{Type: t, Rule: bwl.BwlRuleUpdate{Name: *name, Schedule: sch, PhoneList: splitList(*phones)}})
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]int{"id": id}, []string{"ID"}, [][]string{{strconv.Itoa(id)}})
+}
+
+func bwlDelete(c *cli, args []string) error {
+	a, err := parse(flags("bwl delete"), args, 2, "<абонент> <правило>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(a[1])
+	if err != nil {
+		return beelineapi.Wrap("Неверный идентификатор правила. ", err)
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := bwl.New(client).DeleteSelectiveReceiveRule(a[0], id); err != nil {
+		return err
+	}
+	return c.done("Правило удалено")
+}
+
+func bwlExport(c *cli, args []string) error {
+	a, err := parse(flags("bwl export"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	st, err := bwl.New(client).IncCallRules(a[0])
+	if err != nil {
+		return err
+	}
+	return bwl.WriteCSV(c.out, st.Rules())
+}
+
+// bwlApply Возвращает действие сравнения правил из CSV с правилами абонента и их применения.
+// Если diffOnly или указан -dry-run, выводится только план изменений.
+func bwlApply(diffOnly bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		fs := flags("bwl apply")
+		file := fs.String("f", "", "файл CSV со столбцами type, name, schedule, phones; - для стандартного ввода")
+		dryRun := fs.Bool("dry-run", diffOnly, "только вывести план изменений")
+		a, err := parse(fs, args, 1, "<абонент>")
+		if err != nil {
+			return err
+		}
+		if a[0], err = c.abonentId(a[0]); err != nil {
+			return err
+		}
+		f, err := openInput(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		desired, err := bwl.ReadCSV(f)
+		if err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := bwl.New(client)
+		st, err := s.IncCallRules(a[0])
+		if err != nil {
+			return err
+		}
+		plan := bwl.Diff(st.Rules(), desired)
+		if *dryRun || plan.Empty() {
+			return printBwlPlan(c, plan)
+		}
+		if err := s.ApplyPlan(a[0], plan); err != nil {
+			return err
+		}
+		if err := printBwlPlan(c, plan); err != nil {
+			return err
+		}
+		return c.done("Изменения применены")
+	}
+}
+
+// printBwlPlan Выводит план изменений правил выборочного приема звонков
+func printBwlPlan(c *cli, p bwl.Plan) error {
+	rows := [][]string{}
+	add := func(action string, list []bwl.ListRule) {
+		for _, r := range list {
+			rows = append(rows, []string{action, r.Type.String(), r.Name, r.Schedule.String(), strings.Join(r.PhoneList, ",")})
+		}
+	}
+	add("+", p.Add)
+	add("~", p.Update)
+	add("-", p.Delete)
+	if !c.json && len(rows) == 0 {
+		return c.done("Изменений нет")
+	}
+	return c.print(p, []string{"", "СПИСОК", "НАЗВАНИЕ", "РАСПИСАНИЕ", "НОМЕРА"}, rows)
+}
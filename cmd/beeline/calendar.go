@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/calendar"
+)
+
+func init() {
+	commands["calendar"] = command{
+		usage: "  calendar check [-f календарь.yaml] [-at \"2006-01-02 15:04\"]\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"check": calendarCheck,
+		},
+	}
+}
+
+// calendarCheck Выводит, действует ли рабочее время, какие расписания правил действуют
+// и когда они сменятся
+func calendarCheck(c *cli, args []string) error {
+	fs := flags("calendar check")
+	file := fs.String("f", "", "календарь рабочего времени в формате YAML или JSON, по умолчанию пн-пт 09:00-18:00 по Москве")
+	at := fs.String("at", "", "время в формате 2006-01-02 15:04 в часовом поясе календаря, по умолчанию текущее")
+	if _, err := parse(fs, args, 0, ""); err != nil {
+		return err
+	}
+	cal := calendar.New(nil)
+	if *file != "" {
+		var err error
+		if cal, err = calendar.Load(*file); err != nil {
+			return err
+		}
+	}
+	loc := cal.Location
+	if loc == nil {
+		loc = calendar.Moscow()
+	}
+	t := time.Now().In(loc)
+	if *at != "" {
+		var err error
+		if t, err = time.ParseInLocation("2006-01-02 15:04", *at, loc); err != nil {
+			return beelineapi.Wrap("Неверное время. ", err)
+		}
+	}
+	hours := []string{}
+	for _, iv := range cal.Hours(t) {
+		hours = append(hours, iv.String())
+	}
+	schedules := []string{}
+	for _, s := range cal.Schedules(t) {
+		schedules = append(schedules, s.String())
+	}
+	next := ""
+	if n := cal.Next(t); !n.IsZero() {
+		next = n.Format("2006-01-02 15:04")
+	}
+	day := "обычный"
+	if d := cal.DayType(t); d != 0 {
+		day = d.String()
+	}
+	res := map[string]interface{}{
+		"time": t, "working": cal.IsWorkingTime(t), "day": day, "hours": hours, "schedules": schedules, "next": next,
+	}
+	working := map[bool]string{true: "да", false: "нет"}[cal.IsWorkingTime(t)]
+	return c.print(res, []string{"ВРЕМЯ", "РАБОЧЕЕ", "ДЕНЬ", "ЧАСЫ", "РАСПИСАНИЯ", "СМЕНА"},
+		[][]string{{t.Format("2006-01-02 15:04 MST"), working, day, strings.Join(hours, ","), strings.Join(schedules, ","), next}})
+}
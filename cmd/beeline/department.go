@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/taigasys/beeline-portal-api/bulk"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+func init() {
+	commands["department"] = command{
+		usage: "  department list | members <отдел> | agent <отдел> ONLINE|OFFLINE|BREAK | recording-on|recording-off|forwarding-off <отдел>\n" +
+			"             forwarding-on [-all -busy -unavailable -noanswer -timeout] <отдел>\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"list":    departmentList,
+			"members": departmentMembers,
+			"agent":   departmentAgent,
+			"recording-on": departmentOp(func(b *bulk.Runner, d string) (bulk.Report, error) {
+				return b.TurnOnRecording(context.Background(), d)
+			}),
+			"recording-off": departmentOp(func(b *bulk.Runner, d string) (bulk.Report, error) {
+				return b.TurnOffRecording(context.Background(), d)
+			}),
+			"forwarding-off": departmentOp(func(b *bulk.Runner, d string) (bulk.Report, error) {
+				return b.TurnOffBasicRedirect(context.Background(), d)
+			}),
+			"forwarding-on": departmentForwarding,
+		},
+	}
+}
+
+// runner Возвращает Runner для операций над отделами
+func (c *cli) runner() (*bulk.Runner, error) {
+	dir, err := c.directory()
+	if err != nil {
+		return nil, err
+	}
+	return bulk.New(c.client, dir), nil
+}
+
+func departmentList(c *cli, args []string) error {
+	if _, err := parse(flags("department list"), args, 0, ""); err != nil {
+		return err
+	}
+	dir, err := c.directory()
+	if err != nil {
+		return err
+	}
+	list, err := dir.Departments()
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, d := range list {
+		rows = append(rows, []string{d})
+	}
+	return c.print(list, []string{"ОТДЕЛ"}, rows)
+}
+
+func departmentMembers(c *cli, args []string) error {
+	a, err := parse(flags("department members"), args, 1, "<отдел>")
+	if err != nil {
+		return err
+	}
+	dir, err := c.directory()
+	if err != nil {
+		return err
+	}
+	list, err := dir.Department(a[0])
+	if err != nil {
+		return err
+	}
+	header, rows := abonentRows(list)
+	return c.print(list, header, rows)
+}
+
+func departmentAgent(c *cli, args []string) error {
+	a, err := parse(flags("department agent"), args, 2, "<отдел> ONLINE|OFFLINE|BREAK")
+	if err != nil {
+		return err
+	}
+	status, err := agentStatus(a[1])
+	if err != nil {
+		return err
+	}
+	b, err := c.runner()
+	if err != nil {
+		return err
+	}
+	rep, err := b.SetAgentStatus(context.Background(), a[0], status)
+	if err != nil {
+		return err
+	}
+	return printReport(c, rep)
+}
+
+func departmentForwarding(c *cli, args []string) error {
+	fs := flags("department forwarding-on")
+	br := forwarding.BasicRedirect{}
+	fs.StringVar(&br.ForwardAllCallsPhone, "all", "", "номер для переадресации всех вызовов")
+	fs.StringVar(&br.ForwardBusyPhone, "busy", "", "номер для переадресации, если абонент занят")
+	fs.StringVar(&br.ForwardUnavailablePhone, "unavailable", "", "номер для переадресации, если абонент недоступен")
+	fs.StringVar(&br.ForwardNotAnswerPhone, "noanswer", "", "номер для переадресации, если абонент не отвечает")
+	fs.IntVar(&br.ForwardNotAnswerTimeout, "timeout", 0, "количество гудков до переадресации, если абонент не отвечает")
+	a, err := parse(fs, args, 1, "<отдел>")
+	if err != nil {
+		return err
+	}
+	b, err := c.runner()
+	if err != nil {
+		return err
+	}
+	rep, err := b.TurnOnBasicRedirect(context.Background(), a[0], br)
+	if err != nil {
+		return err
+	}
+	return printReport(c, rep)
+}
+
+// departmentOp Возвращает действие, выполняющее операцию op над отделом
+func departmentOp(op func(b *bulk.Runner, department string) (bulk.Report, error)) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		a, err := parse(flags("department"), args, 1, "<отдел>")
+		if err != nil {
+			return err
+		}
+		b, err := c.runner()
+		if err != nil {
+			return err
+		}
+		rep, err := op(b, a[0])
+		if err != nil {
+			return err
+		}
+		return printReport(c, rep)
+	}
+}
+
+// printReport Выводит отчет о выполнении операции над абонентами.
+// Если операция не выполнена хотя бы для одного абонента, возвращается ошибка.
+func printReport(c *cli, rep bulk.Report) error {
+	rows := [][]string{}
+	for _, r := range rep.Results {
+		status := "OK"
+		if r.Error != "" {
+			status = r.Error
+		}
+		rows = append(rows, []string{r.Abonent.UserId, strings.TrimSpace(r.Abonent.LastName + " " + r.Abonent.FirstName), status})
+	}
+	if err := c.print(rep, []string{"ID", "ИМЯ", "РЕЗУЛЬТАТ"}, rows); err != nil {
+		return err
+	}
+	return rep.Err()
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/calendar"
+	"github.com/taigasys/beeline-portal-api/callflow"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+func init() {
+	commands["forwarding"] = command{
+		usage: "  forwarding get|off|rules <абонент> | set [-all -busy -unavailable -noanswer -timeout] <абонент>\n" +
+			"             add-rule -name -to -schedule -phones <абонент> | delete-rule <абонент> <правило> | selective-on|selective-off <абонент>\n" +
+			"             simulate [-from номер] [-at \"2006-01-02 15:04\"] [-tz пояс] [-state free|busy|unavailable] [-calendar файл] [-f снимок.json] <абонент> | snapshot <абонент>\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"get":           forwardingGet,
+			"set":           forwardingSet,
+			"off":           forwardingOff,
+			"rules":         forwardingRules,
+			"add-rule":      forwardingAddRule,
+			"delete-rule":   forwardingDeleteRule,
+			"selective-on":  forwardingSelective(true),
+			"selective-off": forwardingSelective(false),
+			"simulate":      forwardingSimulate,
+			"snapshot":      forwardingSnapshot,
+		},
+	}
+}
+
+func forwardingGet(c *cli, args []string) error {
+	a, err := parse(flags("forwarding get"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	br, err := forwarding.New(client).GetBasicRedirectStatus(a[0])
+	if err != nil {
+		return err
+	}
+	f := br.Forward
+	return c.print(br, []string{"СТАТУС", "ВСЕ", "ЗАНЯТ", "НЕДОСТУПЕН", "НЕ ОТВЕЧАЕТ", "ГУДКОВ"},
+		[][]string{{br.Status.String(), f.ForwardAllCallsPhone, f.ForwardBusyPhone, f.ForwardUnavailablePhone, f.ForwardNotAnswerPhone, strconv.Itoa(f.ForwardNotAnswerTimeout)}})
+}
+
+func forwardingSet(c *cli, args []string) error {
+	fs := flags("forwarding set")
+	br := forwarding.BasicRedirect{}
+	fs.StringVar(&br.ForwardAllCallsPhone, "all", "", "номер для переадресации всех вызовов")
+	fs.StringVar(&br.ForwardBusyPhone, "busy", "", "номер для переадресации, если абонент занят")
+	fs.StringVar(&br.ForwardUnavailablePhone, "unavailable", "", "номер для переадресации, если абонент недоступен")
+	fs.StringVar(&br.ForwardNotAnswerPhone, "noanswer", "", "номер для переадресации, если абонент не отвечает")
+	fs.IntVar(&br.ForwardNotAnswerTimeout, "timeout", 0, "количество гудков до переадресации, если абонент не отвечает")
+	a, err := parse(fs, args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := forwarding.New(client).TurnOnBasicRedirect(a[0], br); err != nil {
+		return err
+	}
+	return c.done("Переадресация включена")
+}
+
+func forwardingOff(c *cli, args []string) error {
+	a, err := parse(flags("forwarding off"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := forwarding.New(client).TurnOffBasicRedirect(a[0]); err != nil {
+		return err
+	}
+	return c.done("Переадресация отключена")
+}
+
+func forwardingRules(c *cli, args []string) error {
+	a, err := parse(flags("forwarding rules"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	st, err := forwarding.New(client).GetSelectiveCallRules(a[0])
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, r := range st.RuleList {
+		rows = append(rows, []string{strconv.Itoa(r.Id), r.Name, r.ForwardToPhone, r.Schedule.String(), strings.Join(r.PhoneList, ",")})
+	}
+	return c.print(st, []string{"ID", "НАЗВАНИЕ", "КУДА", "РАСПИСАНИЕ", "НОМЕРА"}, rows)
+}
+
+func forwardingAddRule(c *cli, args []string) error {
+	fs := flags("forwarding add-rule")
+	name := fs.String("name", "", "название правила")
+	to := fs.String("to", "", "номер для переадресации")
+	schedule := fs.String("schedule", beelineapi.ROUND_THE_CLOCK.String(), "расписание")
+	phones := fs.String("phones", "", "номера через запятую")
+	a, err := parse(fs, args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	sch, err := beelineapi.ParseSchedule(strings.ToUpper(*schedule))
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	id, err := forwarding.New(client).AddSelectiveCallRule(a[0], forwarding.CfsRuleUpdate{Name: *name, ForwardToPhone: *to, Schedule: sch, PhoneList: splitList(*phones)})
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]int{"id": id}, []string{"ID"}, [][]string{{strconv.Itoa(id)}})
+}
+
+func forwardingDeleteRule(c *cli, args []string) error {
+	a, err := parse(flags("forwarding delete-rule"), args, 2, "<абонент> <правило>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(a[1])
+	if err != nil {
+		return beelineapi.Wrap("Неверный идентификатор правила. ", err)
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := forwarding.New(client).DeleteSelectiveCallRule(a[0], id); err != nil {
+		return err
+	}
+	return c.done("Правило удалено")
+}
+
+// forwardingSelective Возвращает действие включения или отключения выборочной переадресации
+func forwardingSelective(on bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		a, err := parse(flags("forwarding selective"), args, 1, "<абонент>")
+		if err != nil {
+			return err
+		}
+		if a[0], err = c.abonentId(a[0]); err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := forwarding.New(client)
+		if on {
+			err = s.TurnOnSelectiveRedirect(a[0])
+		} else {
+			err = s.TurnOffSelectiveRedirect(a[0])
+		}
+		if err != nil {
+			return err
+		}
+		return c.done("Выборочная переадресация " + map[bool]string{true: "включена", false: "отключена"}[on])
+	}
+}
+
+// forwardingSimulate Выводит маршрут входящего вызова абонента с пошаговым объяснением.
+// Настройки берутся из снимка -f, если он указан, иначе запрашиваются у API.
+func forwardingSimulate(c *cli, args []string) error {
+	fs := flags("forwarding simulate")
+	from := fs.String("from", "", "номер вызывающего абонента")
+	at := fs.String("at", "", "время вызова в формате 2006-01-02 15:04, по умолчанию текущее")
+	tz := fs.String("tz", "", "часовой пояс времени вызова, например Europe/Moscow")
+	state := fs.String("state", "free", "состояние абонента: free, busy или unavailable")
+	file := fs.String("f", "", "снимок настроек абонента в формате JSON (см. forwarding snapshot)")
+	cal := fs.String("calendar", "", "календарь рабочего времени в формате YAML или JSON, по умолчанию пн-пт 09:00-18:00")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	n := 1
+	if *file != "" {
+		n = 0
+	}
+	if fs.NArg() != n {
+		return parseUsage(fs, "<абонент>")
+	}
+	call := callflow.Call{From: *from, Time: time.Now()}
+	var err error
+	if call.State, err = callflow.ParseState(*state); err != nil {
+		return err
+	}
+	loc := time.Local
+	if *tz != "" {
+		if loc, err = time.LoadLocation(*tz); err != nil {
+			return err
+		}
+	}
+	if *at != "" {
+		if call.Time, err = time.ParseInLocation("2006-01-02 15:04", *at, loc); err != nil {
+			return beelineapi.Wrap("Неверное время вызова. ", err)
+		}
+	} else {
+		call.Time = call.Time.In(loc)
+	}
+	snap := callflow.Snapshot{}
+	if *file != "" {
+		r, err := openInput(*file)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := json.NewDecoder(r).Decode(&snap); err != nil {
+			return beelineapi.Wrap("Ошибка при чтении снимка настроек. ", err)
+		}
+	} else {
+		id, err := c.abonentId(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		if snap, err = callflow.Fetch(client, id); err != nil {
+			return err
+		}
+	}
+	sim := callflow.Simulator{}
+	if *cal != "" {
+		wc, err := calendar.Load(*cal)
+		if err != nil {
+			return err
+		}
+		sim.WorkingTime = wc.IsWorkingTime
+	}
+	res := sim.Evaluate(snap, call)
+	rows := [][]string{}
+	for i, step := range res.Trace {
+		rows = append(rows, []string{strconv.Itoa(i + 1), step})
+	}
+	if err := c.print(res, []string{"ШАГ", "ОПИСАНИЕ"}, rows); err != nil {
+		return err
+	}
+	if c.json {
+		return nil
+	}
+	return c.done("Итог: " + res.String())
+}
+
+// forwardingSnapshot Выводит снимок настроек абонента в формате JSON для forwarding simulate -f
+func forwardingSnapshot(c *cli, args []string) error {
+	a, err := parse(flags("forwarding snapshot"), args, 1, "<абонент>")
+	if err != nil {
+		return err
+	}
+	if a[0], err = c.abonentId(a[0]); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	snap, err := callflow.Fetch(client, a[0])
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
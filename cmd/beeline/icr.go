@@ -0,0 +1,226 @@
+package main
+
+import (
+	"github.com/taigasys/beeline-portal-api/icr"
+)
+
+// icrPlan План изменений правил индивидуальной переадресации
+type icrPlan struct {
+	Add    []icr.IcrRouteRule `json:"add"`
+	Delete []icr.IcrRouteRule `json:"delete"`
+}
+
+func init() {
+	commands["icr"] = command{
+		usage: "  icr numbers | redirect-numbers | rules | add|delete <входящий номер> <добавочный> | enable|disable <номер>...\n" +
+			"      export | diff -f файл.csv | apply [-dry-run] [-merge] -f файл.csv\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"numbers":          icrNumbers(false),
+			"redirect-numbers": icrNumbers(true),
+			"rules":            icrRules,
+			"add":              icrRoute("POST"),
+			"delete":           icrRoute("DELETE"),
+			"enable":           icrToggle(true),
+			"disable":          icrToggle(false),
+			"export":           icrExport,
+			"diff":             icrApply(true),
+			"apply":            icrApply(false),
+		},
+	}
+}
+
+// icrNumbers Возвращает действие вывода всех входящих номеров или номеров с индивидуальной переадресацией
+func icrNumbers(redirect bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		if _, err := parse(flags("icr numbers"), args, 0, ""); err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := icr.New(client)
+		var list []icr.NumberInfo
+		if redirect {
+			list, err = s.GetIncNumWithRedirect()
+		} else {
+			list, err = s.GetAllIncNumbers()
+		}
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, n := range list {
+			rows = append(rows, []string{n.NumberId, n.Phone})
+		}
+		return c.print(list, []string{"ID", "ТЕЛЕФОН"}, rows)
+	}
+}
+
+func icrRules(c *cli, args []string) error {
+	if _, err := parse(flags("icr rules"), args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	rules, err := icr.New(client).GetRedirectRulesList()
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, r := range rules {
+		rows = append(rows, []string{r.InboundNumber, r.Extension})
+	}
+	return c.print(rules, []string{"ВХОДЯЩИЙ", "ДОБАВОЧНЫЙ"}, rows)
+}
+
+// icrRoute Возвращает действие добавления (POST) или удаления (DELETE) правила индивидуальной переадресации
+func icrRoute(method string) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		a, err := parse(flags("icr"), args, 2, "<входящий номер> <добавочный>")
+		if err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := icr.New(client)
+		rules := []icr.IcrRouteRule{{InboundNumber: a[0], Extension: a[1]}}
+		var res []icr.IcrRouteResult
+		if method == "POST" {
+			res, err = s.UnionRedirectRulesList(rules)
+		} else {
+			res, err = s.DeleteRedirectRulesList(rules)
+		}
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, r := range res {
+			rows = append(rows, []string{r.Rule.InboundNumber, r.Rule.Extension, r.Status.String(), r.Error.Description})
+		}
+		return c.print(res, []string{"ВХОДЯЩИЙ", "ДОБАВОЧНЫЙ", "РЕЗУЛЬТАТ", "ОШИБКА"}, rows)
+	}
+}
+
+// icrToggle Возвращает действие включения или отключения индивидуальной переадресации для номеров
+func icrToggle(on bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		fs := flags("icr")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return parseUsage(fs, "<номер>...")
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := icr.New(client)
+		var res []icr.IcrNumbersResult
+		if on {
+			res, err = s.TurnOnCustomIncNumRedirect(fs.Args())
+		} else {
+			res, err = s.TurnOffCustomIncNumRedirect(fs.Args())
+		}
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, r := range res {
+			rows = append(rows, []string{r.PhoneNumber, r.Status.String(), r.Error.Description})
+		}
+		return c.print(res, []string{"НОМЕР", "РЕЗУЛЬТАТ", "ОШИБКА"}, rows)
+	}
+}
+
+func icrExport(c *cli, args []string) error {
+	if _, err := parse(flags("icr export"), args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	rules, err := icr.New(client).GetRedirectRulesList()
+	if err != nil {
+		return err
+	}
+	return icr.WriteCSV(c.out, rules)
+}
+
+// icrApply Возвращает действие сравнения правил из CSV с текущими правилами и их применения.
+// По умолчанию правила замещаются списком из файла, с -merge только добавляются новые правила.
+// Если diffOnly или указан -dry-run, выводится только план изменений.
+func icrApply(diffOnly bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		fs := flags("icr apply")
+		file := fs.String("f", "", "файл CSV со столбцами inboundNumber, extension; - для стандартного ввода")
+		dryRun := fs.Bool("dry-run", diffOnly, "только вывести план изменений")
+		merge := fs.Bool("merge", false, "только добавить новые правила, не удаляя отсутствующие в файле")
+		if _, err := parse(fs, args, 0, ""); err != nil {
+			return err
+		}
+		f, err := openInput(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		desired, err := icr.ReadCSV(f)
+		if err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		s := icr.New(client)
+		current, err := s.GetRedirectRulesList()
+		if err != nil {
+			return err
+		}
+		plan := icrPlan{}
+		plan.Add, plan.Delete = icr.Diff(current, desired)
+		if *merge {
+			plan.Delete = nil
+		}
+		if *dryRun || len(plan.Add)+len(plan.Delete) == 0 {
+			return printIcrPlan(c, plan)
+		}
+		var res []icr.IcrRouteResult
+		if *merge {
+			res, err = s.UnionRedirectRulesList(plan.Add)
+		} else {
+			res, err = s.ReplaceRedirectRulesList(desired)
+		}
+		if err != nil {
+			return err
+		}
+		if err := printIcrPlan(c, plan); err != nil {
+			return err
+		}
+		if err := icr.Failed(res); err != nil {
+			return err
+		}
+		return c.done("Изменения применены")
+	}
+}
+
+// printIcrPlan Выводит план изменений правил индивидуальной переадресации
+func printIcrPlan(c *cli, p icrPlan) error {
+	rows := [][]string{}
+	for _, r := range p.Add {
+		rows = append(rows, []string{"+", r.InboundNumber, r.Extension})
+	}
+	for _, r := range p.Delete {
+		rows = append(rows, []string{"-", r.InboundNumber, r.Extension})
+	}
+	if !c.json && len(rows) == 0 {
+		return c.done("Изменений нет")
+	}
+	return c.print(p, []string{"", "ВХОДЯЩИЙ", "ДОБАВОЧНЫЙ"}, rows)
+}
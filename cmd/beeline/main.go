@@ -0,0 +1,258 @@
+// Команда beeline - консольный клиент API портала облачной АТС Билайн.
+//
+// Использование:
+//
+//	beeline [-json] [-config файл] <команда> <действие> [параметры] [аргументы]
+//
+// Ключ безопасности берется из переменной окружения BEELINE_TOKEN или из файла
+// настроек в формате JSON ({"token": "...", "baseUrl": "..."}). Файл настроек задается
+// параметром -config или переменной BEELINE_CONFIG, по умолчанию beeline/config.json
+// в каталоге настроек пользователя. Адрес API можно переопределить переменной BEELINE_URL.
+// Вместо ключа можно указать файл с ключом в переменной BEELINE_TOKEN_FILE или в поле tokenFile
+// файла настроек: файл перечитывается при изменении, что позволяет менять ключ без перезапуска.
+// Действия, не обращающиеся к API (retention verify, forwarding simulate -f, calendar check), ключа не требуют.
+//
+// Абонент указывается идентификатором, мобильным номером в любом формате, добавочным номером или email.
+// Результат выводится таблицей или, с параметром -json, в формате JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+)
+
+// config Настройки клиента из файла настроек
+type config struct {
+	Token     string `json:"token"`     // Ключ безопасности
+	TokenFile string `json:"tokenFile"` // Файл с ключом безопасности, используется, если ключ не задан
+	BaseURL   string `json:"baseUrl"`   // Адрес API портала
+}
+
+// cli Состояние выполнения команды
+type cli struct {
+	cfg    config
+	client *beelineapi.APIClient // Клиент API, создается при первом обращении к API
+	out    io.Writer
+	json   bool // Выводить результат в формате JSON
+
+	resolver *abonents.Resolver
+}
+
+// command Команда верхнего уровня с набором действий
+type command struct {
+	usage   string
+	actions map[string]func(c *cli, args []string) error
+}
+
+// commands Команды по имени
+var commands = map[string]command{}
+
+func main() {
+	if err := run(os.Args[1:], os.Getenv, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run Разбирает аргументы командной строки и выполняет команду
+// args - аргументы без имени программы
+// getenv - функция чтения переменных окружения
+// out - вывод результата
+func run(args []string, getenv func(string) string, out io.Writer) error {
+	fs := flag.NewFlagSet("beeline", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "выводить результат в формате JSON")
+	cfgPath := fs.String("config", getenv("BEELINE_CONFIG"), "файл настроек")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: beeline [-json] [-config файл] <команда> <действие> [параметры] [аргументы]")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "Команды:")
+		fmt.Fprint(fs.Output(), usage())
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("Не указаны команда и действие")
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("Неизвестная команда %q. Команды:\n%s", fs.Arg(0), usage())
+	}
+	action, ok := cmd.actions[fs.Arg(1)]
+	if !ok {
+		return fmt.Errorf("Неизвестное действие %q. Использование:\n%s", fs.Arg(1), cmd.usage)
+	}
+	cfg, err := loadConfig(*cfgPath, getenv)
+	if err != nil {
+		return err
+	}
+	return action(&cli{cfg: cfg, out: out, json: *asJSON}, fs.Args()[2:])
+}
+
+// api Возвращает клиент API, создавая его при первом обращении.
+// Действия, работающие только с локальными файлами, клиент не запрашивают и выполняются без ключа.
+func (c *cli) api() (*beelineapi.APIClient, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	opts := []beelineapi.Option{beelineapi.WithUserAgent("beeline-cli")}
+	if c.cfg.BaseURL != "" {
+		opts = append(opts, beelineapi.WithBaseURL(c.cfg.BaseURL))
+	}
+	if c.cfg.Token == "" && c.cfg.TokenFile != "" {
+		opts = append(opts, beelineapi.WithTokenSource(beelineapi.NewFileToken(c.cfg.TokenFile)))
+	}
+	client, err := beelineapi.NewClient(c.cfg.Token, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s. Задайте ключ в переменной BEELINE_TOKEN, файл с ключом в BEELINE_TOKEN_FILE или ключ в файле настроек", err)
+	}
+	c.client = client
+	return client, nil
+}
+
+// loadConfig Читает настройки из файла path и переменных окружения.
+// Переменные окружения имеют приоритет над файлом. Отсутствие файла по умолчанию не является ошибкой.
+func loadConfig(path string, getenv func(string) string) (config, error) {
+	cfg := config{}
+	explicit := path != ""
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "beeline", "config.json")
+		}
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, &cfg); err != nil {
+				return cfg, fmt.Errorf("Ошибка при разборе файла настроек %s: %s", path, err)
+			}
+		case explicit || !os.IsNotExist(err):
+			return cfg, fmt.Errorf("Ошибка при чтении файла настроек: %s", err)
+		}
+	}
+	if t := getenv("BEELINE_TOKEN"); t != "" {
+		cfg.Token = t
+	}
+	if f := getenv("BEELINE_TOKEN_FILE"); f != "" {
+		cfg.TokenFile = f
+	}
+	if u := getenv("BEELINE_URL"); u != "" {
+		cfg.BaseURL = u
+	}
+	return cfg, nil
+}
+
+// usage Возвращает список команд и их действий
+func usage() string {
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(commands[n].usage)
+	}
+	return b.String()
+}
+
+// print Выводит результат v в формате JSON или таблицей с заголовком header и строками rows
+func (c *cli) print(v interface{}, header []string, rows [][]string) error {
+	if c.json {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.Join(r, "\t"))
+	}
+	return w.Flush()
+}
+
+// done Выводит сообщение об успешном выполнении действия
+func (c *cli) done(msg string) error {
+	if c.json {
+		return c.print(map[string]string{"result": msg}, nil, nil)
+	}
+	_, err := fmt.Fprintln(c.out, msg)
+	return err
+}
+
+// abonentId Возвращает идентификатор абонента по идентификатору, мобильному или добавочному номеру
+// в любом формате или email
+func (c *cli) abonentId(key string) (string, error) {
+	dir, err := c.directory()
+	if err != nil {
+		return "", err
+	}
+	return dir.UserId(key)
+}
+
+// directory Возвращает справочник абонентов, загружаемый один раз за время выполнения команды
+func (c *cli) directory() (*abonents.Resolver, error) {
+	if c.resolver == nil {
+		client, err := c.api()
+		if err != nil {
+			return nil, err
+		}
+		c.resolver = abonents.NewResolver(abonents.New(client), 0)
+	}
+	return c.resolver, nil
+}
+
+// flags Возвращает набор параметров действия name
+func flags(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+// parse Разбирает параметры действия и проверяет количество аргументов
+func parse(fs *flag.FlagSet, args []string, n int, names string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != n {
+		return nil, parseUsage(fs, names)
+	}
+	return fs.Args(), nil
+}
+
+// parseUsage Возвращает ошибку с описанием использования действия
+func parseUsage(fs *flag.FlagSet, names string) error {
+	return fmt.Errorf("Использование: %s [параметры] %s", fs.Name(), names)
+}
+
+// splitList Разбивает список через запятую, пропуская пустые элементы
+func splitList(s string) []string {
+	list := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// openInput Открывает файл name или стандартный ввод, если указан "-"
+func openInput(name string) (io.ReadCloser, error) {
+	switch name {
+	case "":
+		return nil, fmt.Errorf("Не указан файл")
+	case "-":
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/callflow"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/icr"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// newEnv Возвращает функцию чтения переменных окружения для имитатора s
+func newEnv(s *beelinetest.Server) func(string) string {
+	env := map[string]string{"BEELINE_TOKEN": s.Token, "BEELINE_URL": s.URL}
+	return func(k string) string { return env[k] }
+}
+
+// TestCommands Тест на выполнение команд против имитатора портала
+func TestCommands(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Phone: "9000000001", Extension: "101", FirstName: "Иван", LastName: "Петров"})
+	date := beelineapi.UnixMilli{Time: time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)}
+	s.AddRecord(records.CallRecord{Id: "1", Phone: "9000000002", Date: date, Direction: beelineapi.INBOUND, Abonent: abonents.Abonent{UserId: "u1"}}, "call1", []byte("ID3"))
+	env := newEnv(s)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"abonents list", []string{"abonents", "list"}, "Петров Иван"},
+		{"agent set", []string{"agent", "set", "+7 900 000-00-01", "break"}, "Статус агента установлен"},
+		{"agent get", []string{"agent", "get", "u1"}, "BREAK"},
+		{"recording on", []string{"recording", "on", "u1"}, "включена"},
+		{"recording get", []string{"recording", "get", "u1"}, "ON"},
+		{"bwl add", []string{"bwl", "add", "-type", "white_list", "-phones", "9000000002,9000000003", "u1"}, "ID"},
+		{"bwl list", []string{"bwl", "list", "u1"}, "9000000002,9000000003"},
+		{"forwarding set", []string{"forwarding", "set", "-busy", "9000000004", "u1"}, "включена"},
+		{"forwarding get", []string{"forwarding", "get", "u1"}, "9000000004"},
+		{"forwarding simulate", []string{"forwarding", "simulate", "-from", "9000000009", "-state", "busy", "u1"}, "Итог: переадресация на 9000000004"},
+		{"calendar check", []string{"calendar", "check", "-at", "2024-03-08 12:00"}, "NON_WORKING_TIME_AND_HOLIDAYS"},
+		{"forwarding snapshot", []string{"forwarding", "snapshot", "u1"}, `"forwardBusyPhone": "9000000004"`},
+		{"records list", []string{"records", "list", "-all"}, "9000000002"},
+		{"records download", []string{"records", "download", "-o", "-", "1"}, "ID3"},
+		{"records stats", []string{"records", "stats", "-by", "abonent,direction", "-csv"}, "u1,INBOUND,1,1,0"},
+		{"records export", []string{"records", "export", "-columns", "id,phone,abonent.userId"}, "1,9000000002,u1"},
+		{"records export month", []string{"records", "export", "-month", "2024-03", "-tz", "UTC", "-columns", "id", "-format", "jsonl"}, `{"id":"1"}`},
+		{"records delete", []string{"records", "delete", "1"}, "Запись удалена"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(tt.args, env, &out); err != nil {
+				t.Fatalf("Ошибка выполнения команды: %s", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Fatalf("Вывод не содержит %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+	if len(s.Records()) != 0 {
+		t.Fatal("Запись не удалена")
+	}
+}
+
+// TestJSONOutput Тест на вывод в формате JSON
+func TestJSONOutput(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Extension: "101"})
+	var out bytes.Buffer
+	if err := run([]string{"-json", "abonents", "list"}, newEnv(s), &out); err != nil {
+		t.Fatalf("Ошибка выполнения команды: %s", err)
+	}
+	var list []abonents.Abonent
+	if err := json.Unmarshal(out.Bytes(), &list); err != nil {
+		t.Fatalf("Вывод не является JSON: %s\n%s", err, out.String())
+	}
+	if len(list) != 1 || list[0].UserId != "u1" {
+		t.Fatalf("Неверный список абонентов: %+v", list)
+	}
+}
+
+// TestConfig Тест на чтение ключа безопасности из файла настроек
+func TestConfig(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"token":"token","baseUrl":"`+s.URL+`"}`), 0600); err != nil {
+		t.Fatalf("Не удалось записать файл настроек: %s", err)
+	}
+	noEnv := func(string) string { return "" }
+	var out bytes.Buffer
+	if err := run([]string{"-config", path, "icr", "rules"}, noEnv, &out); err != nil {
+		t.Fatalf("Ошибка выполнения команды: %s", err)
+	}
+	if err := run([]string{"-config", filepath.Join(t.TempDir(), "missing.json"), "icr", "rules"}, noEnv, &out); err == nil {
+		t.Fatal("Ожидалась ошибка для отсутствующего файла настроек")
+	}
+	if err := run([]string{"agent", "jump", "u1"}, noEnv, &out); err == nil {
+		t.Fatal("Ожидалась ошибка для неизвестного действия")
+	}
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("token\n"), 0600)
+	fileEnv := func(name string) string {
+		return map[string]string{"BEELINE_TOKEN_FILE": tokenFile, "BEELINE_URL": s.URL}[name]
+	}
+	if err := run([]string{"icr", "rules"}, fileEnv, &out); err != nil {
+		t.Fatalf("Ошибка выполнения команды с ключом из файла: %s", err)
+	}
+}
+
+// TestApplyCSV Тест на сравнение и применение правил из CSV
+func TestApplyCSV(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	s.AddNumber(icr.NumberInfo{NumberId: "n1", Phone: "4950000001"})
+	env := newEnv(s)
+	dir := t.TempDir()
+	bwlFile := filepath.Join(dir, "bwl.csv")
+	os.WriteFile(bwlFile, []byte("type;name;phones\nBLACK_LIST;spam;9000000001\n"), 0600)
+	icrFile := filepath.Join(dir, "icr.csv")
+	os.WriteFile(icrFile, []byte("inboundNumber,extension\n4950000001,101\n"), 0600)
+
+	var out bytes.Buffer
+	if err := run([]string{"bwl", "apply", "-dry-run", "-f", bwlFile, "u1"}, env, &out); err != nil {
+		t.Fatalf("Ошибка сравнения правил: %s", err)
+	}
+	if !strings.Contains(out.String(), "spam") {
+		t.Fatalf("План не содержит новое правило:\n%s", out.String())
+	}
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if st, _ := bwl.New(client).IncCallRules("u1"); len(st.BlackList) != 0 {
+		t.Fatal("В режиме dry-run правила не должны изменяться")
+	}
+	if err := run([]string{"bwl", "apply", "-f", bwlFile, "u1"}, env, &out); err != nil {
+		t.Fatalf("Ошибка применения правил: %s", err)
+	}
+	out.Reset()
+	if err := run([]string{"bwl", "diff", "-f", bwlFile, "u1"}, env, &out); err != nil || !strings.Contains(out.String(), "Изменений нет") {
+		t.Fatalf("После применения остались изменения: %v\n%s", err, out.String())
+	}
+
+	if err := run([]string{"icr", "apply", "-f", icrFile}, env, &out); err == nil {
+		t.Fatal("Ожидалась ошибка для номера без индивидуальной переадресации")
+	}
+	if err := run([]string{"icr", "enable", "4950000001"}, env, &out); err != nil {
+		t.Fatalf("Не удалось включить индивидуальную переадресацию: %s", err)
+	}
+	if err := run([]string{"icr", "apply", "-f", icrFile}, env, &out); err != nil {
+		t.Fatalf("Ошибка применения правил: %s", err)
+	}
+	out.Reset()
+	if err := run([]string{"icr", "export"}, env, &out); err != nil || out.String() != "inboundNumber,extension\n4950000001,101\n" {
+		t.Fatalf("Неверная выгрузка правил: %v\n%s", err, out.String())
+	}
+}
+
+// TestReconcile Тест на построение плана и применение конфигурации
+func TestReconcile(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Extension: "101"})
+	env := newEnv(s)
+	file := filepath.Join(t.TempDir(), "pbx.yaml")
+	os.WriteFile(file, []byte("abonents:\n  - id: u1\n    recording: true\n"), 0600)
+
+	var out bytes.Buffer
+	if err := run([]string{"reconcile", "plan", "-f", file}, env, &out); err != nil || !strings.Contains(out.String(), "recording") {
+		t.Fatalf("Неверный план: %v\n%s", err, out.String())
+	}
+	if err := run([]string{"reconcile", "apply", "-f", file}, env, &out); err != nil {
+		t.Fatalf("Ошибка применения конфигурации: %s", err)
+	}
+	out.Reset()
+	if err := run([]string{"reconcile", "plan", "-f", file}, env, &out); err != nil || !strings.Contains(out.String(), "Изменений нет") {
+		t.Fatalf("После применения остались изменения: %v\n%s", err, out.String())
+	}
+}
+
+// TestRetention Тест на применение политики хранения записей
+func TestRetention(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	old := beelineapi.UnixMilli{Time: time.Now().AddDate(-1, 0, 0)}
+	s.AddRecord(records.CallRecord{Id: "1", Date: old}, "", []byte("ID3"))
+	s.AddRecord(records.CallRecord{Id: "2", Date: beelineapi.UnixMilli{Time: time.Now()}}, "", []byte("ID3"))
+	env := newEnv(s)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "policy.yaml")
+	os.WriteFile(file, []byte("rules:\n  - name: old\n    action: delete\n    olderThanDays: 30\n"), 0600)
+	audit := filepath.Join(dir, "audit.jsonl")
+	archive := filepath.Join(dir, "archive")
+
+	var out bytes.Buffer
+	if err := run([]string{"retention", "plan", "-f", file}, env, &out); err != nil || !strings.Contains(out.String(), "delete") {
+		t.Fatalf("Неверный отчет: %v\n%s", err, out.String())
+	}
+	if len(s.Records()) != 2 {
+		t.Fatal("План не должен удалять записи")
+	}
+	if err := run([]string{"retention", "apply", "-archive", archive, "-audit", audit, "-f", file}, env, &out); err != nil {
+		t.Fatalf("Ошибка применения политики: %s", err)
+	}
+	if recs := s.Records(); len(recs) != 1 || recs[0].Id != "2" {
+		t.Fatalf("Неверные оставшиеся записи: %+v", recs)
+	}
+	if b, _ := os.ReadFile(audit); !strings.Contains(string(b), `"recordId":"1"`) {
+		t.Fatalf("Удаление не записано в журнал аудита: %s", b)
+	}
+	out.Reset()
+	if err := run([]string{"retention", "verify", "-archive", archive}, env, &out); err != nil || !strings.Contains(out.String(), "OK") {
+		t.Fatalf("Архив не прошел проверку: %v\n%s", err, out.String())
+	}
+	os.WriteFile(filepath.Join(archive, old.UTC().Format("2006-01"), "1.mp3"), []byte("ID"), 0644)
+	if err := run([]string{"retention", "verify", "-archive", archive}, env, &out); err == nil {
+		t.Fatal("Измененный файл архива должен не пройти проверку")
+	}
+}
+
+// TestOfflineCommands Тест на выполнение действий с локальными файлами без ключа безопасности
+func TestOfflineCommands(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddRecord(records.CallRecord{Id: "1", Date: beelineapi.UnixMilli{Time: time.Now().AddDate(-1, 0, 0)}}, "", []byte("ID3"))
+	dir := t.TempDir()
+	policy := filepath.Join(dir, "policy.yaml")
+	os.WriteFile(policy, []byte("rules:\n  - name: old\n    action: delete\n    olderThanDays: 30\n"), 0600)
+	archive := filepath.Join(dir, "archive")
+	var out bytes.Buffer
+	if err := run([]string{"retention", "apply", "-archive", archive, "-f", policy}, newEnv(s), &out); err != nil {
+		t.Fatalf("Ошибка применения политики: %s", err)
+	}
+	snap, _ := json.Marshal(callflow.Snapshot{Basic: forwarding.BasicRedirectResponse{Status: beelineapi.ON, Forward: forwarding.BasicRedirect{ForwardBusyPhone: "9000000004"}}})
+	snapFile := filepath.Join(dir, "snapshot.json")
+	os.WriteFile(snapFile, snap, 0600)
+	cfg := filepath.Join(dir, "config.json")
+	os.WriteFile(cfg, []byte("{}"), 0600)
+	noEnv := func(string) string { return "" }
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"retention verify", []string{"retention", "verify", "-archive", archive}, "OK"},
+		{"forwarding simulate", []string{"forwarding", "simulate", "-state", "busy", "-f", snapFile}, "Итог: переадресация на 9000000004"},
+		{"calendar check", []string{"calendar", "check", "-at", "2024-03-08 12:00"}, "NON_WORKING_TIME_AND_HOLIDAYS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			if err := run(append([]string{"-config", cfg}, tt.args...), noEnv, &out); err != nil {
+				t.Fatalf("Ошибка выполнения команды без ключа: %s", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Fatalf("Вывод не содержит %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+	if err := run([]string{"-config", cfg, "abonents", "list"}, noEnv, &out); err == nil || !strings.Contains(err.Error(), "BEELINE_TOKEN") {
+		t.Fatalf("Ожидалась ошибка об отсутствии ключа: %v", err)
+	}
+}
+
+// TestSnapshot Тест на снятие снимка настроек абонентов и восстановление из него
+func TestSnapshot(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Phone: "9000000001"})
+	s.AddAbonent(abonents.Abonent{UserId: "u2"})
+	env := newEnv(s)
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	ab := abonents.New(client)
+	ab.TurnOnRecording("u1")
+
+	var out bytes.Buffer
+	if err := run([]string{"snapshot", "take", "-o", file}, env, &out); err != nil || !strings.Contains(out.String(), "2") {
+		t.Fatalf("Ошибка снятия снимка: %v\n%s", err, out.String())
+	}
+	ab.TurnOffRecording("u1")
+	ab.TurnOffRecording("u2")
+	out.Reset()
+	if err := run([]string{"snapshot", "restore", "-f", file, "+7 900 000-00-01"}, env, &out); err != nil || strings.Count(out.String(), "OK") != 1 {
+		t.Fatalf("Ошибка восстановления: %v\n%s", err, out.String())
+	}
+	if st, _ := ab.GetRecordingStatus("u1"); st != beelineapi.ON {
+		t.Fatal("Запись разговоров не восстановлена")
+	}
+	if err := run([]string{"snapshot", "restore", "-f", file, "u3"}, env, &out); err == nil {
+		t.Fatal("Ожидалась ошибка для абонента, которого нет в снимке")
+	}
+}
+
+// TestDepartment Тест на операции над отделом
+func TestDepartment(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Department: "Смена"})
+	s.AddAbonent(abonents.Abonent{UserId: "u2", Department: "Смена"})
+	env := newEnv(s)
+	var out bytes.Buffer
+	if err := run([]string{"department", "agent", "смена", "BREAK"}, env, &out); err != nil {
+		t.Fatalf("Ошибка выполнения команды: %s", err)
+	}
+	if strings.Count(out.String(), "OK") != 2 {
+		t.Fatalf("Неверный отчет:\n%s", out.String())
+	}
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if st, _ := abonents.New(client).GetAgentStatus("u2"); st != beelineapi.BREAK {
+		t.Fatal("Статус агента не установлен")
+	}
+}
+
+// TestDownload Тест на сохранение записи с расширением по формату файла
+func TestDownload(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVE"), make([]byte, 32)...)
+	s.AddRecord(records.CallRecord{Id: "7", FileSize: len(wav)}, "call7", wav)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var out bytes.Buffer
+	if err := run([]string{"records", "download", "7"}, newEnv(s), &out); err != nil {
+		t.Fatalf("Ошибка выполнения команды: %s", err)
+	}
+	data, err := os.ReadFile("7.wav")
+	if err != nil {
+		t.Fatalf("Файл записи не сохранён: %s\n%s", err, out.String())
+	}
+	if !bytes.Equal(data, wav) {
+		t.Fatal("Содержимое файла не совпадает")
+	}
+	if _, err := os.Stat("7.part"); !os.IsNotExist(err) {
+		t.Fatal("Временный файл не удалён")
+	}
+}
+
+// TestNonNumericRecords Тест на обход всех записей с нечисловыми идентификаторами
+func TestNonNumericRecords(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	date := beelineapi.UnixMilli{Time: time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)}
+	for _, id := range []string{"1", "call-2", "call-3"} {
+		s.AddRecord(records.CallRecord{Id: id, Date: date, Direction: beelineapi.INBOUND, Abonent: abonents.Abonent{UserId: "u1"}}, "", nil)
+	}
+	env := newEnv(s)
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"records list", []string{"records", "list", "-all"}, "call-3"},
+		{"records list from", []string{"records", "list", "-all", "-from", "call-2"}, "call-3"},
+		{"records stats", []string{"records", "stats", "-by", "abonent", "-csv"}, "u1,3"},
+		{"records export", []string{"records", "export", "-columns", "id"}, "call-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(tt.args, env, &out); err != nil {
+				t.Fatalf("Ошибка выполнения команды: %s", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Fatalf("Вывод не содержит %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+// TestExportFormat Тест на проверку формата выгрузки до получения записей и создания файла
+func TestExportFormat(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.InjectError("GET", "/records", 500, beelineapi.APIError{ErrorCode: "Internal"}, 0)
+	file := filepath.Join(t.TempDir(), "export.pdf")
+	var out bytes.Buffer
+	err := run([]string{"records", "export", "-format", "pdf", "-o", file}, newEnv(s), &out)
+	if err == nil || !strings.Contains(err.Error(), "формат") {
+		t.Fatalf("Ожидалась ошибка формата выгрузки, получено %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("Файл выгрузки не должен создаваться")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/taigasys/beeline-portal-api/reconcile"
+)
+
+func init() {
+	commands["reconcile"] = command{
+		usage: "  reconcile plan -f файл.yaml | apply [-dry-run] -f файл.yaml\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"plan":  reconcileApply(true),
+			"apply": reconcileApply(false),
+		},
+	}
+}
+
+// reconcileApply Возвращает действие сравнения настроек абонентов с конфигурацией из файла и их изменения.
+// Если planOnly или указан -dry-run, выводится только план изменений.
+func reconcileApply(planOnly bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		fs := flags("reconcile apply")
+		file := fs.String("f", "", "файл конфигурации в формате YAML или JSON")
+		dryRun := fs.Bool("dry-run", planOnly, "только вывести план изменений")
+		if _, err := parse(fs, args, 0, ""); err != nil {
+			return err
+		}
+		if *file == "" {
+			return parseUsage(fs, "")
+		}
+		cfg, err := reconcile.Load(*file)
+		if err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		r := reconcile.New(client)
+		plan, err := r.Plan(cfg)
+		if err != nil {
+			return err
+		}
+		if err := printPlan(c, plan); err != nil {
+			return err
+		}
+		if *dryRun || plan.Empty() {
+			return nil
+		}
+		if err := r.Apply(plan); err != nil {
+			return err
+		}
+		return c.done("Изменения применены")
+	}
+}
+
+// printPlan Выводит план изменений настроек абонентов
+func printPlan(c *cli, p reconcile.Plan) error {
+	if !c.json && p.Empty() {
+		return c.done("Изменений нет")
+	}
+	rows := [][]string{}
+	for _, ch := range p.Changes {
+		rows = append(rows, []string{ch.Abonent, ch.Setting, ch.Description})
+	}
+	return c.print(p, []string{"АБОНЕНТ", "НАСТРОЙКА", "ИЗМЕНЕНИЕ"}, rows)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/analytics"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+func init() {
+	commands["records"] = command{
+		usage: "  records list [-from ID] [-all] | download [-o файл] <запись> | delete <запись>\n" +
+			"  records stats [-by признаки] [-f архив.json] [-tz пояс] [-top N] [-csv]\n" +
+			"  records export [-format csv|jsonl|xlsx] [-columns столбцы] [-month 2006-01] [-tz пояс] [-o файл]\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"list":     recordsList,
+			"download": recordsDownload,
+			"delete":   recordsDelete,
+			"stats":    recordsStats,
+			"export":   recordsExport,
+		},
+	}
+}
+
+func recordsList(c *cli, args []string) error {
+	fs := flags("records list")
+	from := fs.String("from", "", "начать со следующей после записи с этим ID")
+	all := fs.Bool("all", false, "вывести все записи, а не одну страницу")
+	if _, err := parse(fs, args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	s := records.New(client)
+	var list []records.CallRecord
+	if *all {
+		list = []records.CallRecord{}
+		err = s.ForEachRecordAfter(*from, func(r records.CallRecord) error {
+			list = append(list, r)
+			return nil
+		})
+	} else {
+		var id int64
+		if *from != "" {
+			if id, err = strconv.ParseInt(*from, 10, 64); err != nil {
+				return fmt.Errorf("Для одной страницы нужен числовой ID записи %q, используйте -all", *from)
+			}
+		}
+		list, err = s.GetRecords(id)
+	}
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, r := range list {
+		rows = append(rows, []string{r.Id, r.Date.Format("2006-01-02 15:04:05"), r.Direction.String(), r.Phone, r.Abonent.Extension,
+			strconv.Itoa(r.Duration / 1000), strconv.Itoa(r.FileSize), r.Comment})
+	}
+	return c.print(list, []string{"ID", "ДАТА", "НАПРАВЛЕНИЕ", "ТЕЛЕФОН", "ДОБАВОЧНЫЙ", "СЕКУНД", "РАЗМЕР", "КОММЕНТАРИЙ"}, rows)
+}
+
+func recordsDownload(c *cli, args []string) error {
+	fs := flags("records download")
+	out := fs.String("o", "", "файл для сохранения записи, по умолчанию <запись> с расширением по формату файла")
+	a, err := parse(fs, args, 1, "<запись>")
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	s := records.New(client)
+	rec := records.CallRecord{Id: a[0]}
+	if *out == "-" {
+		_, err = s.DownloadTo(rec, c.out)
+		return err
+	}
+	name := *out
+	if name == "" {
+		// Расширение известно только после получения файла, поэтому он сохраняется во временный
+		name = a[0] + ".part"
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = s.DownloadTo(rec, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		final := a[0] + fileExt(name)
+		if err := os.Rename(name, final); err != nil {
+			return err
+		}
+		name = final
+	}
+	return c.done("Запись сохранена в " + name)
+}
+
+// fileExt Возвращает расширение по формату сохранённого файла записи, по умолчанию .mp3
+func fileExt(name string) string {
+	head := make([]byte, 12)
+	f, err := os.Open(name)
+	if err != nil {
+		return ".mp3"
+	}
+	defer f.Close()
+	n, _ := io.ReadFull(f, head)
+	if ext := records.DetectFormat("", head[:n]).Ext(); ext != "" {
+		return ext
+	}
+	return ".mp3"
+}
+
+func recordsDelete(c *cli, args []string) error {
+	a, err := parse(flags("records delete"), args, 1, "<запись>")
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := records.New(client).DeleteRecord(a[0]); err != nil {
+		return err
+	}
+	return c.done("Запись удалена")
+}
+
+func recordsStats(c *cli, args []string) error {
+	fs := flags("records stats")
+	by := fs.String("by", "abonent", "признаки группировки через запятую: abonent, department, direction, hour, weekday, day, month")
+	file := fs.String("f", "", "архив записей в формате JSON вместо запроса к API, - для стандартного ввода")
+	tz := fs.String("tz", "", "часовой пояс для группировки по времени, например Europe/Moscow")
+	top := fs.Int("top", 0, "вывести только N групп с наибольшим количеством звонков")
+	asCSV := fs.Bool("csv", false, "вывести отчет в формате CSV")
+	if _, err := parse(fs, args, 0, ""); err != nil {
+		return err
+	}
+	dims, err := analytics.ParseDimensions(*by)
+	if err != nil {
+		return err
+	}
+	a := analytics.New(dims...)
+	if *tz != "" {
+		if a.Location, err = time.LoadLocation(*tz); err != nil {
+			return err
+		}
+	}
+	if *file != "" {
+		f, err := openInput(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		err = analytics.ReadJSON(f, a.Add)
+	} else {
+		var client *beelineapi.APIClient
+		if client, err = c.api(); err != nil {
+			return err
+		}
+		err = records.New(client).ForEachRecordAfter("", a.Add)
+	}
+	if err != nil {
+		return err
+	}
+	rep := a.Report()
+	if *top > 0 {
+		rep.Rows = rep.Top(*top)
+	}
+	switch {
+	case *asCSV:
+		return rep.WriteCSV(c.out)
+	case c.json:
+		return rep.WriteJSON(c.out)
+	}
+	header := []string{}
+	for _, d := range dims {
+		header = append(header, strings.ToUpper(d.String()))
+	}
+	header = append(header, "ЗВОНКОВ", "ВХОДЯЩИХ", "ИСХОДЯЩИХ", "СРЕДНЯЯ, С", "МЕДИАНА, С", "P90, С", "МАКС, С")
+	rows := [][]string{}
+	for _, r := range rep.Rows {
+		rows = append(rows, append(append([]string{}, r.Group...), strconv.Itoa(r.Calls), strconv.Itoa(r.Inbound), strconv.Itoa(r.Outbound),
+			strconv.Itoa(r.AvgDuration/1000), strconv.Itoa(r.MedianDuration/1000), strconv.Itoa(r.P90Duration/1000), strconv.Itoa(r.MaxDuration/1000)))
+	}
+	return c.print(rep, header, rows)
+}
+
+func recordsExport(c *cli, args []string) error {
+	fs := flags("records export")
+	format := fs.String("format", "csv", "формат выгрузки: csv, jsonl или xlsx")
+	columns := fs.String("columns", strings.Join(records.DefaultColumns, ","), "столбцы выгрузки через запятую")
+	month := fs.String("month", "", "выгрузить только записи за месяц в формате 2006-01")
+	tz := fs.String("tz", "", "часовой пояс даты разговора, например Europe/Moscow")
+	out := fs.String("o", "-", "файл выгрузки, - для стандартного вывода")
+	if _, err := parse(fs, args, 0, ""); err != nil {
+		return err
+	}
+	var write func(io.Writer, []records.CallRecord, records.ExportOptions) error
+	switch *format {
+	case "csv":
+		write = records.WriteCSV
+	case "jsonl":
+		write = records.WriteJSONL
+	case "xlsx":
+		write = records.WriteXLSX
+	default:
+		return fmt.Errorf("Неизвестный формат выгрузки %q", *format)
+	}
+	opts := records.ExportOptions{Columns: splitList(*columns), Location: time.Local}
+	if *tz != "" {
+		loc, err := time.LoadLocation(*tz)
+		if err != nil {
+			return err
+		}
+		opts.Location = loc
+	}
+	var from, to time.Time
+	if *month != "" {
+		var err error
+		if from, err = time.ParseInLocation("2006-01", *month, opts.Location); err != nil {
+			return fmt.Errorf("Неверный месяц %q, ожидался формат 2006-01", *month)
+		}
+		to = from.AddDate(0, 1, 0)
+	}
+	list := []records.CallRecord{}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	err = records.New(client).ForEachRecordAfter("", func(r records.CallRecord) error {
+		if from.IsZero() || !r.Date.Before(from) && r.Date.Before(to) {
+			list = append(list, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		return write(c.out, list, opts)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = write(f, list, opts)
+	// Ошибка записи буферизованных данных может проявиться только при закрытии файла
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return c.done(fmt.Sprintf("Выгружено записей: %d", len(list)))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/taigasys/beeline-portal-api/retention"
+)
+
+func init() {
+	commands["retention"] = command{
+		usage: "  retention plan -f политика.yaml | apply [-archive каталог] [-audit файл] -f политика.yaml | verify -archive каталог\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"plan":   retentionRun(true),
+			"apply":  retentionRun(false),
+			"verify": retentionVerify,
+		},
+	}
+}
+
+// retentionRun Возвращает действие применения политики хранения записей разговоров из файла.
+// Если dryRun, выводится только список записей, подошедших под правила.
+func retentionRun(dryRun bool) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		fs := flags("retention apply")
+		file := fs.String("f", "", "политика хранения в формате YAML или JSON")
+		archive := fs.String("archive", "", "каталог для сохранения записей перед удалением")
+		audit := fs.String("audit", "", "файл журнала аудита удалений, дополняется")
+		if _, err := parse(fs, args, 0, ""); err != nil {
+			return err
+		}
+		if *file == "" {
+			return parseUsage(fs, "")
+		}
+		p, err := retention.Load(*file)
+		if err != nil {
+			return err
+		}
+		client, err := c.api()
+		if err != nil {
+			return err
+		}
+		e := retention.New(client, p)
+		e.DryRun = dryRun
+		if *archive != "" {
+			e.Archiver = retention.DirArchiver{Dir: *archive}
+		}
+		if *audit != "" && !dryRun {
+			f, err := os.OpenFile(*audit, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			e.Audit = f
+		}
+		rep, err := e.Run(context.Background())
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, d := range rep.Decisions {
+			result := string(d.Action)
+			switch {
+			case d.Error != "":
+				result = "ошибка: " + d.Error
+			case d.Deleted:
+				result = "удалена"
+			}
+			rows = append(rows, []string{d.Record.Id, d.Record.Date.Format("2006-01-02 15:04:05"), d.Record.Abonent.UserId, d.Rule, result})
+		}
+		for _, id := range rep.Skipped {
+			rows = append(rows, []string{id, "", "", "", "пропущена: нет даты разговора"})
+		}
+		if err := c.print(rep, []string{"ЗАПИСЬ", "ДАТА", "АБОНЕНТ", "ПРАВИЛО", "РЕЗУЛЬТАТ"}, rows); err != nil {
+			return err
+		}
+		if err := rep.Err(); err != nil {
+			return err
+		}
+		if dryRun || c.json {
+			return nil
+		}
+		return c.done(fmt.Sprintf("Проверено записей: %d, удалено: %d, пропущено без даты: %d", rep.Checked, rep.Deleted(), len(rep.Skipped)))
+	}
+}
+
+// retentionVerify Повторно проверяет размеры и контрольные суммы файлов архива по его манифесту
+func retentionVerify(c *cli, args []string) error {
+	fs := flags("retention verify")
+	archive := fs.String("archive", "", "каталог архива записей")
+	if _, err := parse(fs, args, 0, ""); err != nil {
+		return err
+	}
+	if *archive == "" {
+		return parseUsage(fs, "")
+	}
+	res, err := retention.VerifyArchive(*archive)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	failed := 0
+	for _, r := range res {
+		result := "OK"
+		if r.Error != "" {
+			result = "ошибка: " + r.Error
+			failed++
+		}
+		rows = append(rows, []string{r.File, r.RecordId, fmt.Sprint(r.Size), r.SHA256, result})
+	}
+	if err := c.print(res, []string{"ФАЙЛ", "ЗАПИСЬ", "РАЗМЕР", "SHA-256", "РЕЗУЛЬТАТ"}, rows); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("Не прошли проверку файлов: %d из %d", failed, len(res))
+	}
+	if c.json {
+		return nil
+	}
+	return c.done(fmt.Sprintf("Проверено файлов: %d", len(res)))
+}
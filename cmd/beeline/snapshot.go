@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/taigasys/beeline-portal-api/snapshot"
+)
+
+func init() {
+	commands["snapshot"] = command{
+		usage: "  snapshot take [-o файл] [абоненты...] | restore -f файл [абоненты...]\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"take":    snapshotTake,
+			"restore": snapshotRestore,
+		},
+	}
+}
+
+// snapshotTake Сохраняет настройки указанных абонентов или всех абонентов в снимок
+func snapshotTake(c *cli, args []string) error {
+	fs := flags("snapshot take")
+	out := fs.String("o", "-", "файл снимка, - для стандартного вывода")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids := []string{}
+	for _, key := range fs.Args() {
+		id, err := c.abonentId(key)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	snap, err := snapshot.New(client).Take(context.Background(), ids...)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = c.out
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := snap.Write(w); err != nil || *out == "-" {
+		return err
+	}
+	return c.done(fmt.Sprintf("Сохранены настройки абонентов: %d", len(snap.Abonents)))
+}
+
+// snapshotRestore Восстанавливает настройки абонентов из снимка. Если абоненты указаны,
+// восстанавливаются только их настройки.
+func snapshotRestore(c *cli, args []string) error {
+	fs := flags("snapshot restore")
+	file := fs.String("f", "", "файл снимка, - для стандартного ввода")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return parseUsage(fs, "[абоненты...]")
+	}
+	r, err := openInput(*file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	snap, err := snapshot.Read(r)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		only := []snapshot.Abonent{}
+		for _, key := range fs.Args() {
+			id, err := c.abonentId(key)
+			if err != nil {
+				return err
+			}
+			a, ok := snap.Find(id)
+			if !ok {
+				return fmt.Errorf("Абонента %s нет в снимке", key)
+			}
+			only = append(only, a)
+		}
+		snap.Abonents = only
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	return printReport(c, snapshot.New(client).Restore(context.Background(), snap))
+}
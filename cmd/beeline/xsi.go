@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/xsi"
+)
+
+func init() {
+	commands["subscriptions"] = command{
+		usage: "  subscriptions create -pattern -expires -type -url | get|delete <подписка>\n",
+		actions: map[string]func(c *cli, args []string) error{
+			"create": subscriptionsCreate,
+			"get":    subscriptionsGet,
+			"delete": subscriptionsDelete,
+		},
+	}
+}
+
+func subscriptionsCreate(c *cli, args []string) error {
+	fs := flags("subscriptions create")
+	req := xsi.SubscriptionRequest{}
+	fs.StringVar(&req.Pattern, "pattern", "", "идентификатор, входящий или добавочный номер абонента или номера")
+	fs.IntVar(&req.Expires, "expires", 3600, "длительность подписки в секундах")
+	typ := fs.String("type", beelineapi.BASIC_CALL.String(), "тип подписки: BASIC_CALL или ADVANCED_CALL")
+	fs.StringVar(&req.Url, "url", "", "адрес приложения для событий")
+	if _, err := parse(fs, args, 0, ""); err != nil {
+		return err
+	}
+	var err error
+	if req.SubscriptionType, err = beelineapi.ParseSubscriptionType(strings.ToUpper(*typ)); err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	res, err := xsi.New(client).XSIEventSubscription(req)
+	if err != nil {
+		return err
+	}
+	return c.print(res, []string{"ID", "ДЛИТЕЛЬНОСТЬ"}, [][]string{{res.SubscriptionId, strconv.Itoa(res.Expires)}})
+}
+
+func subscriptionsGet(c *cli, args []string) error {
+	a, err := parse(flags("subscriptions get"), args, 1, "<подписка>")
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	info, err := xsi.New(client).GetXSIEventSubscriptionInfo(a[0])
+	if err != nil {
+		return err
+	}
+	return c.print(info, []string{"ID", "ОБЪЕКТ", "ИДЕНТИФИКАТОР", "ТИП", "ДЛИТЕЛЬНОСТЬ", "URL"},
+		[][]string{{info.SubscriptionId, info.TargetType.String(), info.TargetId, info.SubscriptionType.String(), strconv.Itoa(info.Expires), info.Url}})
+}
+
+func subscriptionsDelete(c *cli, args []string) error {
+	a, err := parse(flags("subscriptions delete"), args, 1, "<подписка>")
+	if err != nil {
+		return err
+	}
+	client, err := c.api()
+	if err != nil {
+		return err
+	}
+	if err := xsi.New(client).TurnOffXSIEventSubscription(a[0]); err != nil {
+		return err
+	}
+	return c.done("Подписка отключена")
+}
@@ -0,0 +1,374 @@
+package beelineapi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Перечисления начинаются с 1, поэтому нулевое значение не входит в список допустимых:
+// отсутствующее в ответе сервера поле не принимается за первый элемент перечисления.
+// Нулевое значение означает, что значение не задано, и сериализуется в null.
+
+// Direction Тип вызова
+type Direction int
+
+const (
+	INBOUND  Direction = iota + 1 // Входящий вызов
+	OUTBOUND                      // Исходящий вызов
+)
+
+var directionNames = []string{"INBOUND", "OUTBOUND"}
+
+// Schedule Расписание действия правила
+type Schedule int
+
+const (
+	ROUND_THE_CLOCK               Schedule = iota + 1 // Круглосуточно
+	WORKING_TIME                                      // Рабочее время
+	NON_WORKING_TIME_AND_HOLIDAYS                     // Нерабочие часы и выходные
+)
+
+var scheduleNames = []string{"ROUND_THE_CLOCK", "WORKING_TIME", "NON_WORKING_TIME_AND_HOLIDAYS"}
+
+// BwlListType Тип списка правил выборочного приема звонков
+type BwlListType int
+
+const (
+	BLACK_LIST BwlListType = iota + 1 // Не принимать звонки с указанных в списке правил номеров
+	WHITE_LIST                        // Принимать звонки только с указанных в списке правил номеров
+)
+
+var bwlListTypeNames = []string{"BLACK_LIST", "WHITE_LIST"}
+
+// OperationStatus Результат выполнения операции
+type OperationStatus int
+
+const (
+	SUCCESS OperationStatus = iota + 1 // Успешно
+	FAULT                              // Ошибка
+)
+
+var operationStatusNames = []string{"SUCCESS", "FAULT"}
+
+// TargetType Тип объекта, для которого сформирована подписка
+type TargetType int
+
+const (
+	GROUP   TargetType = iota + 1 // События всей группы
+	ABONENT                       // События абонента
+	NUMBER                        // События номера
+)
+
+var targetTypeNames = []string{"GROUP", "ABONENT", "NUMBER"}
+
+// ServiceStatus Статус услуги абонента: записи разговоров или базовой переадресации
+type ServiceStatus int
+
+const (
+	OFF ServiceStatus = iota + 1 // Услуга отключена
+	ON                           // Услуга включена
+)
+
+var serviceStatusNames = []string{"OFF", "ON"}
+
+// AgentStatus Статус агента call-центра
+type AgentStatus int
+
+const (
+	ONLINE  AgentStatus = iota + 1 // Агент на линии
+	OFFLINE                        // Агент не на линии
+	BREAK                          // Агент на перерыве
+)
+
+var agentStatusNames = []string{"ONLINE", "OFFLINE", "BREAK"}
+
+// BwlStatus Статус выборочного приема звонков
+type BwlStatus int
+
+const (
+	BLACK_LIST_ON BwlStatus = iota + 1 // Не принимать звонки с указанных в списке правил номеров
+	WHITE_LIST_ON                      // Принимать звонки только с указанных в списке правил номеров
+	BWL_OFF                            // Услуга отключена, в API - OFF
+)
+
+var bwlStatusNames = []string{"BLACK_LIST_ON", "WHITE_LIST_ON", "OFF"}
+
+// SubscriptionType Тип подписки на Xsi-Events
+type SubscriptionType int
+
+const (
+	BASIC_CALL    SubscriptionType = iota + 1 // Базовая информация о вызове
+	ADVANCED_CALL                             // Расширенная информация о вызове
+)
+
+var subscriptionTypeNames = []string{"BASIC_CALL", "ADVANCED_CALL"}
+
+func (d Direction) String() string { return enumName(directionNames, int(d)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (d Direction) Valid() bool { return enumValid(directionNames, int(d)) }
+
+func (d Direction) MarshalJSON() ([]byte, error) {
+	return enumMarshal("Direction", directionNames, int(d))
+}
+
+func (d *Direction) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("Direction", directionNames, b)
+	if err != nil {
+		return err
+	}
+	*d = Direction(v)
+	return nil
+}
+
+// ParseDirection Возвращает тип вызова по его имени в API
+func ParseDirection(s string) (Direction, error) {
+	v, err := enumParse("Direction", directionNames, s)
+	return Direction(v), err
+}
+
+func (s Schedule) String() string { return enumName(scheduleNames, int(s)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (s Schedule) Valid() bool { return enumValid(scheduleNames, int(s)) }
+
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	return enumMarshal("Schedule", scheduleNames, int(s))
+}
+
+func (s *Schedule) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("Schedule", scheduleNames, b)
+	if err != nil {
+		return err
+	}
+	*s = Schedule(v)
+	return nil
+}
+
+// Effective Возвращает действующее расписание: незаданное расписание действует круглосуточно
+func (s Schedule) Effective() Schedule {
+	if s == 0 {
+		return ROUND_THE_CLOCK
+	}
+	return s
+}
+
+// ParseSchedule Возвращает расписание по его имени в API
+func ParseSchedule(s string) (Schedule, error) {
+	v, err := enumParse("Schedule", scheduleNames, s)
+	return Schedule(v), err
+}
+
+func (t BwlListType) String() string { return enumName(bwlListTypeNames, int(t)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (t BwlListType) Valid() bool { return enumValid(bwlListTypeNames, int(t)) }
+
+func (t BwlListType) MarshalJSON() ([]byte, error) {
+	return enumMarshal("BwlListType", bwlListTypeNames, int(t))
+}
+
+func (t *BwlListType) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("BwlListType", bwlListTypeNames, b)
+	if err != nil {
+		return err
+	}
+	*t = BwlListType(v)
+	return nil
+}
+
+// ParseBwlListType Возвращает тип списка правил по его имени в API
+func ParseBwlListType(s string) (BwlListType, error) {
+	v, err := enumParse("BwlListType", bwlListTypeNames, s)
+	return BwlListType(v), err
+}
+
+func (s OperationStatus) String() string { return enumName(operationStatusNames, int(s)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (s OperationStatus) Valid() bool { return enumValid(operationStatusNames, int(s)) }
+
+func (s OperationStatus) MarshalJSON() ([]byte, error) {
+	return enumMarshal("OperationStatus", operationStatusNames, int(s))
+}
+
+func (s *OperationStatus) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("OperationStatus", operationStatusNames, b)
+	if err != nil {
+		return err
+	}
+	*s = OperationStatus(v)
+	return nil
+}
+
+// ParseOperationStatus Возвращает результат операции по его имени в API
+func ParseOperationStatus(s string) (OperationStatus, error) {
+	v, err := enumParse("OperationStatus", operationStatusNames, s)
+	return OperationStatus(v), err
+}
+
+func (t TargetType) String() string { return enumName(targetTypeNames, int(t)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (t TargetType) Valid() bool { return enumValid(targetTypeNames, int(t)) }
+
+func (t TargetType) MarshalJSON() ([]byte, error) {
+	return enumMarshal("TargetType", targetTypeNames, int(t))
+}
+
+func (t *TargetType) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("TargetType", targetTypeNames, b)
+	if err != nil {
+		return err
+	}
+	*t = TargetType(v)
+	return nil
+}
+
+// ParseTargetType Возвращает тип объекта подписки по его имени в API
+func ParseTargetType(s string) (TargetType, error) {
+	v, err := enumParse("TargetType", targetTypeNames, s)
+	return TargetType(v), err
+}
+
+func (s ServiceStatus) String() string { return enumName(serviceStatusNames, int(s)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (s ServiceStatus) Valid() bool { return enumValid(serviceStatusNames, int(s)) }
+
+func (s ServiceStatus) MarshalJSON() ([]byte, error) {
+	return enumMarshal("ServiceStatus", serviceStatusNames, int(s))
+}
+
+func (s *ServiceStatus) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("ServiceStatus", serviceStatusNames, b)
+	if err != nil {
+		return err
+	}
+	*s = ServiceStatus(v)
+	return nil
+}
+
+// ParseServiceStatus Возвращает статус услуги по его имени в API
+func ParseServiceStatus(s string) (ServiceStatus, error) {
+	v, err := enumParse("ServiceStatus", serviceStatusNames, s)
+	return ServiceStatus(v), err
+}
+
+func (s AgentStatus) String() string { return enumName(agentStatusNames, int(s)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (s AgentStatus) Valid() bool { return enumValid(agentStatusNames, int(s)) }
+
+func (s AgentStatus) MarshalJSON() ([]byte, error) {
+	return enumMarshal("AgentStatus", agentStatusNames, int(s))
+}
+
+func (s *AgentStatus) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("AgentStatus", agentStatusNames, b)
+	if err != nil {
+		return err
+	}
+	*s = AgentStatus(v)
+	return nil
+}
+
+// ParseAgentStatus Возвращает статус агента по его имени в API
+func ParseAgentStatus(s string) (AgentStatus, error) {
+	v, err := enumParse("AgentStatus", agentStatusNames, s)
+	return AgentStatus(v), err
+}
+
+func (s BwlStatus) String() string { return enumName(bwlStatusNames, int(s)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (s BwlStatus) Valid() bool { return enumValid(bwlStatusNames, int(s)) }
+
+func (s BwlStatus) MarshalJSON() ([]byte, error) {
+	return enumMarshal("BwlStatus", bwlStatusNames, int(s))
+}
+
+func (s *BwlStatus) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("BwlStatus", bwlStatusNames, b)
+	if err != nil {
+		return err
+	}
+	*s = BwlStatus(v)
+	return nil
+}
+
+// ParseBwlStatus Возвращает статус выборочного приема звонков по его имени в API
+func ParseBwlStatus(s string) (BwlStatus, error) {
+	v, err := enumParse("BwlStatus", bwlStatusNames, s)
+	return BwlStatus(v), err
+}
+
+func (t SubscriptionType) String() string { return enumName(subscriptionTypeNames, int(t)) }
+
+// Valid Проверяет, что значение входит в список допустимых
+func (t SubscriptionType) Valid() bool { return enumValid(subscriptionTypeNames, int(t)) }
+
+func (t SubscriptionType) MarshalJSON() ([]byte, error) {
+	return enumMarshal("SubscriptionType", subscriptionTypeNames, int(t))
+}
+
+func (t *SubscriptionType) UnmarshalJSON(b []byte) error {
+	v, err := enumUnmarshal("SubscriptionType", subscriptionTypeNames, b)
+	if err != nil {
+		return err
+	}
+	*t = SubscriptionType(v)
+	return nil
+}
+
+// ParseSubscriptionType Возвращает тип подписки по его имени в API
+func ParseSubscriptionType(s string) (SubscriptionType, error) {
+	v, err := enumParse("SubscriptionType", subscriptionTypeNames, s)
+	return SubscriptionType(v), err
+}
+
+// enumValid Проверяет, что значение v есть в списке имен. Значению v соответствует имя names[v-1].
+func enumValid(names []string, v int) bool {
+	return v >= 1 && v <= len(names)
+}
+
+// enumName Возвращает имя значения в API или его числовое представление, если значение недопустимо
+func enumName(names []string, v int) string {
+	if !enumValid(names, v) {
+		return fmt.Sprintf("%d", v)
+	}
+	return names[v-1]
+}
+
+// enumMarshal Сериализует значение в строковое имя API, а незаданное значение - в null
+func enumMarshal(typeName string, names []string, v int) ([]byte, error) {
+	if v == 0 {
+		return []byte("null"), nil
+	}
+	if !enumValid(names, v) {
+		return nil, WrapError{Msg: fmt.Sprintf("Недопустимое значение %s: %d", typeName, v)}
+	}
+	return json.Marshal(names[v-1])
+}
+
+// enumUnmarshal Разбирает строковое имя API из JSON. null разбирается как незаданное значение.
+func enumUnmarshal(typeName string, names []string, b []byte) (int, error) {
+	if string(b) == "null" {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return 0, WrapError{Msg: fmt.Sprintf("Ошибка при разборе %s. Ожидалась строка, получено %s", typeName, b)}
+	}
+	return enumParse(typeName, names, s)
+}
+
+// enumParse Ищет имя s в списке имен
+func enumParse(typeName string, names []string, s string) (int, error) {
+	for i, n := range names {
+		if n == s {
+			return i + 1, nil
+		}
+	}
+	return 0, WrapError{Msg: fmt.Sprintf("Недопустимое значение %s: %q", typeName, s)}
+}
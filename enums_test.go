@@ -0,0 +1,101 @@
+package beelineapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// testRule Правило для проверки сериализации перечислений
+type testRule struct {
+	Type     BwlListType `json:"type"`
+	Schedule Schedule    `json:"schedule"`
+}
+
+// TestEnumJSON Тест на сериализацию перечислений в строковые имена API
+func TestEnumJSON(t *testing.T) {
+	rule := testRule{Type: WHITE_LIST, Schedule: NON_WORKING_TIME_AND_HOLIDAYS}
+	b, err := json.Marshal(rule)
+	if err != nil {
+		t.Fatalf("Не удалось сериализовать правило: %s", err)
+	}
+	want := `{"type":"WHITE_LIST","schedule":"NON_WORKING_TIME_AND_HOLIDAYS"}`
+	if string(b) != want {
+		t.Fatalf("Неверный JSON правила. Ожидалось %s получено %s", want, b)
+	}
+	var decoded testRule
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Не удалось разобрать правило: %s", err)
+	}
+	if decoded != rule {
+		t.Fatalf("Неверно разобрано правило: %+v", decoded)
+	}
+}
+
+// TestEnumValidation Тест на отклонение недопустимых значений перечислений
+func TestEnumValidation(t *testing.T) {
+	var d Direction
+	if err := json.Unmarshal([]byte(`"SIDEWAYS"`), &d); err == nil {
+		t.Fatal("Ожидалась ошибка при разборе недопустимого типа вызова")
+	}
+	if err := json.Unmarshal([]byte(`1`), &d); err == nil {
+		t.Fatal("Ожидалась ошибка при разборе числового типа вызова")
+	}
+	if _, err := json.Marshal(OperationStatus(5)); err == nil {
+		t.Fatal("Ожидалась ошибка при сериализации недопустимого статуса операции")
+	}
+	if Schedule(7).Valid() || !GROUP.Valid() {
+		t.Fatal("Неверная проверка допустимости значений")
+	}
+	if s := OUTBOUND.String(); s != "OUTBOUND" {
+		t.Fatalf("Неверное имя типа вызова. Ожидалось OUTBOUND получено %s", s)
+	}
+	if tt, err := ParseTargetType("NUMBER"); err != nil || tt != NUMBER {
+		t.Fatalf("Неверно разобран тип объекта подписки: %v %v", tt, err)
+	}
+	var zero Direction
+	if zero.Valid() || OperationStatus(0).Valid() {
+		t.Fatal("Нулевое значение перечисления не должно быть допустимым")
+	}
+	var rec struct {
+		Direction Direction       `json:"direction"`
+		Status    OperationStatus `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(`{}`), &rec); err != nil || rec.Direction.Valid() || rec.Status == SUCCESS {
+		t.Fatalf("Отсутствующие поля не должны разбираться как INBOUND и SUCCESS: %+v %v", rec, err)
+	}
+	if err := json.Unmarshal([]byte(`{"status": ""}`), &rec); err == nil {
+		t.Fatal("Ожидалась ошибка при разборе пустого статуса операции")
+	}
+	if b, err := json.Marshal(rec); err != nil || string(b) != `{"direction":null,"status":null}` {
+		t.Fatalf("Незаданные значения должны сериализоваться в null: %s %v", b, err)
+	}
+	if Schedule(0).Effective() != ROUND_THE_CLOCK || WORKING_TIME.Effective() != WORKING_TIME {
+		t.Fatal("Неверное действующее расписание")
+	}
+}
+
+// TestStatusEnums Тест на статусы услуг, агента, выборочного приема звонков и тип подписки
+func TestStatusEnums(t *testing.T) {
+	var st struct {
+		Bwl       BwlStatus        `json:"bwl"`
+		Redirect  ServiceStatus    `json:"redirect"`
+		Agent     AgentStatus      `json:"agent"`
+		Subscribe SubscriptionType `json:"subscriptionType"`
+	}
+	if err := json.Unmarshal([]byte(`{}`), &st); err != nil || st.Bwl == BLACK_LIST_ON || st.Bwl.Valid() || st.Redirect.Valid() || st.Agent.Valid() || st.Subscribe.Valid() {
+		t.Fatalf("Отсутствующие статусы не должны быть допустимыми значениями: %+v %v", st, err)
+	}
+	doc := `{"bwl":"OFF","redirect":"ON","agent":"BREAK","subscriptionType":"ADVANCED_CALL"}`
+	if err := json.Unmarshal([]byte(doc), &st); err != nil || st.Bwl != BWL_OFF || st.Redirect != ON || st.Agent != BREAK || st.Subscribe != ADVANCED_CALL {
+		t.Fatalf("Неверно разобраны статусы: %+v %v", st, err)
+	}
+	if b, err := json.Marshal(st); err != nil || string(b) != doc {
+		t.Fatalf("Неверно сериализованы статусы: %s %v", b, err)
+	}
+	if err := json.Unmarshal([]byte(`{"bwl":0}`), &st); err == nil {
+		t.Fatal("Ожидалась ошибка при разборе числового статуса")
+	}
+	if _, err := ParseAgentStatus("AWAY"); err == nil {
+		t.Fatal("Ожидалась ошибка при разборе неизвестного статуса агента")
+	}
+}
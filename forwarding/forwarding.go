@@ -0,0 +1,199 @@
+// Package forwarding содержит операции с простой и выборочной переадресацией вызовов облачной АТС Билайн
+package forwarding
+
+import (
+	"fmt"
+	"net/url"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// CfsStatusResponse
+type CfsStatusResponse struct {
+	IsCfsServiceEnabled bool
+	RuleList            []CfsRule
+}
+
+// CfsRule
+type CfsRule struct {
+	Id             int                 `json:""`
+	Name           string              `json:""`
+	ForwardToPhone string              `json:""`
+	Schedule       beelineapi.Schedule `json:""`
+	PhoneList      []string            `json:""`
+}
+
+// CfsRuleUpdate Запрос для добавления правила
+type CfsRuleUpdate struct {
+	Name           string
+	ForwardToPhone string
+	Schedule       beelineapi.Schedule
+	PhoneList      []string
+}
+
+// BasicRedirect Номера для переадресации
+type BasicRedirect struct {
+	ForwardAllCallsPhone    string `json:"forwardAllCallsPhone"`
+	ForwardBusyPhone        string `json:"forwardBusyPhone"`
+	ForwardUnavailablePhone string `json:"forwardUnavailablePhone"`
+	ForwardNotAnswerPhone   string `json:"forwardNotAnswerPhone"`   //Номер, на который будет выполнена переадресация если номер не отвечает
+	ForwardNotAnswerTimeout int    `json:"forwardNotAnswerTimeout"` //Колличество гудков, которые необходимо подождать для ответа номера
+}
+
+// BasicRedirectResponse Возвращаемое значение:
+type BasicRedirectResponse struct {
+	Status  beelineapi.ServiceStatus `json:"status"`  // Статус переадресации = [ON (Переадресация включена), OFF (Переадресация выключена)]
+	Forward BasicRedirect            `json:"forward"` // Номера для переадресации
+}
+
+// Service Операции с переадресацией вызовов
+type Service struct {
+	c *beelineapi.APIClient
+}
+
+// New Возвращает операции с переадресацией вызовов для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{c: c}
+}
+
+//  ------------------------------------- Простая переадресация вызовов -------------------------------------
+
+// GetBasicRedirectStatus Возвращает статус базовой переадресации и номера для переадресации
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) GetBasicRedirectStatus(id string) (BasicRedirectResponse, error) {
+	br := BasicRedirectResponse{}
+	if err := s.c.RequestJSON("GET", abonentPath(id, "bfs"), nil, &br); err != nil {
+		return br, beelineapi.Wrap("Ошибка при получении статуса базовой переадресации. ", err)
+	}
+	return br, nil
+}
+
+// TurnOnBasicRedirect Включает базовую переадресацию
+// id - Идентификатор, мобильный или добавочный номер абонента
+// br - Номера для переадресации
+func (s *Service) TurnOnBasicRedirect(id string, br BasicRedirect) error {
+	br, err := br.Normalize()
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при включении базовой переадресации. ", err)
+	}
+	if _, err := s.c.Request("PUT", abonentPath(id, "bfs"), br); err != nil {
+		return beelineapi.Wrap("Ошибка при включении базовой переадресации. ", err)
+	}
+	return nil
+}
+
+// TurnOffBasicRedirect Отключает базовую переадресацию
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOffBasicRedirect(id string) error {
+	if _, err := s.c.Request("DELETE", abonentPath(id, "bfs"), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при отключении базовой переадресации. ", err)
+	}
+	return nil
+}
+
+//  ------------------------------------- Выборочная переадресация вызовов -------------------------------------
+
+// GetSelectiveCallRules Возвращает список правил выборочной переадресации
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) GetSelectiveCallRules(id string) (CfsStatusResponse, error) {
+	cfs := CfsStatusResponse{}
+	if err := s.c.RequestJSON("GET", abonentPath(id, "cfs"), nil, &cfs); err != nil {
+		return cfs, beelineapi.Wrap("Ошибка при получении правил выборочной переадресации. ", err)
+	}
+	return cfs, nil
+}
+
+// AddSelectiveCallRule Добавляет правило для выборочной переадресации и возвращает его идентификатор
+// id - Идентификатор, мобильный или добавочный номер абонента
+// rule -Запрос для добавления правила
+func (s *Service) AddSelectiveCallRule(id string, rule CfsRuleUpdate) (int, error) {
+	var ruleID int
+	rule, err := rule.Normalize()
+	if err != nil {
+		return 0, beelineapi.Wrap("Ошибка при добавлении правила выборочной переадресации. ", err)
+	}
+	if err := s.c.RequestJSON("POST", abonentPath(id, "cfs/rule"), rule, &ruleID); err != nil {
+		return 0, beelineapi.Wrap("Ошибка при добавлении правила выборочной переадресации. ", err)
+	}
+	return ruleID, nil
+}
+
+// TurnOnSelectiveRedirect Включает выборочную переадресацию
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOnSelectiveRedirect(id string) error {
+	if _, err := s.c.Request("PUT", abonentPath(id, "cfs"), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при включении выборочной переадресации. ", err)
+	}
+	return nil
+}
+
+// UpdateSelectiveCallRule Обновляет правило
+// id - Идентификатор, мобильный или добавочный номер абонента
+// ruleID -Идентификатор правила
+// rule - Запрос для обновления правила
+func (s *Service) UpdateSelectiveCallRule(id string, ruleID int, rule CfsRuleUpdate) error {
+	rule, err := rule.Normalize()
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при обновлении правила выборочной переадресации. ", err)
+	}
+	if _, err := s.c.Request("PUT", abonentPath(id, fmt.Sprintf("cfs/rule/%d", ruleID)), rule); err != nil {
+		return beelineapi.Wrap("Ошибка при обновлении правила выборочной переадресации. ", err)
+	}
+	return nil
+}
+
+// TurnOffSelectiveRedirect Отключает выборочную переадресацию
+// id - Идентификатор, мобильный или добавочный номер абонента
+func (s *Service) TurnOffSelectiveRedirect(id string) error {
+	if _, err := s.c.Request("DELETE", abonentPath(id, "cfs"), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при отключении выборочной переадресации. ", err)
+	}
+	return nil
+}
+
+// DeleteSelectiveCallRule Удаляет правило
+// id - Идентификатор, мобильный или добавочный номер абонента
+// ruleID - Идентификатор правила
+func (s *Service) DeleteSelectiveCallRule(id string, ruleID int) error {
+	if _, err := s.c.Request("DELETE", abonentPath(id, fmt.Sprintf("cfs/rule/%d", ruleID)), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при удалении правила выборочной переадресации. ", err)
+	}
+	return nil
+}
+
+// Normalize Возвращает номера для переадресации, приведенные к формату API. Пустые номера не изменяются.
+func (br BasicRedirect) Normalize() (BasicRedirect, error) {
+	for _, p := range []*string{&br.ForwardAllCallsPhone, &br.ForwardBusyPhone, &br.ForwardUnavailablePhone, &br.ForwardNotAnswerPhone} {
+		if *p == "" {
+			continue
+		}
+		n, err := beelineapi.NormalizePhone(*p)
+		if err != nil {
+			return br, err
+		}
+		*p = n
+	}
+	return br, nil
+}
+
+// Normalize Возвращает правило с номерами, приведенными к формату API.
+// Незаданное расписание заменяется на ROUND_THE_CLOCK.
+func (r CfsRuleUpdate) Normalize() (CfsRuleUpdate, error) {
+	to, err := beelineapi.NormalizePhone(r.ForwardToPhone)
+	if err != nil {
+		return r, err
+	}
+	list, err := beelineapi.NormalizePhones(r.PhoneList)
+	if err != nil {
+		return r, err
+	}
+	r.ForwardToPhone = to
+	r.PhoneList = list
+	r.Schedule = r.Schedule.Effective()
+	return r, nil
+}
+
+// abonentPath Возвращает путь к настройке абонента
+func abonentPath(id string, setting string) string {
+	return "abonents/" + url.PathEscape(id) + "/" + setting
+}
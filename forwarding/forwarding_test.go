@@ -0,0 +1,100 @@
+package forwarding_test
+
+import (
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+// TestBasicRedirect Тест на включение и отключение базовой переадресации
+func TestBasicRedirect(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := forwarding.New(client)
+
+	br := forwarding.BasicRedirect{ForwardNotAnswerPhone: "9000000002", ForwardNotAnswerTimeout: 3}
+	if err := svc.TurnOnBasicRedirect("u1", br); err != nil {
+		t.Fatalf("Не удалось включить базовую переадресацию: %s", err)
+	}
+	got, err := svc.GetBasicRedirectStatus("u1")
+	if err != nil || got.Status != beelineapi.ON || got.Forward != br {
+		t.Fatalf("Неверный статус базовой переадресации: %v %+v", err, got)
+	}
+	if err := svc.TurnOffBasicRedirect("u1"); err != nil {
+		t.Fatalf("Не удалось отключить базовую переадресацию: %s", err)
+	}
+	if got, _ := svc.GetBasicRedirectStatus("u1"); got.Status != beelineapi.OFF {
+		t.Fatalf("Базовая переадресация не отключена: %+v", got)
+	}
+}
+
+// TestSelectiveCallRules Тест на операции с правилами выборочной переадресации
+func TestSelectiveCallRules(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := forwarding.New(client)
+
+	id, err := svc.AddSelectiveCallRule("u1", forwarding.CfsRuleUpdate{Name: "night", ForwardToPhone: "9000000002", Schedule: beelineapi.NON_WORKING_TIME_AND_HOLIDAYS})
+	if err != nil {
+		t.Fatalf("Не удалось добавить правило: %s", err)
+	}
+	if err := svc.TurnOnSelectiveRedirect("u1"); err != nil {
+		t.Fatalf("Не удалось включить выборочную переадресацию: %s", err)
+	}
+	if err := svc.UpdateSelectiveCallRule("u1", id, forwarding.CfsRuleUpdate{Name: "night", ForwardToPhone: "9000000003"}); err != nil {
+		t.Fatalf("Не удалось обновить правило: %s", err)
+	}
+	cfs, err := svc.GetSelectiveCallRules("u1")
+	if err != nil || !cfs.IsCfsServiceEnabled || len(cfs.RuleList) != 1 || cfs.RuleList[0].ForwardToPhone != "9000000003" {
+		t.Fatalf("Неверные правила выборочной переадресации: %v %+v", err, cfs)
+	}
+	if err := svc.DeleteSelectiveCallRule("u1", id); err != nil {
+		t.Fatalf("Не удалось удалить правило: %s", err)
+	}
+	if cfs, _ := svc.GetSelectiveCallRules("u1"); len(cfs.RuleList) != 0 {
+		t.Fatalf("Правило не удалено: %+v", cfs)
+	}
+}
+
+// TestEndpoints Тест на успешный ответ и ошибку сервера для каждой операции с переадресацией
+func TestEndpoints(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := forwarding.New(client)
+	rule := forwarding.CfsRuleUpdate{Name: "night", ForwardToPhone: "9000000002", Schedule: beelineapi.NON_WORKING_TIME_AND_HOLIDAYS}
+	ruleId := 1 // Первый идентификатор, выдаваемый имитатором
+
+	if err := s.CheckEndpoints([]beelinetest.Endpoint{
+		{Name: "GetBasicRedirectStatus", Method: "GET", Path: "/abonents/u1/bfs", Call: func() error { _, err := svc.GetBasicRedirectStatus("u1"); return err }},
+		{Name: "TurnOnBasicRedirect", Method: "PUT", Path: "/abonents/u1/bfs", Call: func() error {
+			return svc.TurnOnBasicRedirect("u1", forwarding.BasicRedirect{ForwardAllCallsPhone: "9000000002"})
+		}},
+		{Name: "TurnOffBasicRedirect", Method: "DELETE", Path: "/abonents/u1/bfs", Call: func() error { return svc.TurnOffBasicRedirect("u1") }},
+		{Name: "AddSelectiveCallRule", Method: "POST", Path: "/abonents/u1/cfs/rule", Call: func() error { _, err := svc.AddSelectiveCallRule("u1", rule); return err }},
+		{Name: "GetSelectiveCallRules", Method: "GET", Path: "/abonents/u1/cfs", Call: func() error { _, err := svc.GetSelectiveCallRules("u1"); return err }},
+		{Name: "UpdateSelectiveCallRule", Method: "PUT", Path: "/abonents/u1/cfs/rule/1", Call: func() error { return svc.UpdateSelectiveCallRule("u1", ruleId, rule) }},
+		{Name: "TurnOnSelectiveRedirect", Method: "PUT", Path: "/abonents/u1/cfs", Call: func() error { return svc.TurnOnSelectiveRedirect("u1") }},
+		{Name: "TurnOffSelectiveRedirect", Method: "DELETE", Path: "/abonents/u1/cfs", Call: func() error { return svc.TurnOffSelectiveRedirect("u1") }},
+		{Name: "DeleteSelectiveCallRule", Method: "DELETE", Path: "/abonents/u1/cfs/rule/1", Call: func() error { return svc.DeleteSelectiveCallRule("u1", ruleId) }},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package icr
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/internal/csvutil"
+)
+
+// WriteCSV Записывает правила переадресации в формате CSV со столбцами inboundNumber, extension
+func WriteCSV(w io.Writer, rules []IcrRouteRule) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"inboundNumber", "extension"})
+	for _, r := range rules {
+		cw.Write([]string{r.InboundNumber, r.Extension})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV Читает правила переадресации из CSV со столбцами inboundNumber, extension
+func ReadCSV(r io.Reader) ([]IcrRouteRule, error) {
+	t, err := csvutil.Read(r, "inboundNumber", "extension")
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при чтении правил индивидуальной переадресации. ", err)
+	}
+	rules := []IcrRouteRule{}
+	seen := map[IcrRouteRule]bool{}
+	for i, row := range t.Rows {
+		rule := IcrRouteRule{InboundNumber: t.Get(row, "inboundNumber"), Extension: t.Get(row, "extension")}
+		if rule.InboundNumber == "" || rule.Extension == "" {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: не указан входящий или добавочный номер", i+2)}
+		}
+		if rule.InboundNumber, err = beelineapi.NormalizePhone(rule.InboundNumber); err != nil {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Строка %d: %s", i+2, err), Err: err}
+		}
+		if seen[rule] {
+			continue
+		}
+		seen[rule] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Diff Сравнивает текущие правила current с желаемыми desired
+// и возвращает правила, которые нужно добавить и удалить
+func Diff(current []IcrRouteRule, desired []IcrRouteRule) (add []IcrRouteRule, remove []IcrRouteRule) {
+	have := map[IcrRouteRule]bool{}
+	for _, r := range current {
+		have[r] = true
+	}
+	want := map[IcrRouteRule]bool{}
+	for _, r := range desired {
+		want[r] = true
+		if !have[r] {
+			add = append(add, r)
+		}
+	}
+	for _, r := range current {
+		if !want[r] {
+			remove = append(remove, r)
+		}
+	}
+	return add, remove
+}
+
+// Failed Возвращает ошибку с описанием правил, которые сервер не смог применить, или nil
+func Failed(res []IcrRouteResult) error {
+	msg := ""
+	for _, r := range res {
+		if r.Status == beelineapi.FAULT {
+			msg += fmt.Sprintf("\n%s -> %s: %s %s", r.Rule.InboundNumber, r.Rule.Extension, r.Error.ErrorCode, r.Error.Description)
+		}
+	}
+	if msg == "" {
+		return nil
+	}
+	return beelineapi.WrapError{Msg: "Не удалось применить правила индивидуальной переадресации:" + msg}
+}
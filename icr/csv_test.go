@@ -0,0 +1,35 @@
+package icr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/taigasys/beeline-portal-api/icr"
+)
+
+// TestCSV Тест на чтение, запись и сравнение правил индивидуальной переадресации в CSV
+func TestCSV(t *testing.T) {
+	rules, err := icr.ReadCSV(strings.NewReader("extension,inboundNumber\n101,4950000001\n102,4950000002\n101,4950000001\n"))
+	if err != nil {
+		t.Fatalf("Не удалось прочитать CSV: %s", err)
+	}
+	if len(rules) != 2 || rules[0] != (icr.IcrRouteRule{InboundNumber: "4950000001", Extension: "101"}) {
+		t.Fatalf("Неверно прочитаны правила: %+v", rules)
+	}
+	var out bytes.Buffer
+	if err := icr.WriteCSV(&out, rules); err != nil {
+		t.Fatalf("Не удалось записать CSV: %s", err)
+	}
+	if want := "inboundNumber,extension\n4950000001,101\n4950000002,102\n"; out.String() != want {
+		t.Fatalf("Неверный CSV:\n%s", out.String())
+	}
+	if _, err := icr.ReadCSV(strings.NewReader("inboundNumber,extension\n4950000001,\n")); err == nil {
+		t.Fatal("Ожидалась ошибка для правила без добавочного номера")
+	}
+	current := []icr.IcrRouteRule{{InboundNumber: "4950000001", Extension: "101"}, {InboundNumber: "4950000003", Extension: "103"}}
+	add, remove := icr.Diff(current, rules)
+	if len(add) != 1 || add[0].Extension != "102" || len(remove) != 1 || remove[0].Extension != "103" {
+		t.Fatalf("Неверное сравнение правил: +%v -%v", add, remove)
+	}
+}
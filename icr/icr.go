@@ -0,0 +1,165 @@
+// Package icr содержит операции со входящими номерами и индивидуальной переадресацией облачной АТС Билайн
+package icr
+
+import (
+	"net/url"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// NumberInfo Входящий номер
+type NumberInfo struct {
+	NumberId string `json:"numberId"` // Идентификатор входящего номера
+	Phone    string `json:"phone"`    //Номер телефона
+}
+
+// IcrNumbersResult Результат включения или отключения индивидуальной переадресации для номера
+type IcrNumbersResult struct {
+	PhoneNumber string                     `json:"phoneNumber"` //Номер телефона
+	Status      beelineapi.OperationStatus `json:"status"`      //Результат выполнения операции
+	Error       IcrOperationError          `json:"error"`       //Описание ошибки
+}
+
+// IcrOperationError Описание ошибки операции индивидуальной переадресации
+type IcrOperationError struct {
+	ErrorCode   string `json:"errorCode"`   //Код ошибки
+	Description string `json:"description"` //Сообщение об ошибке
+}
+
+// IcrRouteRule структура хранения правила переадресации
+type IcrRouteRule struct {
+	InboundNumber string `json:"inboundNumber"` //Входящий номер клиента
+	Extension     string `json:"extension"`     //Внутренний номер
+}
+
+// IcrRouteResult структура хранения статуса удаления правил переадресации
+type IcrRouteResult struct {
+	Rule   IcrRouteRule               `json:"rule"`   //Правило переадресации
+	Status beelineapi.OperationStatus `json:"status"` //Результат выполнения операции
+	Error  IcrOperationError          `json:"error"`  //Описание ошибки
+}
+
+// Service Операции со входящими номерами и индивидуальной переадресацией
+type Service struct {
+	c *beelineapi.APIClient
+}
+
+// New Возвращает операции со входящими номерами и индивидуальной переадресацией для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{c: c}
+}
+
+//  ------------------------------------- Операции со входящими номерами  -------------------------------------
+
+// GetAllIncNumbers Возвращает список всех входящих номеров
+func (s *Service) GetAllIncNumbers() ([]NumberInfo, error) {
+	nums := []NumberInfo{}
+	if err := s.c.RequestJSON("GET", "numbers", nil, &nums); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при получении списка входящих номеров. ", err)
+	}
+	return nums, nil
+}
+
+// FindIncNumberById Ищет входящий номер по идентификатору, номеру или добавочному номеру
+// id - Идентификатор, номер или добавочный номер
+func (s *Service) FindIncNumberById(id string) (NumberInfo, error) {
+	n := NumberInfo{}
+	if err := s.c.RequestJSON("GET", "numbers/"+url.PathEscape(id), nil, &n); err != nil {
+		return n, beelineapi.Wrap("Ошибка при поиске входящего номера. ", err)
+	}
+	return n, nil
+}
+
+//  ------------------------------------- Индивидуальная переадресация  -------------------------------------
+
+// GetIncNumWithRedirect Возвращает список входящих номеров, для которых включена переадресация
+func (s *Service) GetIncNumWithRedirect() ([]NumberInfo, error) {
+	nums := []NumberInfo{}
+	if err := s.c.RequestJSON("GET", "icr/numbers", nil, &nums); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при получении списка номеров с индивидуальной переадресацией. ", err)
+	}
+	return nums, nil
+}
+
+// TurnOnCustomIncNumRedirect Включает индивидуальную переадресацию для входящих номеров
+//
+//	numberList - Список входящих номеров, для которых должна быть включена переадресация
+func (s *Service) TurnOnCustomIncNumRedirect(numberList []string) ([]IcrNumbersResult, error) {
+	res := []IcrNumbersResult{}
+	numberList, err := beelineapi.NormalizePhones(numberList)
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при включении индивидуальной переадресации. ", err)
+	}
+	if err := s.c.RequestJSON("PUT", "icr/numbers", numberList, &res); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при включении индивидуальной переадресации. ", err)
+	}
+	return res, nil
+}
+
+// TurnOffCustomIncNumRedirect Отключает индивидуальную переадресацию для входящих номеров
+//
+//	numberList - Список входящих номеров, для которых должна быть отключена переадресация
+func (s *Service) TurnOffCustomIncNumRedirect(numberList []string) ([]IcrNumbersResult, error) {
+	res := []IcrNumbersResult{}
+	numberList, err := beelineapi.NormalizePhones(numberList)
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при отключении индивидуальной переадресации. ", err)
+	}
+	if err := s.c.RequestJSON("DELETE", "icr/numbers", numberList, &res); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при отключении индивидуальной переадресации. ", err)
+	}
+	return res, nil
+}
+
+// GetRedirectRulesList Возвращает список правил переадресации
+func (s *Service) GetRedirectRulesList() ([]IcrRouteRule, error) {
+	rules := []IcrRouteRule{}
+	if err := s.c.RequestJSON("GET", "icr/route", nil, &rules); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при получении правил индивидуальной переадресации. ", err)
+	}
+	return rules, nil
+}
+
+// DeleteRedirectRulesList Удаляет список правил переадресации
+// rules - Список правил переадресации
+func (s *Service) DeleteRedirectRulesList(rules []IcrRouteRule) ([]IcrRouteResult, error) {
+	return s.routeRequest("DELETE", rules, "Ошибка при удалении правил индивидуальной переадресации. ")
+}
+
+// ReplaceRedirectRulesList Замещает правила переадресации
+// rules - Список правил переадресации
+func (s *Service) ReplaceRedirectRulesList(rules []IcrRouteRule) ([]IcrRouteResult, error) {
+	return s.routeRequest("PUT", rules, "Ошибка при замещении правил индивидуальной переадресации. ")
+}
+
+// UnionRedirectRulesList Объединяет существующие правила переадресации с переданным списком правил.
+// rules - Список правил переадресации
+func (s *Service) UnionRedirectRulesList(rules []IcrRouteRule) ([]IcrRouteResult, error) {
+	return s.routeRequest("POST", rules, "Ошибка при объединении правил индивидуальной переадресации. ")
+}
+
+// routeRequest Отправляет список правил переадресации и возвращает результат операции по каждому правилу
+func (s *Service) routeRequest(method string, rules []IcrRouteRule, msg string) ([]IcrRouteResult, error) {
+	res := []IcrRouteResult{}
+	rules, err := NormalizeRules(rules)
+	if err != nil {
+		return nil, beelineapi.Wrap(msg, err)
+	}
+	if err := s.c.RequestJSON(method, "icr/route", rules, &res); err != nil {
+		return nil, beelineapi.Wrap(msg, err)
+	}
+	return res, nil
+}
+
+// NormalizeRules Возвращает копию правил с входящими номерами, приведенными к формату API
+func NormalizeRules(rules []IcrRouteRule) ([]IcrRouteRule, error) {
+	res := make([]IcrRouteRule, len(rules))
+	for i, r := range rules {
+		n, err := beelineapi.NormalizePhone(r.InboundNumber)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = IcrRouteRule{InboundNumber: n, Extension: r.Extension}
+	}
+	return res, nil
+}
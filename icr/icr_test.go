@@ -0,0 +1,70 @@
+package icr_test
+
+import (
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/icr"
+)
+
+// TestRedirectRules Тест на операции с правилами индивидуальной переадресации
+func TestRedirectRules(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddNumber(icr.NumberInfo{NumberId: "n1", Phone: "4950000001"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := icr.New(client)
+
+	res, err := svc.TurnOnCustomIncNumRedirect([]string{"4950000001", "4950000099"})
+	if err != nil || len(res) != 2 || res[0].Status != beelineapi.SUCCESS || res[1].Status != beelineapi.FAULT {
+		t.Fatalf("Неверный результат включения индивидуальной переадресации: %v %+v", err, res)
+	}
+	if nums, err := svc.GetIncNumWithRedirect(); err != nil || len(nums) != 1 {
+		t.Fatalf("Неверный список номеров с индивидуальной переадресацией: %v %+v", err, nums)
+	}
+	rule := icr.IcrRouteRule{InboundNumber: "4950000001", Extension: "101"}
+	if res, err := svc.ReplaceRedirectRulesList([]icr.IcrRouteRule{rule}); err != nil || res[0].Status != beelineapi.SUCCESS {
+		t.Fatalf("Не удалось заместить правила: %v %+v", err, res)
+	}
+	if rules, err := svc.GetRedirectRulesList(); err != nil || len(rules) != 1 || rules[0] != rule {
+		t.Fatalf("Неверный список правил: %v %+v", err, rules)
+	}
+	if res, err := svc.DeleteRedirectRulesList([]icr.IcrRouteRule{rule}); err != nil || res[0].Status != beelineapi.SUCCESS {
+		t.Fatalf("Не удалось удалить правила: %v %+v", err, res)
+	}
+	if n, err := svc.FindIncNumberById("n1"); err != nil || n.Phone != "4950000001" {
+		t.Fatalf("Не удалось найти входящий номер: %v %+v", err, n)
+	}
+}
+
+// TestEndpoints Тест на успешный ответ и ошибку сервера для каждой операции с индивидуальной переадресацией
+func TestEndpoints(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddNumber(icr.NumberInfo{NumberId: "n1", Phone: "4950000001"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := icr.New(client)
+	numbers := []string{"4950000001"}
+	rules := []icr.IcrRouteRule{{InboundNumber: "4950000001", Extension: "101"}}
+
+	if err := s.CheckEndpoints([]beelinetest.Endpoint{
+		{Name: "GetAllIncNumbers", Method: "GET", Path: "/numbers", Call: func() error { _, err := svc.GetAllIncNumbers(); return err }},
+		{Name: "FindIncNumberById", Method: "GET", Path: "/numbers/n1", Call: func() error { _, err := svc.FindIncNumberById("n1"); return err }},
+		{Name: "TurnOnCustomIncNumRedirect", Method: "PUT", Path: "/icr/numbers", Call: func() error { _, err := svc.TurnOnCustomIncNumRedirect(numbers); return err }},
+		{Name: "GetIncNumWithRedirect", Method: "GET", Path: "/icr/numbers", Call: func() error { _, err := svc.GetIncNumWithRedirect(); return err }},
+		{Name: "TurnOffCustomIncNumRedirect", Method: "DELETE", Path: "/icr/numbers", Call: func() error { _, err := svc.TurnOffCustomIncNumRedirect(numbers); return err }},
+		{Name: "ReplaceRedirectRulesList", Method: "PUT", Path: "/icr/route", Call: func() error { _, err := svc.ReplaceRedirectRulesList(rules); return err }},
+		{Name: "UnionRedirectRulesList", Method: "POST", Path: "/icr/route", Call: func() error { _, err := svc.UnionRedirectRulesList(rules); return err }},
+		{Name: "GetRedirectRulesList", Method: "GET", Path: "/icr/route", Call: func() error { _, err := svc.GetRedirectRulesList(); return err }},
+		{Name: "DeleteRedirectRulesList", Method: "DELETE", Path: "/icr/route", Call: func() error { _, err := svc.DeleteRedirectRulesList(rules); return err }},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package csvutil содержит общие функции чтения таблиц CSV, выгруженных из электронных таблиц
+package csvutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Table Таблица CSV со строкой заголовка
+type Table struct {
+	columns map[string]int // Номер столбца по имени в нижнем регистре
+	Rows    [][]string     // Строки без заголовка
+}
+
+// Read Читает таблицу CSV с заголовком. Разделитель "," или ";" определяется по строке заголовка,
+// так как русская локаль электронных таблиц сохраняет CSV с разделителем ";".
+// required - обязательные столбцы
+func Read(r io.Reader, required ...string) (Table, error) {
+	t := Table{columns: map[string]int{}}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return t, beelineapi.Wrap("Ошибка при чтении CSV. ", err)
+	}
+	// Пропускаем BOM, который добавляют электронные таблицы
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	cr := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return t, beelineapi.Wrap("Ошибка при чтении заголовка CSV. ", err)
+	}
+	for i, h := range header {
+		t.columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, name := range required {
+		if _, ok := t.columns[strings.ToLower(name)]; !ok {
+			return t, beelineapi.WrapError{Msg: fmt.Sprintf("В заголовке CSV нет столбца %q", name)}
+		}
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return t, beelineapi.Wrap("Ошибка при чтении CSV. ", err)
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		t.Rows = append(t.Rows, row)
+	}
+}
+
+// Get Возвращает значение столбца name в строке row или пустую строку, если столбца нет
+func (t Table) Get(row []string, name string) string {
+	i, ok := t.columns[strings.ToLower(name)]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// SplitList Разбивает список номеров, разделенных пробелами, запятыми или точками с запятой
+func SplitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
@@ -0,0 +1,30 @@
+// Package yamljson разбирает документы YAML и JSON в структуры с тегами json
+package yamljson
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decode Разбирает документ data в формате YAML или JSON в v.
+// YAML является надмножеством JSON, поэтому документ разбирается как YAML и затем декодируется
+// по тегам json, общим для всех типов API. Даты YAML без кавычек после преобразования
+// передаются строками в формате RFC 3339.
+// strict - считать ошибкой поля, которых нет в v
+func Decode(data []byte, v interface{}, strict bool) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
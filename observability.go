@@ -0,0 +1,277 @@
+package beelineapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics Приемник метрик запросов к API. Вызывается после каждой попытки запроса.
+type Metrics interface {
+	// ObserveRequest Учитывает запрос
+	// endpoint - шаблон пути без идентификаторов, например abonents/{id}/agent
+	// method - тип HTTP запроса
+	// status - HTTP код ответа или 0 при сетевой ошибке
+	// d - длительность запроса
+	ObserveRequest(endpoint string, method string, status int, d time.Duration)
+}
+
+// Tracer Источник трассировочных спанов для запросов к API, например адаптер к OpenTelemetry:
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, beelineapi.Span) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		for k, v := range attrs {
+//			span.SetAttributes(attribute.String(k, v))
+//		}
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// Start Начинает спан name с атрибутами attrs
+	Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span)
+}
+
+// Span Трассировочный спан запроса
+type Span interface {
+	// SetAttribute Устанавливает атрибут спана
+	SetAttribute(key string, value string)
+	// SetError Отмечает спан как завершившийся ошибкой
+	SetError(err error)
+	// End Завершает спан
+	End()
+}
+
+// WithSlog Включает структурированный журнал запросов. Ключ безопасности в сообщениях и атрибутах журнала
+// заменяется на "***". Успешные запросы записываются с уровнем Debug, повторы с уровнем Warn,
+// ошибки с уровнем Error.
+func WithSlog(l *slog.Logger) Option {
+	return func(c *APIClient) error {
+		if l == nil {
+			return WrapError{Msg: "Не указан журнал"}
+		}
+		c.slog = slog.New(&redactHandler{h: l.Handler(), c: c})
+		return nil
+	}
+}
+
+// WithMetrics Включает учет метрик запросов
+func WithMetrics(m Metrics) Option {
+	return func(c *APIClient) error {
+		c.metrics = m
+		return nil
+	}
+}
+
+// WithTracer Включает трассировку запросов. На каждый вызов Request создается спан beeline.request.
+func WithTracer(t Tracer) Option {
+	return func(c *APIClient) error {
+		c.tracer = t
+		return nil
+	}
+}
+
+// redactHandler Обработчик журнала, скрывающий ключ безопасности клиента
+type redactHandler struct {
+	h slog.Handler
+	c *APIClient
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.h.Enabled(ctx, l)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	n := slog.NewRecord(r.Time, r.Level, h.redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		n.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.h.Handle(ctx, n)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	res := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		res[i] = h.attr(a)
+	}
+	return &redactHandler{h: h.h.WithAttrs(res), c: h.c}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{h: h.h.WithGroup(name), c: h.c}
+}
+
+// attr Скрывает ключ безопасности в значении атрибута
+func (h *redactHandler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redact(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		res := make([]any, len(attrs))
+		for i, g := range attrs {
+			res[i] = h.attr(g)
+		}
+		return slog.Group(a.Key, res...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// redact Заменяет ключ безопасности в строке
+func (h *redactHandler) redact(s string) string {
+	for _, token := range h.c.secrets() {
+		s = strings.ReplaceAll(s, token, "***")
+	}
+	return s
+}
+
+// endpoint Возвращает шаблон пути запроса без параметров и идентификаторов для меток метрик
+func endpoint(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(segs); i++ {
+		switch segs[i-1] {
+		case "abonents", "records", "numbers", "subscription", "rule":
+			if segs[i] != "download" {
+				segs[i] = "{id}"
+			}
+		case "{id}":
+			// Запись разговора из события адресуется двумя идентификаторами: records/{callId}/{userId}
+			if i >= 2 && segs[i-2] == "records" && (i < 3 || segs[i-3] != "v2") && segs[i] != "download" {
+				segs[i] = "{id}"
+			}
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// observe Передает результат попытки запроса в журнал и метрики
+func (c *APIClient) observe(ctx context.Context, method string, path string, status int, d time.Duration, err error) {
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(endpoint(path), method, status, d)
+	}
+	if c.slog == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("path", path),
+		slog.Int("status", status),
+		slog.Duration("duration", d),
+	}
+	if err != nil {
+		c.slog.LogAttrs(ctx, slog.LevelError, "Ошибка запроса к API Beeline", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	c.slog.LogAttrs(ctx, slog.LevelDebug, "Запрос к API Beeline", attrs...)
+}
+
+// DefaultBuckets Границы корзин гистограммы длительности запросов по умолчанию в секундах
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// PromMetrics Метрики запросов в формате Prometheus без внешних зависимостей:
+// счетчик beeline_api_requests_total и гистограмма beeline_api_request_duration_seconds.
+// PromMetrics можно зарегистрировать как http.Handler для сбора метрик.
+type PromMetrics struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	counters  map[[3]string]uint64
+	histogram map[[2]string]*histogram
+}
+
+// histogram Гистограмма длительности запросов
+type histogram struct {
+	counts []uint64 // Количество наблюдений в каждой корзине, последняя - +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewPromMetrics Возвращает метрики с границами корзин гистограммы buckets в секундах
+// или DefaultBuckets, если границы не указаны
+func NewPromMetrics(buckets ...float64) *PromMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &PromMetrics{buckets: b, counters: map[[3]string]uint64{}, histogram: map[[2]string]*histogram{}}
+}
+
+// ObserveRequest Учитывает запрос
+func (m *PromMetrics) ObserveRequest(endpoint string, method string, status int, d time.Duration) {
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[[3]string{endpoint, method, code}]++
+	key := [2]string{endpoint, method}
+	h := m.histogram[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.histogram[key] = h
+	}
+	s := d.Seconds()
+	i := sort.SearchFloat64s(m.buckets, s)
+	h.counts[i]++
+	h.sum += s
+	h.count++
+}
+
+// WritePrometheus Записывает метрики в текстовом формате Prometheus
+func (m *PromMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("# HELP beeline_api_requests_total Количество запросов к API Beeline.\n")
+	b.WriteString("# TYPE beeline_api_requests_total counter\n")
+	ckeys := make([][3]string, 0, len(m.counters))
+	for k := range m.counters {
+		ckeys = append(ckeys, k)
+	}
+	sort.Slice(ckeys, func(i, j int) bool { return strings.Join(ckeys[i][:], " ") < strings.Join(ckeys[j][:], " ") })
+	for _, k := range ckeys {
+		fmt.Fprintf(&b, "beeline_api_requests_total{endpoint=%q,method=%q,status=%q} %d\n", k[0], k[1], k[2], m.counters[k])
+	}
+	b.WriteString("# HELP beeline_api_request_duration_seconds Длительность запросов к API Beeline.\n")
+	b.WriteString("# TYPE beeline_api_request_duration_seconds histogram\n")
+	hkeys := make([][2]string, 0, len(m.histogram))
+	for k := range m.histogram {
+		hkeys = append(hkeys, k)
+	}
+	sort.Slice(hkeys, func(i, j int) bool { return hkeys[i][0]+" "+hkeys[i][1] < hkeys[j][0]+" "+hkeys[j][1] })
+	for _, k := range hkeys {
+		h := m.histogram[k]
+		var cum uint64
+		for i, le := range m.buckets {
+			cum += h.counts[i]
+			fmt.Fprintf(&b, "beeline_api_request_duration_seconds_bucket{endpoint=%q,method=%q,le=%q} %d\n", k[0], k[1], strconv.FormatFloat(le, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(&b, "beeline_api_request_duration_seconds_bucket{endpoint=%q,method=%q,le=\"+Inf\"} %d\n", k[0], k[1], h.count)
+		fmt.Fprintf(&b, "beeline_api_request_duration_seconds_sum{endpoint=%q,method=%q} %g\n", k[0], k[1], h.sum)
+		fmt.Fprintf(&b, "beeline_api_request_duration_seconds_count{endpoint=%q,method=%q} %d\n", k[0], k[1], h.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP Отдает метрики в текстовом формате Prometheus
+func (m *PromMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
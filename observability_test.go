@@ -0,0 +1,104 @@
+package beelineapi
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSpan Спан, сохраняющий атрибуты и ошибку
+type testSpan struct {
+	attrs map[string]string
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value string) { s.attrs[key] = value }
+func (s *testSpan) SetError(err error)                    { s.err = err }
+func (s *testSpan) End()                                  { s.ended = true }
+
+// testTracer Источник спанов для теста
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+	s := &testSpan{attrs: attrs}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+// TestObservability Тест на журнал со скрытием ключа безопасности, метрики и трассировку запросов
+func TestObservability(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/abonents/u1/agent" {
+			w.Write([]byte(`"ONLINE"`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errorCode":"Forbidden","description":"Token secret-token is not allowed"}`))
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	metrics := NewPromMetrics(0.5, 1)
+	tracer := &testTracer{}
+	c, err := NewClient("secret-token", WithBaseURL(srv.URL), WithSlog(logger), WithMetrics(metrics), WithTracer(tracer))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if _, err := c.Request("GET", "abonents/u1/agent", nil); err != nil {
+		t.Fatalf("Ошибка запроса: %s", err)
+	}
+	if _, err := c.Request("DELETE", "v2/records/42", nil); err == nil {
+		t.Fatal("Ожидалась ошибка 403")
+	}
+
+	if strings.Contains(logs.String(), "secret-token") {
+		t.Fatalf("Ключ безопасности попал в журнал:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "Token *** is not allowed") || !strings.Contains(logs.String(), "level=DEBUG") {
+		t.Fatalf("Неверный журнал:\n%s", logs.String())
+	}
+
+	var out bytes.Buffer
+	metrics.WritePrometheus(&out)
+	for _, want := range []string{
+		`beeline_api_requests_total{endpoint="abonents/{id}/agent",method="GET",status="200"} 1`,
+		`beeline_api_requests_total{endpoint="v2/records/{id}",method="DELETE",status="403"} 1`,
+		`beeline_api_request_duration_seconds_bucket{endpoint="abonents/{id}/agent",method="GET",le="+Inf"} 1`,
+		`beeline_api_request_duration_seconds_count{endpoint="v2/records/{id}",method="DELETE"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("В метриках нет строки %s:\n%s", want, out.String())
+		}
+	}
+
+	if len(tracer.spans) != 2 || !tracer.spans[0].ended || tracer.spans[0].err != nil || tracer.spans[1].err == nil ||
+		tracer.spans[1].attrs["http.status_code"] != "403" || tracer.spans[1].attrs["endpoint"] != "v2/records/{id}" {
+		t.Fatalf("Неверные спаны: %+v %+v", tracer.spans[0], tracer.spans[1])
+	}
+}
+
+// TestEndpoint Тест на построение шаблона пути для меток метрик
+func TestEndpoint(t *testing.T) {
+	for path, want := range map[string]string{
+		"abonents":                       "abonents",
+		"abonents/9000000001/bwl/rule/5": "abonents/{id}/bwl/rule/{id}",
+		"abonents/u1/agent?status=BREAK": "abonents/{id}/agent",
+		"records?id=10":                  "records",
+		"records/call1/u1/download":      "records/{id}/{id}/download",
+		"v2/records/7/download":          "v2/records/{id}/download",
+		"subscription/abc":               "subscription/{id}",
+		"icr/route":                      "icr/route",
+	} {
+		if got := endpoint(path); got != want {
+			t.Fatalf("Шаблон пути %s: ожидалось %s получено %s", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package beelineapi
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL Адрес API портала Билайн по умолчанию
+const DefaultBaseURL = "https://cloudpbx.beeline.ru/apis/portal/"
+
+// DefaultTimeout Время ожидания ответа от сервера по умолчанию
+const DefaultTimeout = 60 * time.Second
+
+// Option Параметр клиента API для NewClient
+type Option func(c *APIClient) error
+
+// RetryPolicy Параметры повтора запросов
+type RetryPolicy struct {
+	Attempts int           // Количество повторов после первой неудачной попытки
+	Backoff  time.Duration // Пауза перед первым повтором, перед каждым следующим она удваивается
+}
+
+// NewClient Возвращает клиента API портала с ключом безопасности token
+// token - Ключ безопасности, передаваемый в заголовке X-MPBX-API-AUTH-TOKEN. Может быть пустым, если задан WithTokenSource
+// opts - Параметры клиента
+func NewClient(token string, opts ...Option) (*APIClient, error) {
+	c := &APIClient{
+		Token:      token,
+		Provider:   "Beeline",
+		BaseApiUrl: DefaultBaseURL,
+		timeout:    DefaultTimeout,
+		auth:       &tokenState{},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.Token == "" && c.tokens == nil {
+		return nil, WrapError{Msg: "Не указан ключ безопасности"}
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{}
+	}
+	return c, nil
+}
+
+// WithBaseURL Задает адрес API портала. Все пути запросов отсчитываются от него,
+// поэтому адрес дополняется завершающим "/", если его нет.
+func WithBaseURL(base string) Option {
+	return func(c *APIClient) error {
+		u, err := url.Parse(base)
+		if err != nil {
+			return Wrap("Неверный адрес API. ", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return WrapError{Msg: "Неверный адрес API " + base + ". Ожидался абсолютный адрес http или https"}
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return WrapError{Msg: "Неверный адрес API " + base + ". Адрес не должен содержать параметры запроса"}
+		}
+		if strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/records") || strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/v2") {
+			return WrapError{Msg: "Неверный адрес API " + base + ". Адрес должен указывать на корень API, например " + DefaultBaseURL}
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		c.BaseApiUrl = u.String()
+		return nil
+	}
+}
+
+// WithHTTPClient Задает HTTP клиента для отправки запросов
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *APIClient) error {
+		if hc == nil {
+			return WrapError{Msg: "Не указан HTTP клиент"}
+		}
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// WithUserAgent Задает значение заголовка User-Agent
+func WithUserAgent(ua string) Option {
+	return func(c *APIClient) error {
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// WithLogger Задает журнал для сообщений о повторах и ошибках запросов
+func WithLogger(l *log.Logger) Option {
+	return func(c *APIClient) error {
+		c.logger = l
+		return nil
+	}
+}
+
+// WithRetry Включает повтор запросов GET, PUT и DELETE при сетевых ошибках и ответах 429 и 5xx
+// attempts - Количество повторов после первой неудачной попытки
+// backoff - Пауза перед первым повтором, перед каждым следующим она удваивается
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(c *APIClient) error {
+		if attempts < 0 || backoff < 0 {
+			return WrapError{Msg: "Количество повторов и пауза между ними не могут быть отрицательными"}
+		}
+		c.retry = RetryPolicy{Attempts: attempts, Backoff: backoff}
+		return nil
+	}
+}
+
+// WithRateLimit Ограничивает частоту запросов: не более n запросов за период per
+func WithRateLimit(n int, per time.Duration) Option {
+	return func(c *APIClient) error {
+		if n <= 0 || per <= 0 {
+			return WrapError{Msg: "Ограничение частоты запросов должно быть положительным"}
+		}
+		c.limiter = &rateLimiter{interval: per / time.Duration(n)}
+		return nil
+	}
+}
+
+// WithTimeout Задает время ожидания ответа от сервера на каждый запрос
+func WithTimeout(d time.Duration) Option {
+	return func(c *APIClient) error {
+		if d <= 0 {
+			return WrapError{Msg: "Время ожидания ответа должно быть положительным"}
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// rateLimiter Выдерживает минимальный интервал между запросами
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait Ожидает, пока можно будет отправить следующий запрос, или отмены ctx
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
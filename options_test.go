@@ -0,0 +1,104 @@
+package beelineapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWithBaseURL Тест на проверку и нормализацию адреса API
+func TestWithBaseURL(t *testing.T) {
+	c, err := NewClient("token", WithBaseURL("http://localhost:8080/apis/portal"))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if c.BaseApiUrl != "http://localhost:8080/apis/portal/" {
+		t.Fatalf("Адрес API не дополнен завершающим /: %s", c.BaseApiUrl)
+	}
+	for _, base := range []string{"localhost/apis", "ftp://host/", "http://host/?a=1", "http://host/apis/portal/v2/", "http://host/apis/portal/records"} {
+		if _, err := NewClient("token", WithBaseURL(base)); err == nil {
+			t.Fatalf("Ожидалась ошибка для адреса %s", base)
+		}
+	}
+	if _, err := NewClient(""); err == nil {
+		t.Fatal("Ожидалась ошибка для пустого ключа безопасности")
+	}
+}
+
+// TestRetry Тест на повтор запросов и заголовок User-Agent
+func TestRetry(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if ua := r.Header.Get("User-Agent"); ua != "crm/1.0" {
+			t.Errorf("Неверный User-Agent: %s", ua)
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient("token", WithBaseURL(srv.URL), WithUserAgent("crm/1.0"), WithRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if _, err := c.Request("GET", "abonents", nil); err != nil {
+		t.Fatalf("Запрос должен завершиться успешно после повторов: %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("Ожидалось 3 попытки, выполнено %d", calls)
+	}
+	calls = 0
+	if _, err := c.Request("POST", "abonents", nil); err == nil || calls != 1 {
+		t.Fatalf("Запрос POST не должен повторяться: %v, попыток %d", err, calls)
+	}
+
+	tokens := 0
+	c, _ = NewClient("", WithBaseURL(srv.URL), WithRetry(2, time.Millisecond),
+		WithTokenSource(TokenFunc(func(ctx context.Context) (string, error) {
+			tokens++
+			return "", errors.New("ключ недоступен")
+		})))
+	if _, err := c.Request("GET", "abonents", nil); err == nil || tokens != 1 {
+		t.Fatalf("Ошибка источника ключа не должна повторяться: %v, попыток %d", err, tokens)
+	}
+	attempts := 0
+	logger := log.New(writerFunc(func(p []byte) (int, error) { attempts++; return len(p), nil }), "", 0)
+	c, _ = NewClient("token", WithBaseURL("http://127.0.0.1:1/"), WithRetry(2, time.Millisecond), WithLogger(logger))
+	if _, err := c.Request("GET", "abonents", nil); err == nil || attempts != 2 {
+		t.Fatalf("Сетевая ошибка должна повторяться: %v, повторов %d", err, attempts)
+	}
+}
+
+// writerFunc Адаптер функции к io.Writer
+type writerFunc func(p []byte) (int, error)
+
+// TestRateLimitCancel Тест на прерывание ожидания очереди запросов при отмене контекста
+func TestRateLimitCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	c, err := NewClient("token", WithBaseURL(srv.URL), WithRateLimit(1, time.Hour))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if _, err := c.Request("GET", "abonents", nil); err != nil {
+		t.Fatalf("Первый запрос не должен ожидать: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.RequestContext(ctx, "GET", "abonents", nil); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Fatalf("Ожидание очереди должно прерываться при отмене контекста: %v за %s", err, time.Since(start))
+	}
+}
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
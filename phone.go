@@ -0,0 +1,89 @@
+package beelineapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PhoneLength Количество цифр в номере телефона, которое ожидает API
+const PhoneLength = 10
+
+// Phone Номер телефона в формате API - 10 цифр без кода страны, например 9123456789
+type Phone string
+
+// PhoneError Ошибка разбора номера телефона
+type PhoneError struct {
+	Phone  string // Исходный номер
+	Reason string // Причина ошибки
+}
+
+func (e PhoneError) Error() string {
+	return fmt.Sprintf("Неверный номер телефона %q: %s", e.Phone, e.Reason)
+}
+
+// ParsePhone Приводит номер телефона в российском формате к формату API.
+// Допускаются пробелы, скобки, дефисы и точки, а также префиксы +7, 7 и 8 перед 10 цифрами номера,
+// например "+7 (912) 345-67-89" и "8 912 345 67 89" приводятся к "9123456789".
+func ParsePhone(s string) (Phone, error) {
+	digits := make([]byte, 0, len(s))
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == '+' && i == 0:
+			// Код страны проверяется ниже
+		case r == ' ' || r == '(' || r == ')' || r == '-' || r == '.' || r == '\u00a0':
+		default:
+			return "", PhoneError{Phone: s, Reason: fmt.Sprintf("недопустимый символ %q", r)}
+		}
+	}
+	plus := strings.HasPrefix(strings.TrimSpace(s), "+")
+	switch {
+	case len(digits) == PhoneLength+1 && digits[0] == '7':
+		digits = digits[1:]
+	case len(digits) == PhoneLength+1 && digits[0] == '8' && !plus:
+		digits = digits[1:]
+	case plus:
+		return "", PhoneError{Phone: s, Reason: "ожидался российский номер с кодом страны +7"}
+	}
+	if len(digits) != PhoneLength {
+		return "", PhoneError{Phone: s, Reason: fmt.Sprintf("ожидалось %d цифр номера, получено %d", PhoneLength, len(digits))}
+	}
+	return Phone(digits), nil
+}
+
+// NormalizePhone Приводит номер телефона к формату API и возвращает его строкой
+func NormalizePhone(s string) (string, error) {
+	p, err := ParsePhone(s)
+	return string(p), err
+}
+
+// NormalizePhones Приводит список номеров телефонов к формату API.
+// Возвращает ошибку для первого неверного номера.
+func NormalizePhones(list []string) ([]string, error) {
+	if list == nil {
+		return nil, nil
+	}
+	res := make([]string, len(list))
+	for i, s := range list {
+		p, err := ParsePhone(s)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = string(p)
+	}
+	return res, nil
+}
+
+func (p Phone) String() string {
+	return string(p)
+}
+
+// Format Возвращает номер в удобном для чтения виде, например +7 (912) 345-67-89
+func (p Phone) Format() string {
+	s := string(p)
+	if len(s) != PhoneLength {
+		return s
+	}
+	return "+7 (" + s[:3] + ") " + s[3:6] + "-" + s[6:8] + "-" + s[8:]
+}
@@ -0,0 +1,42 @@
+package beelineapi
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParsePhone Тест на приведение номеров телефонов к формату API
+func TestParsePhone(t *testing.T) {
+	valid := map[string]Phone{
+		"9123456789":         "9123456789",
+		"+7 (912) 345-67-89": "9123456789",
+		"8 912 345 67 89":    "9123456789",
+		"89123456789":        "9123456789",
+		"79123456789":        "9123456789",
+		" 8(495)123-45-67 ":  "4951234567",
+		"+7 912.345.67.89":   "9123456789",
+	}
+	for in, want := range valid {
+		t.Run(in, func(t *testing.T) {
+			got, err := ParsePhone(in)
+			if err != nil || got != want {
+				t.Fatalf("Ожидалось %s получено %q %v", want, got, err)
+			}
+		})
+	}
+	for _, in := range []string{"", "12345", "912345678901", "+1 212 555 0100", "+8 912 345 67 89", "912-345-67-8x", "9+123456789"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := ParsePhone(in)
+			var pe PhoneError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Ожидалась ошибка PhoneError, получено %v", err)
+			}
+		})
+	}
+	if f := Phone("9123456789").Format(); f != "+7 (912) 345-67-89" {
+		t.Fatalf("Неверный формат номера: %s", f)
+	}
+	if _, err := NormalizePhones([]string{"9123456789", "123"}); err == nil {
+		t.Fatal("Ожидалась ошибка для списка с неверным номером")
+	}
+}
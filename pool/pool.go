@@ -0,0 +1,264 @@
+// Package pool содержит пул клиентов API для обслуживания многих клиентов облачной АТС,
+// у каждого из которых свой ключ безопасности.
+// Клиенты пула используют общий HTTP транспорт, у каждого клиента свое ограничение частоты запросов,
+// а события Xsi-Events направляются клиенту, которому принадлежит подписка.
+package pool
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/xsi"
+)
+
+// maxEventSize Максимальный размер тела запроса с событием Xsi-Events
+const maxEventSize = 1 << 20
+
+// Option Параметр пула для New
+type Option func(p *ClientPool) error
+
+// RateLimit Ограничение частоты запросов: не более N запросов за период Per
+type RateLimit struct {
+	N   int
+	Per time.Duration
+}
+
+// ClientPool Пул клиентов API по имени клиента облачной АТС. Создается функцией New.
+// Клиент API создается при первом обращении с ключом безопасности из SecretProvider.
+type ClientPool struct {
+	secrets   SecretProvider
+	transport http.RoundTripper
+	opts      []beelineapi.Option
+	limit     RateLimit
+	limits    map[string]RateLimit
+	slog      *slog.Logger
+
+	mu            sync.Mutex
+	httpClient    *http.Client
+	clients       map[string]*beelineapi.APIClient
+	subscriptions map[string]string // Идентификатор подписки Xsi-Events - клиент
+}
+
+// New Возвращает пул клиентов API
+// secrets - Источник ключей безопасности клиентов
+// opts - Параметры пула
+func New(secrets SecretProvider, opts ...Option) (*ClientPool, error) {
+	if secrets == nil {
+		return nil, beelineapi.WrapError{Msg: "Не указан источник ключей безопасности"}
+	}
+	p := &ClientPool{
+		secrets:       secrets,
+		limits:        map[string]RateLimit{},
+		clients:       map[string]*beelineapi.APIClient{},
+		subscriptions: map[string]string{},
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	if p.transport == nil {
+		p.transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	p.httpClient = &http.Client{Transport: p.transport}
+	return p, nil
+}
+
+// WithTransport Задает HTTP транспорт, общий для всех клиентов пула
+func WithTransport(rt http.RoundTripper) Option {
+	return func(p *ClientPool) error {
+		if rt == nil {
+			return beelineapi.WrapError{Msg: "Не указан HTTP транспорт"}
+		}
+		p.transport = rt
+		return nil
+	}
+}
+
+// WithClientOptions Задает параметры, с которыми создаются все клиенты API пула, например beelineapi.WithRetry.
+// Параметр beelineapi.WithHTTPClient не нужен: клиенты используют общий транспорт пула
+func WithClientOptions(opts ...beelineapi.Option) Option {
+	return func(p *ClientPool) error {
+		p.opts = append(p.opts, opts...)
+		return nil
+	}
+}
+
+// WithRateLimit Ограничивает частоту запросов каждого клиента, для которого не задано собственное ограничение
+func WithRateLimit(n int, per time.Duration) Option {
+	return func(p *ClientPool) error {
+		if n <= 0 || per <= 0 {
+			return beelineapi.WrapError{Msg: "Ограничение частоты запросов должно быть положительным"}
+		}
+		p.limit = RateLimit{N: n, Per: per}
+		return nil
+	}
+}
+
+// WithTenantRateLimit Ограничивает частоту запросов клиента tenant
+func WithTenantRateLimit(tenant string, n int, per time.Duration) Option {
+	return func(p *ClientPool) error {
+		if n <= 0 || per <= 0 {
+			return beelineapi.WrapError{Msg: "Ограничение частоты запросов клиента " + tenant + " должно быть положительным"}
+		}
+		p.limits[tenant] = RateLimit{N: n, Per: per}
+		return nil
+	}
+}
+
+// WithSlog Задает журнал slog для сообщений об отклоненных событиях Xsi-Events.
+// Журнал запросов клиентов задается параметром клиента beelineapi.WithSlog.
+func WithSlog(l *slog.Logger) Option {
+	return func(p *ClientPool) error {
+		if l == nil {
+			return beelineapi.WrapError{Msg: "Не указан журнал"}
+		}
+		p.slog = l
+		return nil
+	}
+}
+
+// Client Возвращает клиента API для клиента облачной АТС tenant, создавая его при первом обращении
+func (p *ClientPool) Client(ctx context.Context, tenant string) (*beelineapi.APIClient, error) {
+	p.mu.Lock()
+	c, ok := p.clients[tenant]
+	p.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	token, err := p.secrets.Token(ctx, tenant)
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при получении ключа безопасности клиента "+tenant+". ", err)
+	}
+	opts := append([]beelineapi.Option{beelineapi.WithHTTPClient(p.httpClient)}, p.opts...)
+	limit, ok := p.limits[tenant]
+	if !ok {
+		limit = p.limit
+	}
+	if limit.N > 0 {
+		opts = append(opts, beelineapi.WithRateLimit(limit.N, limit.Per))
+	}
+	c, err = beelineapi.NewClient(token, opts...)
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при создании клиента API для "+tenant+". ", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Клиент мог быть создан параллельным вызовом, тогда используется он, чтобы не разделять ограничение частоты
+	if existing, ok := p.clients[tenant]; ok {
+		return existing, nil
+	}
+	p.clients[tenant] = c
+	return c, nil
+}
+
+// Forget Удаляет клиента API tenant из пула, например после смены ключа безопасности.
+// При следующем обращении клиент будет создан заново. Подписки Xsi-Events клиента сохраняются
+func (p *ClientPool) Forget(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, tenant)
+}
+
+// Tenants Возвращает отсортированный список клиентов, для которых созданы клиенты API
+func (p *ClientPool) Tenants() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tenants := make([]string, 0, len(p.clients))
+	for t := range p.clients {
+		tenants = append(tenants, t)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// Subscribe Формирует подписку на Xsi-Events для клиента tenant и запоминает ее для маршрутизации событий
+func (p *ClientPool) Subscribe(ctx context.Context, tenant string, req xsi.SubscriptionRequest) (xsi.SubscriptionResult, error) {
+	c, err := p.Client(ctx, tenant)
+	if err != nil {
+		return xsi.SubscriptionResult{}, err
+	}
+	res, err := xsi.New(c).XSIEventSubscription(req)
+	if err != nil {
+		return res, err
+	}
+	p.Register(tenant, res.SubscriptionId)
+	return res, nil
+}
+
+// Unsubscribe Отключает подписку на Xsi-Events клиента tenant и перестает направлять ее события
+func (p *ClientPool) Unsubscribe(ctx context.Context, tenant string, subscriptionId string) error {
+	c, err := p.Client(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	if err := xsi.New(c).TurnOffXSIEventSubscription(subscriptionId); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.subscriptions, subscriptionId)
+	return nil
+}
+
+// Register Запоминает, что подписка subscriptionId, созданная ранее, принадлежит клиенту tenant
+func (p *ClientPool) Register(tenant string, subscriptionId string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscriptions[subscriptionId] = tenant
+}
+
+// Tenant Возвращает клиента, которому принадлежит подписка subscriptionId
+func (p *ClientPool) Tenant(subscriptionId string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tenant, ok := p.subscriptions[subscriptionId]
+	return tenant, ok
+}
+
+// EventHandler Обработчик события Xsi-Events клиента tenant
+type EventHandler func(tenant string, ev xsi.Event)
+
+// Handler Возвращает HTTP обработчик для URL приложения из подписок Xsi-Events.
+// Событие передается в h вместе с клиентом, которому принадлежит подписка.
+// На события неизвестных подписок обработчик отвечает 404, на неверные события - 400
+func (p *ClientPool) Handler(h EventHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxEventSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ev, err := xsi.ParseEvent(data)
+		if err != nil {
+			p.warn(r.Context(), "Отклонено событие Xsi-Events", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tenant, ok := p.Tenant(ev.SubscriptionId)
+		if !ok {
+			p.warn(r.Context(), "Отклонено событие Xsi-Events неизвестной подписки", slog.String("subscriptionId", ev.SubscriptionId))
+			http.Error(w, "unknown subscription", http.StatusNotFound)
+			return
+		}
+		h(tenant, ev)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// warn Записывает предупреждение в журнал пула, если он задан
+func (p *ClientPool) warn(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if p.slog != nil {
+		p.slog.LogAttrs(ctx, slog.LevelWarn, msg, attrs...)
+	}
+}
@@ -0,0 +1,176 @@
+package pool_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/pool"
+	"github.com/taigasys/beeline-portal-api/xsi"
+)
+
+// countingTransport HTTP транспорт, считающий запросы
+type countingTransport struct {
+	n int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newPool Возвращает имитатор портала с ключом клиента acme и пул с клиентами acme и other
+func newPool(t *testing.T, opts ...pool.Option) (*beelinetest.Server, *pool.ClientPool) {
+	t.Helper()
+	s := beelinetest.NewServer("acme-token")
+	t.Cleanup(s.Close)
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Extension: "101"})
+	secrets := pool.StaticSecrets{"acme": "acme-token", "other": "other-token"}
+	opts = append([]pool.Option{pool.WithClientOptions(beelineapi.WithBaseURL(s.URL))}, opts...)
+	p, err := pool.New(secrets, opts...)
+	if err != nil {
+		t.Fatalf("Не удалось создать пул: %s", err)
+	}
+	return s, p
+}
+
+// TestClients Тест на создание клиентов API с ключами из источника и общим транспортом
+func TestClients(t *testing.T) {
+	tr := &countingTransport{}
+	_, p := newPool(t, pool.WithTransport(tr))
+	ctx := context.Background()
+
+	acme, err := p.Client(ctx, "acme")
+	if err != nil {
+		t.Fatalf("Не удалось получить клиента API: %s", err)
+	}
+	if again, _ := p.Client(ctx, "acme"); again != acme {
+		t.Fatalf("Клиент API должен создаваться один раз")
+	}
+	if _, err := abonents.New(acme).GetAbonents(); err != nil {
+		t.Fatalf("Запрос с ключом клиента acme не выполнен: %s", err)
+	}
+	other, err := p.Client(ctx, "other")
+	if err != nil {
+		t.Fatalf("Не удалось получить клиента API: %s", err)
+	}
+	if _, err := abonents.New(other).GetAbonents(); err == nil {
+		t.Fatalf("Запрос с ключом клиента other должен быть отклонен имитатором")
+	}
+	if n := atomic.LoadInt32(&tr.n); n != 2 {
+		t.Fatalf("Запросы должны идти через общий транспорт, выполнено %d", n)
+	}
+	if _, err := p.Client(ctx, "unknown"); err == nil {
+		t.Fatalf("Ожидалась ошибка для клиента без ключа безопасности")
+	}
+	if tenants := p.Tenants(); len(tenants) != 2 || tenants[0] != "acme" || tenants[1] != "other" {
+		t.Fatalf("Неверный список клиентов: %v", tenants)
+	}
+	p.Forget("acme")
+	if c, _ := p.Client(ctx, "acme"); c == acme {
+		t.Fatalf("После Forget клиент API должен быть создан заново")
+	}
+}
+
+// TestRateLimit Тест на ограничение частоты запросов отдельного клиента
+func TestRateLimit(t *testing.T) {
+	_, p := newPool(t, pool.WithTenantRateLimit("acme", 1, 50*time.Millisecond))
+	c, err := p.Client(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("Не удалось получить клиента API: %s", err)
+	}
+	svc := abonents.New(c)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := svc.GetAbonents(); err != nil {
+			t.Fatalf("Не удалось получить список абонентов: %s", err)
+		}
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("Ограничение частоты запросов не применено: 3 запроса за %s", d)
+	}
+	if _, err := pool.New(pool.StaticSecrets{}, pool.WithTenantRateLimit("acme", 0, time.Second)); err == nil {
+		t.Fatalf("Ожидалась ошибка для нулевого ограничения частоты")
+	}
+}
+
+// TestSecrets Тест на источники ключей безопасности
+func TestSecrets(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("BEELINE_TOKEN_ACME_CORP", "env-token")
+	if token, err := (pool.EnvSecrets{Prefix: "BEELINE_TOKEN_"}).Token(ctx, "acme-corp"); err != nil || token != "env-token" {
+		t.Fatalf("Неверный ключ из переменной окружения: %q %v", token, err)
+	}
+	if _, err := (pool.EnvSecrets{Prefix: "BEELINE_TOKEN_"}).Token(ctx, "missing"); err == nil {
+		t.Fatalf("Ожидалась ошибка для незаданной переменной окружения")
+	}
+	f := pool.SecretFunc(func(ctx context.Context, tenant string) (string, error) { return "vault-" + tenant, nil })
+	if token, _ := f.Token(ctx, "acme"); token != "vault-acme" {
+		t.Fatalf("Неверный ключ из функции: %q", token)
+	}
+}
+
+// TestEventRouting Тест на направление событий Xsi-Events клиенту, которому принадлежит подписка
+func TestEventRouting(t *testing.T) {
+	var logs bytes.Buffer
+	_, p := newPool(t, pool.WithSlog(slog.New(slog.NewTextHandler(&logs, nil))))
+	res, err := p.Subscribe(context.Background(), "acme", xsi.SubscriptionRequest{Pattern: "101", Expires: 3600, Url: "https://crm.example/events"})
+	if err != nil {
+		t.Fatalf("Не удалось подписаться на события: %s", err)
+	}
+	p.Register("other", "sub-other")
+
+	var got []string
+	h := p.Handler(func(tenant string, ev xsi.Event) {
+		got = append(got, tenant+"/"+ev.SubscriptionId+"/"+ev.EventType)
+	})
+	post := func(subscriptionId string) int {
+		body := `<?xml version="1.0" encoding="UTF-8"?>
+<xsi:Event xmlns:xsi="http://schema.broadsoft.com/xsi" xmlns:xsi1="http://www.w3.org/2001/XMLSchema-instance" xsi1:type="xsi:SubscriptionEvent">
+<xsi:eventID>e1</xsi:eventID><xsi:sequenceNumber>1</xsi:sequenceNumber><xsi:userId>u1</xsi:userId>
+<xsi:subscriptionId>` + subscriptionId + `</xsi:subscriptionId><xsi:targetId>u1</xsi:targetId>
+<xsi:eventData xsi1:type="xsi:CallReceivedEvent"></xsi:eventData></xsi:Event>`
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/events", strings.NewReader(body)))
+		return w.Code
+	}
+
+	tests := []struct {
+		name           string
+		subscriptionId string
+		code           int
+	}{
+		{"acme", res.SubscriptionId, http.StatusOK},
+		{"other", "sub-other", http.StatusOK},
+		{"unknown", "sub-unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := post(tt.subscriptionId); code != tt.code {
+				t.Fatalf("Неверный код ответа: ожидался %d получен %d", tt.code, code)
+			}
+		})
+	}
+	if len(got) != 2 || got[0] != "acme/"+res.SubscriptionId+"/CallReceivedEvent" || got[1] != "other/sub-other/CallReceivedEvent" {
+		t.Fatalf("Неверно направлены события: %v", got)
+	}
+	if !strings.Contains(logs.String(), "subscriptionId=sub-unknown") {
+		t.Fatalf("Отклоненное событие не записано в журнал: %s", logs.String())
+	}
+
+	if err := p.Unsubscribe(context.Background(), "acme", res.SubscriptionId); err != nil {
+		t.Fatalf("Не удалось отключить подписку: %s", err)
+	}
+	if _, ok := p.Tenant(res.SubscriptionId); ok {
+		t.Fatalf("Отключенная подписка не должна направляться клиенту")
+	}
+}
@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// SecretProvider Источник ключей безопасности X-MPBX-API-AUTH-TOKEN клиентов
+type SecretProvider interface {
+	// Token Возвращает ключ безопасности клиента tenant
+	Token(ctx context.Context, tenant string) (string, error)
+}
+
+// SecretFunc Позволяет использовать функцию как SecretProvider, например для чтения ключей из хранилища секретов
+type SecretFunc func(ctx context.Context, tenant string) (string, error)
+
+// Token Вызывает f
+func (f SecretFunc) Token(ctx context.Context, tenant string) (string, error) {
+	return f(ctx, tenant)
+}
+
+// StaticSecrets Ключи безопасности, заданные заранее: клиент - ключ
+type StaticSecrets map[string]string
+
+// Token Возвращает ключ клиента tenant из списка
+func (s StaticSecrets) Token(ctx context.Context, tenant string) (string, error) {
+	token, ok := s[tenant]
+	if !ok || token == "" {
+		return "", beelineapi.WrapError{Msg: "Не найден ключ безопасности клиента " + tenant}
+	}
+	return token, nil
+}
+
+// EnvSecrets Ключи безопасности в переменных окружения с именем Prefix + имя клиента в верхнем регистре,
+// в котором символы, отличные от латинских букв и цифр, заменены на "_".
+// Например, для Prefix "BEELINE_TOKEN_" ключ клиента acme-corp читается из BEELINE_TOKEN_ACME_CORP
+type EnvSecrets struct {
+	Prefix string
+}
+
+// Token Возвращает ключ клиента tenant из переменной окружения
+func (s EnvSecrets) Token(ctx context.Context, tenant string) (string, error) {
+	name := s.Prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, tenant)
+	token := os.Getenv(name)
+	if token == "" {
+		return "", beelineapi.WrapError{Msg: "Не задана переменная окружения " + name + " с ключом безопасности клиента " + tenant}
+	}
+	return token, nil
+}
@@ -0,0 +1,129 @@
+package reconcile
+
+import (
+	"os"
+	"strconv"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/internal/yamljson"
+)
+
+// Config Желаемая конфигурация абонентов облачной АТС
+type Config struct {
+	Abonents []Abonent `json:"abonents"`
+}
+
+// Abonent Желаемые настройки абонента. Незаданные настройки не изменяются.
+type Abonent struct {
+	Id                  string               `json:"id"`                            // Идентификатор, мобильный или добавочный номер абонента
+	Recording           *bool                `json:"recording,omitempty"`           // Запись разговоров
+	Forwarding          *BasicForwarding     `json:"forwarding,omitempty"`          // Базовая переадресация
+	SelectiveForwarding *SelectiveForwarding `json:"selectiveForwarding,omitempty"` // Выборочная переадресация
+	Bwl                 *Bwl                 `json:"bwl,omitempty"`                 // Выборочный прием звонков
+	Icr                 *[]string            `json:"icr,omitempty"`                 // Входящие номера, направленные на добавочный номер абонента
+}
+
+// BasicForwarding Желаемая базовая переадресация
+type BasicForwarding struct {
+	Enabled bool `json:"enabled"`
+	forwarding.BasicRedirect
+}
+
+// SelectiveForwarding Желаемая выборочная переадресация. Правила сопоставляются по названию.
+type SelectiveForwarding struct {
+	Enabled bool                       `json:"enabled"`
+	Rules   []forwarding.CfsRuleUpdate `json:"rules"`
+}
+
+// Bwl Желаемый выборочный прием звонков. Правила сопоставляются по типу списка и названию.
+type Bwl struct {
+	Mode  string         `json:"mode"` // BLACK_LIST, WHITE_LIST или OFF
+	Rules []bwl.ListRule `json:"rules"`
+}
+
+// Parse Разбирает конфигурацию в формате YAML или JSON.
+// Неизвестные поля считаются ошибкой, чтобы опечатка не приводила к молчаливому пропуску настройки.
+func Parse(data []byte) (Config, error) {
+	cfg := Config{}
+	if err := yamljson.Decode(data, &cfg, true); err != nil {
+		return cfg, beelineapi.Wrap("Ошибка при разборе конфигурации. ", err)
+	}
+	return cfg.check()
+}
+
+// Load Читает конфигурацию из файла в формате YAML или JSON
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, beelineapi.Wrap("Ошибка при чтении конфигурации. ", err)
+	}
+	return Parse(data)
+}
+
+// check Проверяет конфигурацию и возвращает ее копию с номерами телефонов, приведенными к формату API,
+// чтобы номера из конфигурации совпадали с номерами, которые возвращает сервер
+func (cfg Config) check() (Config, error) {
+	res := Config{Abonents: make([]Abonent, len(cfg.Abonents))}
+	seen := map[string]bool{}
+	for i, a := range cfg.Abonents {
+		if a.Id == "" {
+			return res, beelineapi.WrapError{Msg: "Не указан идентификатор абонента №" + strconv.Itoa(i+1)}
+		}
+		if seen[a.Id] {
+			return res, beelineapi.WrapError{Msg: "Абонент " + a.Id + " указан несколько раз"}
+		}
+		seen[a.Id] = true
+		n, err := a.normalize()
+		if err != nil {
+			return res, beelineapi.Wrap("Абонент "+a.Id+". ", err)
+		}
+		res.Abonents[i] = n
+	}
+	return res, nil
+}
+
+// normalize Проверяет настройки абонента и возвращает их копию с номерами в формате API
+func (a Abonent) normalize() (Abonent, error) {
+	if f := a.Forwarding; f != nil {
+		br, err := f.BasicRedirect.Normalize()
+		if err != nil {
+			return a, err
+		}
+		a.Forwarding = &BasicForwarding{Enabled: f.Enabled, BasicRedirect: br}
+	}
+	if sf := a.SelectiveForwarding; sf != nil {
+		n := &SelectiveForwarding{Enabled: sf.Enabled, Rules: make([]forwarding.CfsRuleUpdate, len(sf.Rules))}
+		for i, r := range sf.Rules {
+			var err error
+			if n.Rules[i], err = r.Normalize(); err != nil {
+				return a, err
+			}
+		}
+		a.SelectiveForwarding = n
+	}
+	if b := a.Bwl; b != nil {
+		if _, err := bwlMode(b.Mode); err != nil {
+			return a, err
+		}
+		n := &Bwl{Mode: b.Mode, Rules: make([]bwl.ListRule, len(b.Rules))}
+		for i, r := range b.Rules {
+			phones, err := beelineapi.NormalizePhones(r.PhoneList)
+			if err != nil {
+				return a, err
+			}
+			n.Rules[i] = r
+			n.Rules[i].PhoneList = phones
+		}
+		a.Bwl = n
+	}
+	if a.Icr != nil {
+		list, err := beelineapi.NormalizePhones(*a.Icr)
+		if err != nil {
+			return a, err
+		}
+		a.Icr = &list
+	}
+	return a, nil
+}
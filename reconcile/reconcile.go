@@ -0,0 +1,351 @@
+// Package reconcile приводит настройки абонентов облачной АТС к желаемой конфигурации.
+// Конфигурация описывается в YAML или JSON: базовая и выборочная переадресация,
+// правила выборочного приема звонков, запись разговоров и правила индивидуальной переадресации.
+// Reconciler сравнивает ее с текущими настройками, возвращает план изменений
+// и применяет только необходимые изменения.
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/icr"
+)
+
+// Change Изменение настройки абонента
+type Change struct {
+	Abonent     string `json:"abonent"`     // Идентификатор абонента из конфигурации
+	Setting     string `json:"setting"`     // Настройка: recording, forwarding, selectiveForwarding, bwl или icr
+	Description string `json:"description"` // Описание изменения
+	apply       func() error
+}
+
+func (c Change) String() string {
+	return c.Abonent + " " + c.Setting + ": " + c.Description
+}
+
+// Plan План изменений в порядке применения
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Empty Проверяет, что изменений нет
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p Plan) String() string {
+	lines := make([]string, 0, len(p.Changes))
+	for _, c := range p.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Reconciler Сравнивает конфигурацию с текущими настройками и применяет изменения
+type Reconciler struct {
+	abonents   *abonents.Service
+	forwarding *forwarding.Service
+	bwl        *bwl.Service
+	icr        *icr.Service
+}
+
+// New Возвращает Reconciler для клиента c
+func New(c *beelineapi.APIClient) *Reconciler {
+	return &Reconciler{
+		abonents:   abonents.New(c),
+		forwarding: forwarding.New(c),
+		bwl:        bwl.New(c),
+		icr:        icr.New(c),
+	}
+}
+
+// Plan Сравнивает конфигурацию cfg с текущими настройками и возвращает план изменений.
+// Настройки не изменяются.
+func (r *Reconciler) Plan(cfg Config) (Plan, error) {
+	cfg, err := cfg.check()
+	if err != nil {
+		return Plan{}, err
+	}
+	p := Plan{}
+	var routes []icr.IcrRouteRule
+	for _, a := range cfg.Abonents {
+		steps := []func(Abonent) ([]Change, error){r.planRecording, r.planForwarding, r.planSelective, r.planBwl}
+		if a.Icr != nil && routes == nil {
+			var err error
+			if routes, err = r.icr.GetRedirectRulesList(); err != nil {
+				return p, err
+			}
+		}
+		steps = append(steps, func(a Abonent) ([]Change, error) { return r.planIcr(a, routes) })
+		for _, step := range steps {
+			changes, err := step(a)
+			if err != nil {
+				return p, beelineapi.Wrap("Абонент "+a.Id+". ", err)
+			}
+			p.Changes = append(p.Changes, changes...)
+		}
+	}
+	return p, nil
+}
+
+// Apply Применяет изменения плана по порядку. При ошибке остальные изменения не применяются,
+// а в ошибке указывается изменение, на котором она произошла.
+func (r *Reconciler) Apply(p Plan) error {
+	for i, c := range p.Changes {
+		if c.apply == nil {
+			return beelineapi.WrapError{Msg: "Изменение " + c.String() + " не получено из Plan"}
+		}
+		if err := c.apply(); err != nil {
+			return beelineapi.Wrap(fmt.Sprintf("Ошибка при применении изменения %d из %d (%s). ", i+1, len(p.Changes), c), err)
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) planRecording(a Abonent) ([]Change, error) {
+	if a.Recording == nil {
+		return nil, nil
+	}
+	status, err := r.abonents.GetRecordingStatus(a.Id)
+	if err != nil {
+		return nil, err
+	}
+	want := *a.Recording
+	if (status == beelineapi.ON) == want {
+		return nil, nil
+	}
+	if want {
+		return []Change{{Abonent: a.Id, Setting: "recording", Description: "включить", apply: func() error { return r.abonents.TurnOnRecording(a.Id) }}}, nil
+	}
+	return []Change{{Abonent: a.Id, Setting: "recording", Description: "отключить", apply: func() error { return r.abonents.TurnOffRecording(a.Id) }}}, nil
+}
+
+func (r *Reconciler) planForwarding(a Abonent) ([]Change, error) {
+	want := a.Forwarding
+	if want == nil {
+		return nil, nil
+	}
+	cur, err := r.forwarding.GetBasicRedirectStatus(a.Id)
+	if err != nil {
+		return nil, err
+	}
+	enabled := cur.Status == beelineapi.ON
+	switch {
+	case want.Enabled && (!enabled || cur.Forward != want.BasicRedirect):
+		br := want.BasicRedirect
+		return []Change{{Abonent: a.Id, Setting: "forwarding", Description: "включить " + describeRedirect(br),
+			apply: func() error { return r.forwarding.TurnOnBasicRedirect(a.Id, br) }}}, nil
+	case !want.Enabled && enabled:
+		return []Change{{Abonent: a.Id, Setting: "forwarding", Description: "отключить",
+			apply: func() error { return r.forwarding.TurnOffBasicRedirect(a.Id) }}}, nil
+	}
+	return nil, nil
+}
+
+func (r *Reconciler) planSelective(a Abonent) ([]Change, error) {
+	want := a.SelectiveForwarding
+	if want == nil {
+		return nil, nil
+	}
+	cur, err := r.forwarding.GetSelectiveCallRules(a.Id)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	add := func(desc string, fn func() error) {
+		changes = append(changes, Change{Abonent: a.Id, Setting: "selectiveForwarding", Description: desc, apply: fn})
+	}
+	existing := map[string]forwarding.CfsRule{}
+	for _, rule := range cur.RuleList {
+		existing[rule.Name] = rule
+	}
+	// Отключение выполняется до изменения правил, а включение - после
+	if !want.Enabled && cur.IsCfsServiceEnabled {
+		add("отключить", func() error { return r.forwarding.TurnOffSelectiveRedirect(a.Id) })
+	}
+	wanted := map[string]bool{}
+	for _, rule := range want.Rules {
+		rule := rule
+		wanted[rule.Name] = true
+		c, ok := existing[rule.Name]
+		switch {
+		case !ok:
+			add("добавить правило "+describeCfs(rule), func() error {
+				_, err := r.forwarding.AddSelectiveCallRule(a.Id, rule)
+				return err
+			})
+		case c.ForwardToPhone != rule.ForwardToPhone || c.Schedule.Effective() != rule.Schedule.Effective() || !sameSet(c.PhoneList, rule.PhoneList):
+			add("изменить правило "+describeCfs(rule), func() error { return r.forwarding.UpdateSelectiveCallRule(a.Id, c.Id, rule) })
+		}
+	}
+	for _, c := range cur.RuleList {
+		c := c
+		if !wanted[c.Name] {
+			add("удалить правило "+c.Name, func() error { return r.forwarding.DeleteSelectiveCallRule(a.Id, c.Id) })
+		}
+	}
+	if want.Enabled && !cur.IsCfsServiceEnabled {
+		add("включить", func() error { return r.forwarding.TurnOnSelectiveRedirect(a.Id) })
+	}
+	return changes, nil
+}
+
+func (r *Reconciler) planBwl(a Abonent) ([]Change, error) {
+	want := a.Bwl
+	if want == nil {
+		return nil, nil
+	}
+	cur, err := r.bwl.IncCallRules(a.Id)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	mode, _ := bwlMode(want.Mode)
+	if mode == bwl.OFF && cur.Status != bwl.OFF {
+		changes = append(changes, Change{Abonent: a.Id, Setting: "bwl", Description: "отключить",
+			apply: func() error { return r.bwl.TurnOffSelectiveReceiveRule(a.Id) }})
+	}
+	plan := bwl.Diff(cur.Rules(), want.Rules)
+	if !plan.Empty() {
+		changes = append(changes, Change{Abonent: a.Id, Setting: "bwl", Description: describeBwl(plan),
+			apply: func() error { return r.bwl.ApplyPlan(a.Id, plan) }})
+	}
+	if mode.Valid() && mode != bwl.OFF && cur.Status != mode {
+		t := beelineapi.BLACK_LIST
+		if mode == bwl.WHITE_LIST_ON {
+			t = beelineapi.WHITE_LIST
+		}
+		changes = append(changes, Change{Abonent: a.Id, Setting: "bwl", Description: "включить " + t.String(),
+			apply: func() error { return r.bwl.TurnOnSelectiveCallReceive(a.Id, t) }})
+	}
+	return changes, nil
+}
+
+// planIcr Сравнивает правила индивидуальной переадресации на добавочный номер абонента с желаемыми
+func (r *Reconciler) planIcr(a Abonent, routes []icr.IcrRouteRule) ([]Change, error) {
+	if a.Icr == nil {
+		return nil, nil
+	}
+	ab, err := r.abonents.GetAbonent(a.Id)
+	if err != nil {
+		return nil, err
+	}
+	if ab.Extension == "" {
+		return nil, beelineapi.WrapError{Msg: "У абонента нет добавочного номера для индивидуальной переадресации"}
+	}
+	current := []icr.IcrRouteRule{}
+	for _, rule := range routes {
+		if rule.Extension == ab.Extension {
+			current = append(current, rule)
+		}
+	}
+	desired := []icr.IcrRouteRule{}
+	for _, n := range *a.Icr {
+		desired = append(desired, icr.IcrRouteRule{InboundNumber: n, Extension: ab.Extension})
+	}
+	add, remove := icr.Diff(current, desired)
+	changes := []Change{}
+	if len(remove) > 0 {
+		changes = append(changes, Change{Abonent: a.Id, Setting: "icr", Description: "удалить " + describeRoutes(remove),
+			apply: func() error { return routeResult(r.icr.DeleteRedirectRulesList(remove)) }})
+	}
+	if len(add) > 0 {
+		changes = append(changes, Change{Abonent: a.Id, Setting: "icr", Description: "добавить " + describeRoutes(add),
+			apply: func() error { return routeResult(r.icr.UnionRedirectRulesList(add)) }})
+	}
+	return changes, nil
+}
+
+// routeResult Возвращает ошибку запроса или ошибку по правилам, которые сервер не смог применить
+func routeResult(res []icr.IcrRouteResult, err error) error {
+	if err != nil {
+		return err
+	}
+	return icr.Failed(res)
+}
+
+// bwlMode Возвращает статус выборочного приема звонков по режиму из конфигурации
+// или 0, если режим не задан и не должен изменяться
+func bwlMode(mode string) (beelineapi.BwlStatus, error) {
+	switch strings.ToUpper(mode) {
+	case "":
+		return 0, nil
+	case "BLACK_LIST":
+		return bwl.BLACK_LIST_ON, nil
+	case "WHITE_LIST":
+		return bwl.WHITE_LIST_ON, nil
+	case "OFF":
+		return bwl.OFF, nil
+	}
+	return 0, beelineapi.WrapError{Msg: fmt.Sprintf("Неверный режим выборочного приема звонков %q. Допустимые значения: BLACK_LIST, WHITE_LIST, OFF", mode)}
+}
+
+// describeRedirect Возвращает описание номеров базовой переадресации
+func describeRedirect(br forwarding.BasicRedirect) string {
+	parts := []string{}
+	for _, p := range []struct{ name, phone string }{
+		{"все", br.ForwardAllCallsPhone},
+		{"занят", br.ForwardBusyPhone},
+		{"недоступен", br.ForwardUnavailablePhone},
+		{"не отвечает", br.ForwardNotAnswerPhone},
+	} {
+		if p.phone != "" {
+			parts = append(parts, p.name+" -> "+p.phone)
+		}
+	}
+	if br.ForwardNotAnswerPhone != "" {
+		parts = append(parts, fmt.Sprintf("гудков %d", br.ForwardNotAnswerTimeout))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// describeCfs Возвращает описание правила выборочной переадресации
+func describeCfs(rule forwarding.CfsRuleUpdate) string {
+	return fmt.Sprintf("%s (%s -> %s, %s)", rule.Name, strings.Join(rule.PhoneList, ","), rule.ForwardToPhone, rule.Schedule.Effective())
+}
+
+// describeBwl Возвращает описание изменений правил выборочного приема звонков
+func describeBwl(p bwl.Plan) string {
+	parts := []string{}
+	for _, g := range []struct {
+		verb  string
+		rules []bwl.ListRule
+	}{{"добавить", p.Add}, {"изменить", p.Update}, {"удалить", p.Delete}} {
+		for _, rule := range g.rules {
+			parts = append(parts, fmt.Sprintf("%s правило %s %s (%s, %s)", g.verb, rule.Type, rule.Name, strings.Join(rule.PhoneList, ","), rule.Schedule.Effective()))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeRoutes Возвращает описание правил индивидуальной переадресации
+func describeRoutes(rules []icr.IcrRouteRule) string {
+	parts := []string{}
+	for _, rule := range rules {
+		parts = append(parts, rule.InboundNumber+" -> "+rule.Extension)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sameSet Сравнивает списки номеров без учета порядка
+func sameSet(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
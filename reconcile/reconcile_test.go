@@ -0,0 +1,113 @@
+package reconcile_test
+
+import (
+	"strings"
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/icr"
+	"github.com/taigasys/beeline-portal-api/reconcile"
+)
+
+const testConfig = `
+abonents:
+  - id: "101"
+    recording: true
+    forwarding:
+      enabled: true
+      forwardBusyPhone: "9000000002"
+    selectiveForwarding:
+      enabled: true
+      rules:
+        - name: boss
+          forwardToPhone: "9000000003"
+          schedule: WORKING_TIME
+          phoneList: ["9000000004"]
+    bwl:
+      mode: BLACK_LIST
+      rules:
+        - type: BLACK_LIST
+          name: spam
+          phoneList: ["9000000005", "9000000006"]
+    icr: ["4950000001"]
+`
+
+// TestReconcile Тест на построение плана, применение изменений и повторное сравнение без изменений
+func TestReconcile(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Extension: "101"})
+	s.AddNumber(icr.NumberInfo{NumberId: "n1", Phone: "4950000001"})
+	c, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if _, err := icr.New(c).TurnOnCustomIncNumRedirect([]string{"4950000001"}); err != nil {
+		t.Fatalf("Не удалось включить индивидуальную переадресацию: %s", err)
+	}
+	if _, err := bwl.New(c).AddIncCallRule("u1", bwl.BwlRuleAdd{Type: beelineapi.BLACK_LIST, Rule: bwl.BwlRuleUpdate{Name: "old", PhoneList: []string{"9000000009"}}}); err != nil {
+		t.Fatalf("Не удалось добавить правило: %s", err)
+	}
+
+	cfg, err := reconcile.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Не удалось разобрать конфигурацию: %s", err)
+	}
+	r := reconcile.New(c)
+	plan, err := r.Plan(cfg)
+	if err != nil {
+		t.Fatalf("Не удалось построить план: %s", err)
+	}
+	settings := map[string]bool{}
+	for _, ch := range plan.Changes {
+		settings[ch.Setting] = true
+	}
+	for _, want := range []string{"recording", "forwarding", "selectiveForwarding", "bwl", "icr"} {
+		if !settings[want] {
+			t.Fatalf("В плане нет изменений настройки %s:\n%s", want, plan)
+		}
+	}
+	if !strings.Contains(plan.String(), "удалить правило BLACK_LIST old") {
+		t.Fatalf("В плане нет удаления лишнего правила:\n%s", plan)
+	}
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("Не удалось применить план: %s", err)
+	}
+
+	if st, _ := abonents.New(c).GetRecordingStatus("101"); st != beelineapi.ON {
+		t.Fatal("Запись разговоров не включена")
+	}
+	if br, _ := forwarding.New(c).GetBasicRedirectStatus("101"); br.Status != beelineapi.ON || br.Forward.ForwardBusyPhone != "9000000002" {
+		t.Fatalf("Базовая переадресация не включена: %+v", br)
+	}
+	if routes, _ := icr.New(c).GetRedirectRulesList(); len(routes) != 1 || routes[0].Extension != "101" {
+		t.Fatalf("Правило индивидуальной переадресации не добавлено: %+v", routes)
+	}
+	plan, err = r.Plan(cfg)
+	if err != nil || !plan.Empty() {
+		t.Fatalf("После применения остались изменения: %v\n%s", err, plan)
+	}
+}
+
+// TestParse Тест на разбор конфигурации в формате JSON и отклонение ошибок
+func TestParse(t *testing.T) {
+	cfg, err := reconcile.Parse([]byte(`{"abonents":[{"id":"u1","recording":false}]}`))
+	if err != nil || len(cfg.Abonents) != 1 || cfg.Abonents[0].Recording == nil || *cfg.Abonents[0].Recording {
+		t.Fatalf("Неверно разобрана конфигурация JSON: %v %+v", err, cfg)
+	}
+	for _, bad := range []string{
+		`{"abonents":[{"id":"u1","recordng":true}]}`,
+		`{"abonents":[{"id":"u1"},{"id":"u1"}]}`,
+		`{"abonents":[{"recording":true}]}`,
+		`{"abonents":[{"id":"u1","bwl":{"mode":"GREY_LIST"}}]}`,
+		`{"abonents":[{"id":"u1","selectiveForwarding":{"rules":[{"name":"x","schedule":"SOMETIMES"}]}}]}`,
+	} {
+		if _, err := reconcile.Parse([]byte(bad)); err == nil {
+			t.Fatalf("Ожидалась ошибка для конфигурации %s", bad)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package records
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// AudioFormat Формат файла записи разговора
+type AudioFormat int
+
+const (
+	UNKNOWN_FORMAT AudioFormat = iota // Формат не распознан
+	MP3                               // MPEG Audio Layer III
+	WAV                               // RIFF WAVE
+)
+
+var audioFormatNames = []string{"UNKNOWN", "MP3", "WAV"}
+
+// String Возвращает название формата
+func (f AudioFormat) String() string {
+	if f < 0 || int(f) >= len(audioFormatNames) {
+		return fmt.Sprintf("AudioFormat(%d)", int(f))
+	}
+	return audioFormatNames[f]
+}
+
+// Ext Возвращает расширение файла для формата с точкой или пустую строку для нераспознанного формата
+func (f AudioFormat) Ext() string {
+	switch f {
+	case MP3:
+		return ".mp3"
+	case WAV:
+		return ".wav"
+	}
+	return ""
+}
+
+// DetectFormat Определяет формат файла записи по первым байтам data, а если они не распознаны -
+// по заголовку Content-Type ответа сервера
+func DetectFormat(contentType string, data []byte) AudioFormat {
+	switch {
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return WAV
+	case len(data) >= 3 && string(data[:3]) == "ID3":
+		return MP3
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		return MP3
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return UNKNOWN_FORMAT
+	}
+	switch strings.ToLower(mt) {
+	case "audio/mpeg", "audio/mp3", "audio/mpeg3", "audio/x-mpeg-3":
+		return MP3
+	case "audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave":
+		return WAV
+	}
+	return UNKNOWN_FORMAT
+}
+
+// RecordFile Файл записи разговора с распознанным форматом
+type RecordFile struct {
+	Data        []byte      // Содержимое файла
+	ContentType string      // Заголовок Content-Type ответа сервера
+	Format      AudioFormat // Формат, определенный по содержимому и Content-Type
+}
+
+// Reader Возвращает поток для чтения содержимого файла
+func (f RecordFile) Reader() io.Reader {
+	return bytes.NewReader(f.Data)
+}
+
+// Duration Возвращает длительность записи по заголовкам файла
+func (f RecordFile) Duration() (time.Duration, error) {
+	switch f.Format {
+	case WAV:
+		h, err := ParseWAV(bytes.NewReader(f.Data))
+		if err != nil {
+			return 0, err
+		}
+		return h.Duration(), nil
+	case MP3:
+		return MP3Duration(f.Data)
+	}
+	return 0, beelineapi.WrapError{Msg: "Не удалось определить длительность записи: формат файла не распознан"}
+}
+
+// Check Проверяет, что формат файла распознан, а длительность по заголовкам файла
+// отличается от длительности разговора rec.Duration не более чем на tolerance
+func (f RecordFile) Check(rec CallRecord, tolerance time.Duration) error {
+	d, err := f.Duration()
+	if err != nil {
+		return beelineapi.Wrap("Файл записи "+rec.Id+" поврежден. ", err)
+	}
+	want := time.Duration(rec.Duration) * time.Millisecond
+	if diff := d - want; diff > tolerance || -diff > tolerance {
+		return beelineapi.WrapError{Msg: fmt.Sprintf("Длительность файла записи %s %s не совпадает с длительностью разговора %s", rec.Id, d, want)}
+	}
+	return nil
+}
+
+// DownloadRecord Возвращает файл записи разговора с распознанным форматом
+// id - Идентификатор записи
+func (s *Service) DownloadRecord(id string) (RecordFile, error) {
+	body, h, err := s.c.RequestWithHeader("GET", recordPath(id)+"/download", nil)
+	if err != nil {
+		return RecordFile{}, recordError(err, "Ошибка при получении файла записи разговора. ", id, "")
+	}
+	return RecordFile{Data: body, ContentType: h.Get("Content-Type"), Format: DetectFormat(h.Get("Content-Type"), body)}, nil
+}
+
+// DownloadRecordFromEvent Возвращает файл записи разговора с распознанным форматом по ID разговора и ID пользователя из события
+// id - Идентификатор разговора из события
+// userId - Идентификатор пользователя из события
+func (s *Service) DownloadRecordFromEvent(id string, userId string) (RecordFile, error) {
+	body, h, err := s.c.RequestWithHeader("GET", eventPath(id, userId)+"/download", nil)
+	if err != nil {
+		return RecordFile{}, recordError(err, "Ошибка при получении файла записи разговора из события. ", id, userId)
+	}
+	return RecordFile{Data: body, ContentType: h.Get("Content-Type"), Format: DetectFormat(h.Get("Content-Type"), body)}, nil
+}
+
+// WavHeader Параметры звука из заголовка файла WAV
+type WavHeader struct {
+	AudioFormat   uint16 // Кодирование: 1 - PCM, 6 - A-law, 7 - mu-law
+	Channels      uint16 // Количество каналов
+	SampleRate    uint32 // Частота дискретизации, Гц
+	ByteRate      uint32 // Байт в секунду
+	BlockAlign    uint16 // Байт на отсчет всех каналов
+	BitsPerSample uint16 // Бит на отсчет одного канала
+	DataSize      uint32 // Размер звуковых данных, байт
+}
+
+// Duration Возвращает длительность звуковых данных
+func (h WavHeader) Duration() time.Duration {
+	if h.ByteRate == 0 {
+		return 0
+	}
+	return time.Duration(uint64(h.DataSize) * uint64(time.Second) / uint64(h.ByteRate))
+}
+
+// ParseWAV Читает заголовок файла WAV до начала звуковых данных.
+// Блоки, отличные от fmt и data, например LIST, пропускаются
+func ParseWAV(r io.Reader) (WavHeader, error) {
+	h := WavHeader{}
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return h, beelineapi.Wrap("Ошибка при чтении заголовка WAV. ", err)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return h, beelineapi.WrapError{Msg: "Файл не является файлом WAV"}
+	}
+	hasFmt := false
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return h, beelineapi.Wrap("Ошибка при чтении заголовка WAV: не найден блок data. ", err)
+		}
+		id, size := string(chunk[:4]), binary.LittleEndian.Uint32(chunk[4:])
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return h, beelineapi.WrapError{Msg: fmt.Sprintf("Неверный размер блока fmt файла WAV: %d", size)}
+			}
+			var f [16]byte
+			if _, err := io.ReadFull(r, f[:]); err != nil {
+				return h, beelineapi.Wrap("Ошибка при чтении блока fmt файла WAV. ", err)
+			}
+			h.AudioFormat = binary.LittleEndian.Uint16(f[0:])
+			h.Channels = binary.LittleEndian.Uint16(f[2:])
+			h.SampleRate = binary.LittleEndian.Uint32(f[4:])
+			h.ByteRate = binary.LittleEndian.Uint32(f[8:])
+			h.BlockAlign = binary.LittleEndian.Uint16(f[12:])
+			h.BitsPerSample = binary.LittleEndian.Uint16(f[14:])
+			if err := skip(r, int64(size-16+size%2)); err != nil {
+				return h, err
+			}
+			hasFmt = true
+		case "data":
+			if !hasFmt {
+				return h, beelineapi.WrapError{Msg: "Блок data файла WAV расположен перед блоком fmt"}
+			}
+			if h.ByteRate == 0 || h.Channels == 0 {
+				return h, beelineapi.WrapError{Msg: "Неверные параметры звука в заголовке WAV"}
+			}
+			h.DataSize = size
+			return h, nil
+		default:
+			if err := skip(r, int64(size)+int64(size%2)); err != nil {
+				return h, err
+			}
+		}
+	}
+}
+
+// skip Пропускает n байт потока
+func skip(r io.Reader, n int64) error {
+	if _, err := io.CopyN(io.Discard, r, n); err != nil {
+		return beelineapi.Wrap("Ошибка при чтении заголовка WAV. ", err)
+	}
+	return nil
+}
+
+// Битрейты MPEG Audio, кбит/с, по индексу из заголовка кадра
+var (
+	mpeg1Bitrates = [3][16]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}, // Layer I
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},    // Layer II
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},     // Layer III
+	}
+	mpeg2Bitrates = [3][16]int{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256}, // Layer I
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},      // Layer II
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},      // Layer III
+	}
+	mpegSampleRates = map[int][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// MP3Duration Возвращает длительность MP3 по первому кадру: по количеству кадров из заголовка Xing, Info или VBRI,
+// а если его нет - по битрейту первого кадра, считая, что битрейт постоянный
+func MP3Duration(data []byte) (time.Duration, error) {
+	start := 0
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+		start = 10 + size
+		if data[5]&0x10 != 0 {
+			start += 10
+		}
+	}
+	end := len(data)
+	if end-start >= 128 && string(data[end-128:end-125]) == "TAG" {
+		end -= 128
+	}
+	// Пропуск мусора между тегом и первым кадром
+	for start+4 <= end && !(data[start] == 0xFF && data[start+1]&0xE0 == 0xE0) {
+		start++
+	}
+	if start+4 > end {
+		return 0, beelineapi.WrapError{Msg: "В файле MP3 не найден заголовок кадра"}
+	}
+	h := data[start : start+4]
+	version := int(h[1]>>3) & 3
+	layer := 3 - int(h[1]>>1)&3 // 0 - Layer I, 1 - Layer II, 2 - Layer III
+	bitrateIdx, rateIdx := int(h[2]>>4), int(h[2]>>2)&3
+	rates, ok := mpegSampleRates[version]
+	if !ok || layer < 0 || layer > 2 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return 0, beelineapi.WrapError{Msg: "Неверный заголовок кадра MP3"}
+	}
+	sampleRate := rates[rateIdx]
+	bitrate := mpeg2Bitrates[layer][bitrateIdx]
+	if version == 3 {
+		bitrate = mpeg1Bitrates[layer][bitrateIdx]
+	}
+	samples := 1152
+	switch {
+	case layer == 0:
+		samples = 384
+	case layer == 2 && version != 3:
+		samples = 576
+	}
+	mono := h[3]>>6 == 3
+	if frames := vbrFrames(data[start:end], version == 3, mono); frames > 0 {
+		return time.Duration(int64(frames) * int64(samples) * int64(time.Second) / int64(sampleRate)), nil
+	}
+	return time.Duration(int64(end-start) * 8 * int64(time.Second) / int64(bitrate*1000)), nil
+}
+
+// vbrFrames Возвращает количество кадров из заголовка Xing, Info или VBRI в первом кадре или 0, если заголовка нет
+func vbrFrames(frame []byte, mpeg1 bool, mono bool) int {
+	offset := 4 + 17
+	switch {
+	case mpeg1 && !mono:
+		offset = 4 + 32
+	case !mpeg1 && mono:
+		offset = 4 + 9
+	}
+	if len(frame) >= offset+12 {
+		if tag := string(frame[offset : offset+4]); tag == "Xing" || tag == "Info" {
+			flags := binary.BigEndian.Uint32(frame[offset+4:])
+			if flags&1 != 0 {
+				return int(binary.BigEndian.Uint32(frame[offset+8:]))
+			}
+		}
+	}
+	if len(frame) >= 4+32+18 && string(frame[4+32:4+36]) == "VBRI" {
+		return int(binary.BigEndian.Uint32(frame[4+32+14:]))
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package records
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// DefaultDateFormat Формат даты разговора в выгрузке по умолчанию
+const DefaultDateFormat = "2006-01-02 15:04:05"
+
+// Column Столбец выгрузки записей разговоров
+type Column struct {
+	Name   string // Имя столбца: ключ в JSON Lines и имя для ExportOptions.Columns
+	Header string // Заголовок столбца в CSV и XLSX
+	value  func(r CallRecord, o ExportOptions) interface{}
+}
+
+// Columns Все столбцы выгрузки. Поля абонента выводятся отдельными столбцами с префиксом abonent.
+var Columns = []Column{
+	{"id", "ID записи", func(r CallRecord, o ExportOptions) interface{} { return r.Id }},
+	{"externalId", "Внешний ID", func(r CallRecord, o ExportOptions) interface{} { return r.ExternalId }},
+	{"date", "Дата и время", func(r CallRecord, o ExportOptions) interface{} { return r.Date.In(o.location()).Format(o.dateFormat()) }},
+	{"direction", "Направление", func(r CallRecord, o ExportOptions) interface{} { return r.Direction.String() }},
+	{"phone", "Телефон", func(r CallRecord, o ExportOptions) interface{} { return r.Phone }},
+	{"duration", "Длительность, с", func(r CallRecord, o ExportOptions) interface{} { return (r.Duration + 500) / 1000 }},
+	{"fileSize", "Размер файла, байт", func(r CallRecord, o ExportOptions) interface{} { return r.FileSize }},
+	{"comment", "Комментарий", func(r CallRecord, o ExportOptions) interface{} { return r.Comment }},
+	{"abonent.userId", "ID абонента", func(r CallRecord, o ExportOptions) interface{} { return r.Abonent.UserId }},
+	{"abonent.name", "Абонент", func(r CallRecord, o ExportOptions) interface{} {
+		return strings.TrimSpace(r.Abonent.LastName + " " + r.Abonent.FirstName)
+	}},
+	{"abonent.phone", "Мобильный номер абонента", func(r CallRecord, o ExportOptions) interface{} { return r.Abonent.Phone }},
+	{"abonent.extension", "Добавочный номер", func(r CallRecord, o ExportOptions) interface{} { return r.Abonent.Extension }},
+	{"abonent.email", "Email абонента", func(r CallRecord, o ExportOptions) interface{} { return r.Abonent.Email }},
+	{"abonent.department", "Отдел", func(r CallRecord, o ExportOptions) interface{} { return r.Abonent.Department }},
+}
+
+// DefaultColumns Столбцы выгрузки по умолчанию
+var DefaultColumns = []string{"date", "direction", "phone", "duration", "abonent.name", "abonent.extension", "abonent.department", "comment"}
+
+// ExportOptions Параметры выгрузки записей разговоров
+type ExportOptions struct {
+	Columns    []string       // Имена столбцов из Columns в порядке вывода, по умолчанию DefaultColumns
+	Location   *time.Location // Часовой пояс даты разговора, по умолчанию time.Local
+	DateFormat string         // Формат даты разговора, по умолчанию DefaultDateFormat
+	Comma      rune           // Разделитель CSV, по умолчанию ","
+	BOM        bool           // Начинать CSV с метки порядка байтов UTF-8, чтобы Excel распознал кодировку
+}
+
+// location Возвращает часовой пояс выгрузки
+func (o ExportOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+// dateFormat Возвращает формат даты выгрузки
+func (o ExportOptions) dateFormat() string {
+	if o.DateFormat == "" {
+		return DefaultDateFormat
+	}
+	return o.DateFormat
+}
+
+// columns Возвращает столбцы выгрузки по именам из параметров
+func (o ExportOptions) columns() ([]Column, error) {
+	names := o.Columns
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	res := make([]Column, 0, len(names))
+	for _, n := range names {
+		found := false
+		for _, c := range Columns {
+			if strings.EqualFold(c.Name, n) {
+				res = append(res, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, beelineapi.WrapError{Msg: fmt.Sprintf("Неизвестный столбец выгрузки %q", n)}
+		}
+	}
+	return res, nil
+}
+
+// WriteCSV Записывает записи разговоров в формате CSV с заголовками столбцов на русском языке
+func WriteCSV(w io.Writer, recs []CallRecord, o ExportOptions) error {
+	cols, err := o.columns()
+	if err != nil {
+		return err
+	}
+	if o.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return beelineapi.Wrap("Ошибка при выгрузке записей в CSV. ", err)
+		}
+	}
+	cw := csv.NewWriter(w)
+	if o.Comma != 0 {
+		cw.Comma = o.Comma
+	}
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		row[i] = c.Header
+	}
+	cw.Write(row)
+	for _, r := range recs {
+		for i, c := range cols {
+			row[i] = fmt.Sprint(c.value(r, o))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return beelineapi.Wrap("Ошибка при выгрузке записей в CSV. ", err)
+	}
+	return nil
+}
+
+// WriteJSONL Записывает записи разговоров в формате JSON Lines: по объекту на строку с ключами - именами столбцов
+func WriteJSONL(w io.Writer, recs []CallRecord, o ExportOptions) error {
+	cols, err := o.columns()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for _, r := range recs {
+		obj := make(map[string]interface{}, len(cols))
+		for _, c := range cols {
+			obj[c.Name] = c.value(r, o)
+		}
+		if err := enc.Encode(obj); err != nil {
+			return beelineapi.Wrap("Ошибка при выгрузке записей в JSON Lines. ", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package records
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Digest Размер и контрольная сумма SHA-256 файла записи разговора
+type Digest struct {
+	Size   int64  `json:"size"`   // Размер, байт
+	SHA256 string `json:"sha256"` // Контрольная сумма SHA-256 в шестнадцатеричном виде
+}
+
+// IntegrityError Файл записи разговора не совпадает с ожидаемым размером или контрольной суммой
+type IntegrityError struct {
+	Id       string // Идентификатор записи
+	Expected Digest // Ожидаемые значения. Пустые поля не проверялись
+	Actual   Digest // Фактические значения
+}
+
+func (e IntegrityError) Error() string {
+	if e.Expected.SHA256 != "" && e.Expected.SHA256 != e.Actual.SHA256 {
+		return fmt.Sprintf("Контрольная сумма файла записи %s не совпадает: ожидалась %s, получена %s", e.Id, e.Expected.SHA256, e.Actual.SHA256)
+	}
+	return fmt.Sprintf("Размер файла записи %s не совпадает: ожидалось %d байт, получено %d", e.Id, e.Expected.Size, e.Actual.Size)
+}
+
+// HashingReader Поток, считающий размер и контрольную сумму SHA-256 прочитанных данных.
+// Создается функцией NewHashingReader.
+type HashingReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+// NewHashingReader Возвращает поток, читающий данные из r и считающий их контрольную сумму
+func NewHashingReader(r io.Reader) *HashingReader {
+	return &HashingReader{r: r, h: sha256.New()}
+}
+
+// Read Читает данные из исходного потока, добавляя их к контрольной сумме
+func (hr *HashingReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.h.Write(p[:n])
+	hr.n += int64(n)
+	return n, err
+}
+
+// Digest Возвращает размер и контрольную сумму данных, прочитанных к этому моменту
+func (hr *HashingReader) Digest() Digest {
+	return Digest{Size: hr.n, SHA256: hex.EncodeToString(hr.h.Sum(nil))}
+}
+
+// Check Сравнивает фактические размер и контрольную сумму d с ожидаемыми expected.
+// Пустые поля expected не проверяются
+func (d Digest) Check(id string, expected Digest) error {
+	if expected.Size > 0 && expected.Size != d.Size || expected.SHA256 != "" && expected.SHA256 != d.SHA256 {
+		return IntegrityError{Id: id, Expected: expected, Actual: d}
+	}
+	return nil
+}
+
+// FileDigest Возвращает размер и контрольную сумму файла path
+func FileDigest(path string) (Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digest{}, beelineapi.Wrap("Ошибка при чтении файла записи. ", err)
+	}
+	defer f.Close()
+	hr := NewHashingReader(f)
+	if _, err := io.Copy(io.Discard, hr); err != nil {
+		return Digest{}, beelineapi.Wrap("Ошибка при чтении файла записи. ", err)
+	}
+	return hr.Digest(), nil
+}
+
+// DownloadTo Записывает файл записи разговора rec в w по мере получения, не загружая его в память целиком,
+// считает контрольную сумму и проверяет, что размер файла совпадает с rec.FileSize, если он известен.
+// При несовпадении возвращается IntegrityError, при этом данные уже записаны в w
+func (s *Service) DownloadTo(rec CallRecord, w io.Writer) (Digest, error) {
+	body, _, err := s.c.RequestStream(context.Background(), "GET", recordPath(rec.Id)+"/download")
+	if err != nil {
+		return Digest{}, recordError(err, "Ошибка при получении файла записи разговора. ", rec.Id, "")
+	}
+	defer body.Close()
+	hr := NewHashingReader(body)
+	if _, err := io.Copy(w, hr); err != nil {
+		return hr.Digest(), beelineapi.Wrap("Ошибка при сохранении файла записи разговора. ", err)
+	}
+	d := hr.Digest()
+	return d, d.Check(rec.Id, Digest{Size: int64(rec.FileSize)})
+}
@@ -0,0 +1,289 @@
+// Package records содержит операции с записями разговоров облачной АТС Билайн
+// и выгрузку списков записей в CSV, JSON Lines и XLSX
+package records
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+)
+
+// CallRecord структура хранения подробной информации об отдельной записи
+type CallRecord struct {
+	Id         string               `json:"id"`         //Идентификатор записи
+	ExternalId string               `json:"externalId"` //Внешний идентификатор записи
+	Phone      string               `json:"phone"`      //Мобильный номер абонента
+	Direction  beelineapi.Direction `json:"direction"`  //Тип вызова
+	Date       beelineapi.UnixMilli `json:"date"`       //Дата и время разговора
+	Duration   int                  `json:"duration"`   //Длительность разговора в миллисекундах
+	FileSize   int                  `json:"fileSize"`   //Размер файла записи разговора
+	Comment    string               `json:"comment"`    //Комментарий к записи разговора
+	Abonent    abonents.Abonent     `json:"abonent"`    //Абонент
+}
+
+// RecordUpdate Запрос для изменения записи разговора. Незаполненные поля не изменяются.
+type RecordUpdate struct {
+	Comment    *string `json:"comment,omitempty"`    //Комментарий к записи разговора
+	ExternalId *string `json:"externalId,omitempty"` //Внешний идентификатор записи
+}
+
+// RecordNotFoundError Запись разговора не найдена. Запись появляется на портале
+// через некоторое время после завершения разговора, поэтому запрос можно повторить позже.
+type RecordNotFoundError struct {
+	Id     string // Идентификатор записи или разговора из события
+	UserId string // Идентификатор пользователя из события, если запись искалась по событию
+}
+
+func (e RecordNotFoundError) Error() string {
+	if e.UserId != "" {
+		return fmt.Sprintf("Запись разговора %s абонента %s не найдена", e.Id, e.UserId)
+	}
+	return fmt.Sprintf("Запись разговора %s не найдена", e.Id)
+}
+
+// Service Операции с записями разговоров
+type Service struct {
+	c *beelineapi.APIClient
+}
+
+// New Возвращает операции с записями разговоров для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{c: c}
+}
+
+// GetRecords Записи разговоров передаются по порядку начиная со следующей после переданного
+// ID или с первой записи, если ID не передан. За один запрос передаётся не более чем 100 записей.
+// id - Начальный ID записи
+func (s *Service) GetRecords(id int64) ([]CallRecord, error) {
+	path := "records"
+	if id > 0 {
+		path = fmt.Sprintf("v2/records/%d", id)
+	}
+	recs := []CallRecord{}
+	if err := s.c.RequestJSON("GET", path, nil, &recs); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// ForEachRecord Обходит все записи разговоров начиная со следующей после переданного ID,
+// запрашивая их постранично через GetRecords. Обход прекращается при первой ошибке fn.
+// id - Начальный ID записи
+// fn - Функция, вызываемая для каждой записи
+func (s *Service) ForEachRecord(id int64, fn func(CallRecord) error) error {
+	for {
+		recs, err := s.GetRecords(id)
+		if err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			return nil
+		}
+		for _, r := range recs {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		last := recs[len(recs)-1].Id
+		next, err := strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return beelineapi.WrapError{Msg: fmt.Sprintf("Ошибка при переходе к следующей странице записей. Неверный ID записи %q", last)}
+		}
+		// Сервер вернул ту же страницу - дальше записей нет
+		if next <= id {
+			return nil
+		}
+		id = next
+	}
+}
+
+// ForEachRecordAfter Обходит все записи разговоров начиная со следующей после записи с идентификатором id
+// или с первой записи, если id пустой. Следующая страница запрашивается по идентификатору последней записи
+// без преобразования в число, поэтому обход не прерывается на записях с нечисловым ID.
+// Обход прекращается при первой ошибке fn.
+// id - Идентификатор записи, после которой начинается обход
+// fn - Функция, вызываемая для каждой записи
+func (s *Service) ForEachRecordAfter(id string, fn func(CallRecord) error) error {
+	for {
+		path := "records"
+		if id != "" {
+			path = recordPath(id)
+		}
+		recs := []CallRecord{}
+		if err := s.c.RequestJSON("GET", path, nil, &recs); err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			return nil
+		}
+		for _, r := range recs {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		last := recs[len(recs)-1].Id
+		// Сервер вернул ту же страницу - дальше записей нет
+		if last == id || last == "" {
+			return nil
+		}
+		id = last
+	}
+}
+
+// UpdateRecord Изменяет комментарий и/или внешний идентификатор записи разговора.
+// id - Идентификатор записи разговора
+// upd - Запрос для изменения записи
+func (s *Service) UpdateRecord(id string, upd RecordUpdate) error {
+	if _, err := s.c.Request("PUT", recordPath(id), upd); err != nil {
+		return recordError(err, "Ошибка при изменении записи разговора. ", id, "")
+	}
+	return nil
+}
+
+// SetRecordComment Устанавливает комментарий к записи разговора
+// id - Идентификатор записи разговора
+// comment - Комментарий
+func (s *Service) SetRecordComment(id string, comment string) error {
+	return s.UpdateRecord(id, RecordUpdate{Comment: &comment})
+}
+
+// SetRecordExternalId Устанавливает внешний идентификатор записи разговора, например номер заявки в CRM
+// id - Идентификатор записи разговора
+// externalId - Внешний идентификатор
+func (s *Service) SetRecordExternalId(id string, externalId string) error {
+	return s.UpdateRecord(id, RecordUpdate{ExternalId: &externalId})
+}
+
+// FindRecordsByExternalId Возвращает все записи разговоров с указанным внешним идентификатором.
+// API не поддерживает поиск по внешнему идентификатору, поэтому при каждом вызове запрашивается вся история записей
+// постранично. Если известна запись, раньше которой искомых нет, используйте FindRecordsByExternalIdAfter.
+// externalId - Внешний идентификатор записи
+func (s *Service) FindRecordsByExternalId(externalId string) ([]CallRecord, error) {
+	return s.FindRecordsByExternalIdAfter(externalId, "")
+}
+
+// FindRecordsByExternalIdAfter Возвращает записи разговоров с указанным внешним идентификатором,
+// просматривая только записи после записи с идентификатором afterId
+// externalId - Внешний идентификатор записи
+// afterId - Идентификатор записи, после которой начинается поиск, или пустая строка для поиска по всей истории
+func (s *Service) FindRecordsByExternalIdAfter(externalId string, afterId string) ([]CallRecord, error) {
+	recs := []CallRecord{}
+	err := s.ForEachRecordAfter(afterId, func(r CallRecord) error {
+		if r.ExternalId == externalId {
+			recs = append(recs, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// DeleteRecord Удаляет запись разговора по уникальному идентификатору записи recordId.
+// id - Идентификатор записи разговора
+func (s *Service) DeleteRecord(id string) error {
+	if _, err := s.c.Request("DELETE", recordPath(id), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при удалении записи с сервера Билайн. ", err)
+	}
+	return nil
+}
+
+// GetRecordInfo Возвращает запись разговора по уникальному идентификатору записи recordId.
+// На запрос GET v2/records/{id} портал отвечает либо самой записью, либо, как для GetRecords,
+// страницей записей, следующих после {id}. Во втором случае запись ищется в начале страницы,
+// следующей после предыдущего ID, поэтому запись с нечисловым ID так найти нельзя.
+// id - Идентификатор записи разговора
+func (s *Service) GetRecordInfo(id string) (CallRecord, error) {
+	rec := CallRecord{}
+	var raw json.RawMessage
+	if err := s.c.RequestJSON("GET", recordPath(id), nil, &raw); err != nil {
+		return rec, recordError(err, "Ошибка при получении информации о записи разговора. ", id, "")
+	}
+	if b := bytes.TrimSpace(raw); len(b) > 0 && b[0] == '{' {
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return rec, beelineapi.Wrap("Ошибка при разборе информации о записи разговора. ", err)
+		}
+		return rec, nil
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || n <= 0 {
+		return rec, beelineapi.WrapError{Msg: "Портал вернул список записей вместо записи " + id + ", а найти запись в списке можно только по числовому ID"}
+	}
+	recs, err := s.GetRecords(n - 1)
+	if err != nil {
+		return rec, beelineapi.Wrap("Ошибка при получении информации о записи разговора. ", err)
+	}
+	if len(recs) == 0 || recs[0].Id != id {
+		return rec, RecordNotFoundError{Id: id}
+	}
+	return recs[0], nil
+}
+
+// GetRecordInfoFromEvent Возвращает запись разговора по ID разговора из события и ID пользователя из того же события.
+// id - Идентификатор разговора из события
+// userId - Идентификатор пользователя из события
+func (s *Service) GetRecordInfoFromEvent(id string, userId string) (CallRecord, error) {
+	return s.recordInfoFromEvent(context.Background(), id, userId)
+}
+
+func (s *Service) recordInfoFromEvent(ctx context.Context, id string, userId string) (CallRecord, error) {
+	rec := CallRecord{}
+	if err := s.c.RequestJSONContext(ctx, "GET", eventPath(id, userId), nil, &rec); err != nil {
+		return rec, recordError(err, "Ошибка при получении информации о записи разговора из события. ", id, userId)
+	}
+	return rec, nil
+}
+
+// GetRecordFile Возвращает файл записи разговора по уникальному идентификатору записи recordId
+// id - Идентификатор записи разговора
+func (s *Service) GetRecordFile(id string) (io.Reader, error) {
+	return s.recordFile(context.Background(), id)
+}
+
+func (s *Service) recordFile(ctx context.Context, id string) (io.Reader, error) {
+	var r io.Reader
+	body, err := s.c.RequestContext(ctx, "GET", recordPath(id)+"/download", nil)
+	if err != nil {
+		return nil, recordError(err, "Ошибка при подготовке запроса на получение информации о записях разговоров. ", id, "")
+	}
+	r = bytes.NewReader(body)
+	return r, nil
+}
+
+// GetRecordFileFromEvent Возвращает файл записи разговора по ID разговора из события и ID пользователя из того же события.
+// id - Идентификатор разговора из события
+// userId - Идентификатор пользователя из события
+func (s *Service) GetRecordFileFromEvent(id string, userId string) (io.Reader, error) {
+	body, err := s.c.Request("GET", eventPath(id, userId)+"/download", nil)
+	if err != nil {
+		return nil, recordError(err, "Ошибка при получении файла записи разговора из события. ", id, userId)
+	}
+	return bytes.NewReader(body), nil
+}
+
+// recordPath Возвращает путь к записи разговора по ее идентификатору
+func recordPath(id string) string {
+	return "v2/records/" + url.PathEscape(id)
+}
+
+// eventPath Возвращает путь к записи разговора по ID разговора и ID пользователя из события
+func eventPath(id string, userId string) string {
+	return "records/" + url.PathEscape(id) + "/" + url.PathEscape(userId)
+}
+
+// recordError Возвращает RecordNotFoundError, если сервер ответил, что запись не найдена,
+// иначе оборачивает ошибку сообщением msg
+func recordError(err error, msg string, id string, userId string) error {
+	if beelineapi.IsNotFound(err) {
+		return RecordNotFoundError{Id: id, UserId: userId}
+	}
+	return beelineapi.Wrap(msg, err)
+}
@@ -0,0 +1,561 @@
+package records_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// update Перезаписывает эталонные файлы в testdata результатами тестов
+var update = flag.Bool("update", false, "перезаписать эталонные файлы в testdata")
+
+// readFixture Читает файл из testdata
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Не удалось прочитать файл %s: %s", name, err)
+	}
+	return b
+}
+
+// checkGolden Сравнивает got с эталонным файлом из testdata или перезаписывает его с флагом -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Не удалось записать эталонный файл %s: %s", name, err)
+		}
+	}
+	want := readFixture(t, name)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Результат не совпадает с эталоном %s. Ожидалось:\n%s\nполучено:\n%s", name, want, got)
+	}
+}
+
+// newServer Возвращает имитатор портала и операции с записями разговоров для тестов
+func newServer(t *testing.T) (*beelinetest.Server, *records.Service) {
+	t.Helper()
+	s := beelinetest.NewServer("token")
+	t.Cleanup(s.Close)
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	return s, records.New(client)
+}
+
+// TestGetRecords Тест на получение информации о записях
+func TestGetRecords(t *testing.T) {
+	s, client := newServer(t)
+	for _, r := range fixtureRecords(t) {
+		s.AddRecord(r, "", nil)
+	}
+	recs, err := client.GetRecords(0)
+	if err != nil {
+		t.Fatalf("Не удалось получить инфо о записях: %s", err)
+	}
+	var out bytes.Buffer
+	for _, r := range recs {
+		fmt.Fprintf(&out, "%s %s %s %s %d %d %q %q %s/%s/%s\n", r.Id, r.Date.UTC().Format(time.RFC3339), r.Direction, r.Phone,
+			r.Duration, r.FileSize, r.ExternalId, r.Comment, r.Abonent.UserId, r.Abonent.Extension, r.Abonent.Department)
+	}
+	checkGolden(t, "records.golden", out.Bytes())
+}
+
+// TestGetWavFileFromServer Тест на получение файла с сервера
+func TestGetWavFileFromServer(t *testing.T) {
+	s, client := newServer(t)
+	wav := readFixture(t, "test.wav")
+	s.AddRecord(records.CallRecord{Id: "1001"}, "", wav)
+
+	reader, err := client.GetRecordFile("1001")
+	if err != nil {
+		t.Fatalf("Не удалось получить файл записи: %s", err)
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Не удалось считать данные из потока с файлом записи: %s", err)
+	}
+	if !bytes.Equal(content, wav) {
+		t.Fatalf("Содержимое файла записи не совпадает: получено %d байт вместо %d", len(content), len(wav))
+	}
+
+	f, err := client.DownloadRecord("1001")
+	if err != nil {
+		t.Fatalf("Не удалось получить файл записи: %s", err)
+	}
+	if f.Format != records.WAV || f.Format.Ext() != ".wav" {
+		t.Fatalf("Неверный формат файла записи: %s", f.Format)
+	}
+	if d, err := f.Duration(); err != nil || d != 100*time.Millisecond {
+		t.Fatalf("Неверная длительность файла записи: %s %v", d, err)
+	}
+	if err := f.Check(records.CallRecord{Id: "1001", Duration: 120}, 50*time.Millisecond); err != nil {
+		t.Fatalf("Длительность файла должна совпадать с длительностью разговора: %s", err)
+	}
+	if err := f.Check(records.CallRecord{Id: "1001", Duration: 5000}, time.Second); err == nil {
+		t.Fatalf("Ожидалась ошибка несовпадения длительности")
+	}
+}
+
+// TestDownloadTo Тест на проверку размера и контрольной суммы файла записи при загрузке
+func TestDownloadTo(t *testing.T) {
+	s, client := newServer(t)
+	wav := readFixture(t, "test.wav")
+	s.AddRecord(records.CallRecord{Id: "1001"}, "", wav)
+	sum := sha256.Sum256(wav)
+
+	t.Run("complete", func(t *testing.T) {
+		var out bytes.Buffer
+		d, err := client.DownloadTo(records.CallRecord{Id: "1001", FileSize: len(wav)}, &out)
+		if err != nil {
+			t.Fatalf("Не удалось загрузить файл записи: %s", err)
+		}
+		if d.Size != int64(len(wav)) || d.SHA256 != hex.EncodeToString(sum[:]) || !bytes.Equal(out.Bytes(), wav) {
+			t.Fatalf("Неверная контрольная сумма файла записи: %+v", d)
+		}
+	})
+	t.Run("truncated", func(t *testing.T) {
+		_, err := client.DownloadTo(records.CallRecord{Id: "1001", FileSize: len(wav) + 100}, io.Discard)
+		var ie records.IntegrityError
+		if !errors.As(err, &ie) || ie.Actual.Size != int64(len(wav)) {
+			t.Fatalf("Ожидалась ошибка IntegrityError, получено %v", err)
+		}
+	})
+	t.Run("file", func(t *testing.T) {
+		d, err := records.FileDigest(filepath.Join("testdata", "test.wav"))
+		if err != nil || d.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("Неверная контрольная сумма файла: %+v %v", d, err)
+		}
+		if err := d.Check("1001", records.Digest{SHA256: "00"}); err == nil {
+			t.Fatalf("Ожидалась ошибка несовпадения контрольной суммы")
+		}
+	})
+}
+
+// mp3Frames Возвращает n кадров MPEG-1 Layer III 128 кбит/с 44100 Гц стерео с заголовком Xing в первом кадре,
+// если xingFrames больше 0
+func mp3Frames(n int, xingFrames uint32) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	var data []byte
+	for i := 0; i < n; i++ {
+		f := append([]byte(nil), frame...)
+		if i == 0 && xingFrames > 0 {
+			copy(f[36:], "Xing")
+			binary.BigEndian.PutUint32(f[40:], 1)
+			binary.BigEndian.PutUint32(f[44:], xingFrames)
+		}
+		data = append(data, f...)
+	}
+	return data
+}
+
+// TestAudioFormat Тест на определение формата и длительности файла записи
+func TestAudioFormat(t *testing.T) {
+	wav := readFixture(t, "test.wav")
+	id3 := append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 10}, make([]byte, 10)...)
+	formats := []struct {
+		name        string
+		contentType string
+		data        []byte
+		want        records.AudioFormat
+	}{
+		{"wav magic", "application/octet-stream", wav, records.WAV},
+		{"mp3 id3", "", append(id3, mp3Frames(1, 0)...), records.MP3},
+		{"mp3 frame", "", mp3Frames(1, 0), records.MP3},
+		{"content type", "audio/mpeg; charset=binary", []byte("????"), records.MP3},
+		{"x-wav", "audio/x-wav", []byte("????"), records.WAV},
+		{"unknown", "text/plain", []byte("hello"), records.UNKNOWN_FORMAT},
+	}
+	for _, tt := range formats {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := records.DetectFormat(tt.contentType, tt.data); got != tt.want {
+				t.Fatalf("Неверный формат: ожидался %s получен %s", tt.want, got)
+			}
+		})
+	}
+
+	durations := []struct {
+		name string
+		data []byte
+		want time.Duration
+	}{
+		{"cbr", mp3Frames(10, 0), 260625 * time.Microsecond},
+		{"xing", mp3Frames(2, 100), 2612244897 * time.Nanosecond},
+		{"id3", append(id3, mp3Frames(10, 0)...), 260625 * time.Microsecond},
+	}
+	for _, tt := range durations {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := records.RecordFile{Data: tt.data, Format: records.MP3}.Duration()
+			if err != nil || d != tt.want {
+				t.Fatalf("Неверная длительность MP3: ожидалась %s получена %s %v", tt.want, d, err)
+			}
+		})
+	}
+	if _, err := (records.RecordFile{Data: []byte("hello")}).Duration(); err == nil {
+		t.Fatalf("Ожидалась ошибка для нераспознанного формата")
+	}
+}
+
+// TestParseWAV Тест на разбор заголовка WAV
+func TestParseWAV(t *testing.T) {
+	h, err := records.ParseWAV(bytes.NewReader(readFixture(t, "test.wav")))
+	if err != nil {
+		t.Fatalf("Не удалось разобрать заголовок WAV: %s", err)
+	}
+	want := records.WavHeader{AudioFormat: 1, Channels: 1, SampleRate: 8000, ByteRate: 16000, BlockAlign: 2, BitsPerSample: 16, DataSize: 1600}
+	if h != want {
+		t.Fatalf("Неверный заголовок WAV. Ожидалось %+v получено %+v", want, h)
+	}
+
+	// Блок LIST перед fmt должен пропускаться
+	wav := readFixture(t, "test.wav")
+	list := append([]byte("LIST\x03\x00\x00\x00abc\x00"), wav[12:]...)
+	withList := append(append([]byte(nil), wav[:12]...), list...)
+	if h, err := records.ParseWAV(bytes.NewReader(withList)); err != nil || h.DataSize != 1600 {
+		t.Fatalf("Не удалось разобрать WAV с блоком LIST: %+v %v", h, err)
+	}
+
+	for name, data := range map[string][]byte{
+		"not wav":   []byte("RIFF\x00\x00\x00\x00AVI LIST"),
+		"truncated": wav[:30],
+		"no fmt":    append(append([]byte(nil), wav[:12]...), wav[36:44]...),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := records.ParseWAV(bytes.NewReader(data)); err == nil {
+				t.Fatalf("Ожидалась ошибка разбора")
+			}
+		})
+	}
+}
+
+// TestDeleteRecord Тест на удаление записи с сервера Билайн
+func TestDeleteRecord(t *testing.T) {
+	s, client := newServer(t)
+	s.AddRecord(records.CallRecord{Id: "1001"}, "", nil)
+	if err := client.DeleteRecord("1001"); err != nil {
+		t.Fatalf("Не удалось удалить запись: %s", err)
+	}
+	if len(s.Records()) != 0 {
+		t.Fatal("Запись не удалена")
+	}
+}
+
+// TestGetRecordInfo Тест на получение информации о записи по идентификатору
+func TestGetRecordInfo(t *testing.T) {
+	t.Run("page", func(t *testing.T) {
+		// Имитатор, как и GetRecords, отвечает на v2/records/{id} страницей записей после {id}
+		s, client := newServer(t)
+		for _, id := range []string{"1", "2", "3", "call-4"} {
+			s.AddRecord(records.CallRecord{Id: id, Direction: beelineapi.INBOUND, Duration: 5000}, "", nil)
+		}
+		for _, id := range []string{"1", "3"} {
+			got, err := client.GetRecordInfo(id)
+			if err != nil {
+				t.Fatalf("Не удалось получить инфо о записи %s: %s", id, err)
+			}
+			if got.Id != id || got.Direction != beelineapi.INBOUND || got.Duration != 5000 {
+				t.Fatalf("Неверная информация о записи %s: %+v", id, got)
+			}
+		}
+		if _, err := client.GetRecordInfo("7"); !errors.As(err, &records.RecordNotFoundError{}) {
+			t.Fatalf("Ожидалась ошибка RecordNotFoundError, получено %v", err)
+		}
+		if _, err := client.GetRecordInfo("call-4"); err == nil {
+			t.Fatal("Ожидалась ошибка для нечислового ID")
+		}
+	})
+	t.Run("record", func(t *testing.T) {
+		testRec := records.CallRecord{Id: "info", Direction: beelineapi.INBOUND, Duration: 5000}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2/records/info" {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(testRec)
+		}))
+		defer srv.Close()
+		c, err := beelineapi.NewClient("token", beelineapi.WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatalf("Не удалось создать клиента: %s", err)
+		}
+		got, err := records.New(c).GetRecordInfo("info")
+		if err != nil {
+			t.Fatalf("Не удалось получить инфо о записи: %s", err)
+		}
+		if got.Id != testRec.Id || got.Direction != testRec.Direction || got.Duration != testRec.Duration {
+			t.Fatalf("Неверная информация о записи. Ожидалось %+v получено %+v", testRec, got)
+		}
+	})
+}
+
+// TestGetRecordInfoFromEventNotFound Тест на получение типизированной ошибки, если запись еще не готова
+func TestGetRecordInfoFromEventNotFound(t *testing.T) {
+	_, client := newServer(t)
+	_, err := client.GetRecordInfoFromEvent("call", "user")
+	nf, ok := err.(records.RecordNotFoundError)
+	if !ok {
+		t.Fatalf("Ожидалась ошибка RecordNotFoundError, получено %v", err)
+	}
+	if nf.Id != "call" || nf.UserId != "user" {
+		t.Fatalf("Неверные идентификаторы в ошибке: %+v", nf)
+	}
+}
+
+// TestWaitForRecord Тест на ожидание появления записи после завершения разговора
+func TestWaitForRecord(t *testing.T) {
+	s, client := newServer(t)
+	s.AddRecord(records.CallRecord{Id: "ready", Abonent: abonents.Abonent{UserId: "user"}}, "call", []byte("RIFF"))
+
+	t.Run("ready", func(t *testing.T) {
+		// Первые две попытки запись еще не готова
+		s.InjectError("GET", "/records/call/user", 404, beelineapi.APIError{ErrorCode: "NotFound"}, 2)
+		opts := records.WaitOptions{Timeout: time.Second, Interval: time.Millisecond, Download: true}
+		got, r, err := client.WaitForRecord(context.Background(), "call", "user", opts)
+		if err != nil {
+			t.Fatalf("Не удалось дождаться записи: %s", err)
+		}
+		if got.Id != "ready" {
+			t.Fatalf("Неверный результат ожидания. Запись %s", got.Id)
+		}
+		if b, _ := ioutil.ReadAll(r); string(b) != "RIFF" {
+			t.Fatalf("Неверное содержимое файла записи: %q", b)
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		_, _, err := client.WaitForRecord(context.Background(), "call", "missing", records.WaitOptions{Timeout: 10 * time.Millisecond, Interval: time.Millisecond})
+		if _, ok := err.(records.RecordNotFoundError); !ok {
+			t.Fatalf("Ожидалась ошибка RecordNotFoundError, получено %v", err)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		// Сервер не отвечает, пока клиент не прервет запрос
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer srv.Close()
+		c, err := beelineapi.NewClient("token", beelineapi.WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatalf("Не удалось создать клиента: %s", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err = records.New(c).WaitForRecord(ctx, "call", "slow", records.WaitOptions{Timeout: time.Minute})
+		if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+			t.Fatalf("Запрос должен прерываться при отмене контекста: %v за %s", err, time.Since(start))
+		}
+	})
+}
+
+// TestUpdateRecord Тест на изменение комментария и внешнего идентификатора записи
+func TestUpdateRecord(t *testing.T) {
+	s, client := newServer(t)
+	s.AddRecord(records.CallRecord{Id: "42"}, "", nil)
+	tests := []struct {
+		name  string
+		call  func() error
+		field string // Изменяемое поле записи
+		want  string
+	}{
+		{"externalId", func() error { return client.SetRecordExternalId("42", "TICKET-1") }, "externalId", "TICKET-1"},
+		{"comment", func() error { return client.SetRecordComment("42", "перезвонить") }, "comment", "перезвонить"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatalf("Не удалось изменить запись: %s", err)
+			}
+			rec := s.Records()[0]
+			got := map[string]string{"externalId": rec.ExternalId, "comment": rec.Comment}
+			if got[tt.field] != tt.want {
+				t.Fatalf("Поле %s не изменено: %+v", tt.field, rec)
+			}
+		})
+	}
+	// Изменение комментария не должно сбрасывать внешний идентификатор
+	if rec := s.Records()[0]; rec.ExternalId != "TICKET-1" {
+		t.Fatalf("Внешний идентификатор сброшен: %+v", rec)
+	}
+}
+
+// TestFindRecordsByExternalId Тест на поиск записей по внешнему идентификатору с обходом страниц
+func TestFindRecordsByExternalId(t *testing.T) {
+	s, client := newServer(t)
+	for id, ext := range []string{"A", "B", "A"} {
+		s.AddRecord(records.CallRecord{Id: fmt.Sprint(id + 1), ExternalId: ext}, "", nil)
+	}
+	recs, err := client.FindRecordsByExternalId("A")
+	if err != nil {
+		t.Fatalf("Не удалось найти записи: %s", err)
+	}
+	if len(recs) != 2 || recs[0].Id != "1" || recs[1].Id != "3" {
+		t.Fatalf("Неверный результат поиска записей: %+v", recs)
+	}
+	recs, err = client.FindRecordsByExternalIdAfter("A", "2")
+	if err != nil {
+		t.Fatalf("Не удалось найти записи: %s", err)
+	}
+	if len(recs) != 1 || recs[0].Id != "3" {
+		t.Fatalf("Неверный результат поиска записей после 2: %+v", recs)
+	}
+}
+
+// TestForEachRecordAfter Тест на постраничный обход записей с нечисловыми идентификаторами
+func TestForEachRecordAfter(t *testing.T) {
+	s, client := newServer(t)
+	for _, id := range []string{"1", "call-2", "call-3"} {
+		s.AddRecord(records.CallRecord{Id: id}, "", nil)
+	}
+	ids := []string{}
+	err := client.ForEachRecordAfter("", func(r records.CallRecord) error {
+		ids = append(ids, r.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Не удалось обойти записи: %s", err)
+	}
+	if strings.Join(ids, ",") != "1,call-2,call-3" {
+		t.Fatalf("Неверный порядок обхода записей: %v", ids)
+	}
+	ids = ids[:0]
+	client.ForEachRecordAfter("call-2", func(r records.CallRecord) error {
+		ids = append(ids, r.Id)
+		return nil
+	})
+	if strings.Join(ids, ",") != "call-3" {
+		t.Fatalf("Неверный обход записей после call-2: %v", ids)
+	}
+}
+
+// TestEndpoints Тест на возврат ошибки сервера каждой операцией с записями разговоров
+func TestEndpoints(t *testing.T) {
+	s, client := newServer(t)
+	s.AddRecord(records.CallRecord{Id: "1", Abonent: abonents.Abonent{UserId: "user"}}, "call", []byte("RIFF"))
+	if err := s.CheckEndpoints([]beelinetest.Endpoint{
+		{Name: "GetRecords", Method: "GET", Path: "/records", Call: func() error { _, err := client.GetRecords(0); return err }},
+		{Name: "UpdateRecord", Method: "PUT", Path: "/v2/records/1", Call: func() error { return client.SetRecordComment("1", "x") }},
+		{Name: "GetRecordInfo", Method: "GET", Path: "/v2/records/1", Call: func() error { _, err := client.GetRecordInfo("1"); return err }},
+		{Name: "GetRecordInfoFromEvent", Method: "GET", Path: "/records/call/user", Call: func() error { _, err := client.GetRecordInfoFromEvent("call", "user"); return err }},
+		{Name: "GetRecordFile", Method: "GET", Path: "/v2/records/1/download", Call: func() error { _, err := client.GetRecordFile("1"); return err }},
+		{Name: "GetRecordFileFromEvent", Method: "GET", Path: "/records/call/user/download", Call: func() error { _, err := client.GetRecordFileFromEvent("call", "user"); return err }},
+		{Name: "FindRecordsByExternalId", Method: "GET", Path: "/records", Call: func() error { _, err := client.FindRecordsByExternalId("A"); return err }},
+		{Name: "DeleteRecord", Method: "DELETE", Path: "/v2/records/1", Call: func() error { return client.DeleteRecord("1") }},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fixtureRecords Возвращает записи разговоров из testdata/records.json
+func fixtureRecords(t *testing.T) []records.CallRecord {
+	t.Helper()
+	var recs []records.CallRecord
+	if err := json.Unmarshal(readFixture(t, "records.json"), &recs); err != nil {
+		t.Fatalf("Не удалось разобрать записи: %s", err)
+	}
+	return recs
+}
+
+// TestExport Тест на выгрузку записей разговоров в CSV и JSON Lines
+func TestExport(t *testing.T) {
+	recs := fixtureRecords(t)
+	all := []string{}
+	for _, c := range records.Columns {
+		all = append(all, c.Name)
+	}
+	t.Run("csv", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := records.WriteCSV(&out, recs, records.ExportOptions{Columns: all, Location: time.UTC, Comma: ';'}); err != nil {
+			t.Fatalf("Не удалось выгрузить записи в CSV: %s", err)
+		}
+		checkGolden(t, "export.csv", out.Bytes())
+	})
+	t.Run("timezone", func(t *testing.T) {
+		var out bytes.Buffer
+		msk := time.FixedZone("MSK", 3*60*60)
+		opts := records.ExportOptions{Columns: []string{"id", "date"}, Location: msk, DateFormat: "02.01.2006 15:04", BOM: true}
+		if err := records.WriteCSV(&out, recs[:1], opts); err != nil {
+			t.Fatalf("Не удалось выгрузить записи в CSV: %s", err)
+		}
+		if want := "\ufeffID записи,Дата и время\n1001,14.07.2017 05:40\n"; out.String() != want {
+			t.Fatalf("Неверная выгрузка. Ожидалось %q получено %q", want, out.String())
+		}
+	})
+	t.Run("jsonl", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := records.WriteJSONL(&out, recs, records.ExportOptions{Columns: []string{"id", "duration", "abonent.department"}}); err != nil {
+			t.Fatalf("Не удалось выгрузить записи в JSON Lines: %s", err)
+		}
+		want := `{"abonent.department":"Продажи","duration":65,"id":"1001"}` + "\n" +
+			`{"abonent.department":"Поддержка","duration":1,"id":"1002"}` + "\n"
+		if out.String() != want {
+			t.Fatalf("Неверная выгрузка. Ожидалось:\n%s\nполучено:\n%s", want, out.String())
+		}
+	})
+	t.Run("unknown column", func(t *testing.T) {
+		if err := records.WriteCSV(&bytes.Buffer{}, recs, records.ExportOptions{Columns: []string{"price"}}); err == nil {
+			t.Fatalf("Ожидалась ошибка для неизвестного столбца")
+		}
+	})
+}
+
+// TestExportXLSX Тест на выгрузку записей разговоров в книгу Excel
+func TestExportXLSX(t *testing.T) {
+	var out bytes.Buffer
+	if err := records.WriteXLSX(&out, fixtureRecords(t), records.ExportOptions{Columns: []string{"id", "duration", "comment"}}); err != nil {
+		t.Fatalf("Не удалось выгрузить записи в XLSX: %s", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Книга XLSX не является архивом zip: %s", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Не удалось открыть часть книги %s: %s", f.Name, err)
+		}
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("В книге нет части %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">ID записи</t></is></c>`,
+		`<c r="B2"><v>65</v></c>`,
+		`<c r="C3" t="inlineStr"><is><t xml:space="preserve">перезвонить</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Fatalf("Лист не содержит %s:\n%s", want, sheet)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// WaitOptions Параметры ожидания появления записи разговора на портале
+type WaitOptions struct {
+	Timeout     time.Duration // Максимальное время ожидания, по умолчанию 5 минут
+	Interval    time.Duration // Интервал перед первым повторным запросом, по умолчанию 5 секунд
+	MaxInterval time.Duration // Максимальный интервал между запросами, по умолчанию 1 минута
+	Download    bool          // Загрузить файл записи после получения информации о ней
+}
+
+// WaitForRecord Ожидает появления записи разговора по ID разговора из события и ID пользователя из того же события.
+// Запись появляется на портале через некоторое время после завершения разговора, поэтому запрос повторяется
+// с увеличивающимся интервалом, пока запись не будет найдена или не истечет время ожидания.
+// Если время ожидания истекло, возвращается RecordNotFoundError. При отмене ctx прерывается и выполняемый запрос.
+// id - Идентификатор разговора из события
+// userId - Идентификатор пользователя из события
+// opts - Параметры ожидания
+func (s *Service) WaitForRecord(ctx context.Context, id string, userId string, opts WaitOptions) (CallRecord, io.Reader, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = time.Minute
+	}
+	if opts.MaxInterval < opts.Interval {
+		opts.MaxInterval = opts.Interval
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	interval := opts.Interval
+	for {
+		rec, err := s.recordInfoFromEvent(ctx, id, userId)
+		if err == nil {
+			if !opts.Download {
+				return rec, nil, nil
+			}
+			r, err := s.recordFile(ctx, rec.Id)
+			return rec, r, err
+		}
+		var nf RecordNotFoundError
+		if !errors.As(err, &nf) {
+			return rec, nil, err
+		}
+		wait := interval
+		if left := time.Until(deadline); left <= 0 {
+			return rec, nil, err
+		} else if left < wait {
+			wait = left
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return rec, nil, ctx.Err()
+		case <-t.C:
+		}
+		interval *= 2
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
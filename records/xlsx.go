@@ -0,0 +1,104 @@
+package records
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Части книги XLSX, кроме листа с данными
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Записи" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// WriteXLSX Записывает записи разговоров в книгу Excel (XLSX) с одним листом "Записи".
+// Первая строка листа содержит заголовки столбцов, числовые столбцы записываются числами
+func WriteXLSX(w io.Writer, recs []CallRecord, o ExportOptions) error {
+	cols, err := o.columns()
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, p := range xlsxParts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return beelineapi.Wrap("Ошибка при выгрузке записей в XLSX. ", err)
+		}
+		if _, err := io.WriteString(f, p.content); err != nil {
+			return beelineapi.Wrap("Ошибка при выгрузке записей в XLSX. ", err)
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при выгрузке записей в XLSX. ", err)
+	}
+	var sheet bytes.Buffer
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]interface{}, len(cols))
+	for i, c := range cols {
+		header[i] = c.Header
+	}
+	xlsxRow(&sheet, 1, header)
+	values := make([]interface{}, len(cols))
+	for n, r := range recs {
+		for i, c := range cols {
+			values[i] = c.value(r, o)
+		}
+		xlsxRow(&sheet, n+2, values)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+	if _, err := sheet.WriteTo(f); err != nil {
+		return beelineapi.Wrap("Ошибка при выгрузке записей в XLSX. ", err)
+	}
+	if err := zw.Close(); err != nil {
+		return beelineapi.Wrap("Ошибка при выгрузке записей в XLSX. ", err)
+	}
+	return nil
+}
+
+// xlsxRow Записывает строку листа с номером n. Целые числа записываются числовыми ячейками, остальное - строками
+func xlsxRow(b *bytes.Buffer, n int, values []interface{}) {
+	fmt.Fprintf(b, `<row r="%d">`, n)
+	for i, v := range values {
+		ref := fmt.Sprintf("%s%d", xlsxColumn(i), n)
+		if num, ok := v.(int); ok {
+			fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, num)
+			continue
+		}
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xml.EscapeText(b, []byte(fmt.Sprint(v)))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+}
+
+// xlsxColumn Возвращает буквенное обозначение столбца по индексу с нуля: A, B, ..., Z, AA, ...
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
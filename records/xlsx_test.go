@@ -0,0 +1,12 @@
+package records
+
+import "testing"
+
+// TestXlsxColumn Тест на обозначение столбцов листа Excel буквами
+func TestXlsxColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Fatalf("Неверное обозначение столбца %d: ожидалось %s получено %s", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package retention
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// ManifestName Имя файла манифеста в каталоге архива
+const ManifestName = "manifest.jsonl"
+
+// ManifestEntry Запись манифеста архива о сохраненном файле записи разговора
+type ManifestEntry struct {
+	RecordId   string    `json:"recordId"`   // Идентификатор записи разговора
+	File       string    `json:"file"`       // Путь к файлу относительно каталога архива через "/"
+	Size       int64     `json:"size"`       // Размер файла, байт
+	SHA256     string    `json:"sha256"`     // Контрольная сумма SHA-256
+	ArchivedAt time.Time `json:"archivedAt"` // Время сохранения в архив
+}
+
+// VerifyResult Результат проверки файла архива
+type VerifyResult struct {
+	ManifestEntry
+	Error string `json:"error,omitempty"` // Причина, по которой файл не прошел проверку
+}
+
+// appendManifest Добавляет запись в манифест архива dir
+func appendManifest(dir string, e ManifestEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при записи манифеста архива. ", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, ManifestName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при записи манифеста архива. ", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return beelineapi.Wrap("Ошибка при записи манифеста архива. ", err)
+	}
+	if err := f.Close(); err != nil {
+		return beelineapi.Wrap("Ошибка при записи манифеста архива. ", err)
+	}
+	return nil
+}
+
+// ReadManifest Читает манифест архива dir. Если файл сохранялся несколько раз, возвращается последняя запись о нем
+func ReadManifest(dir string) ([]ManifestEntry, error) {
+	f, err := os.Open(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, beelineapi.Wrap("Ошибка при чтении манифеста архива. ", err)
+	}
+	defer f.Close()
+	entries := []ManifestEntry{}
+	index := map[string]int{}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e ManifestEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, beelineapi.Wrap("Ошибка в строке "+strconv.Itoa(line)+" манифеста архива. ", err)
+		}
+		if i, ok := index[e.File]; ok {
+			entries[i] = e
+			continue
+		}
+		index[e.File] = len(entries)
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, beelineapi.Wrap("Ошибка при чтении манифеста архива. ", err)
+	}
+	return entries, nil
+}
+
+// VerifyArchive Пересчитывает контрольные суммы файлов архива dir и сравнивает их с манифестом.
+// Ошибка возвращается, только если манифест не удалось прочитать; результаты проверки файлов - в VerifyResult
+func VerifyArchive(dir string) ([]VerifyResult, error) {
+	entries, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]VerifyResult, len(entries))
+	for i, e := range entries {
+		res[i] = VerifyResult{ManifestEntry: e}
+		d, err := records.FileDigest(filepath.Join(dir, filepath.FromSlash(e.File)))
+		if err == nil {
+			err = d.Check(e.RecordId, records.Digest{Size: e.Size, SHA256: e.SHA256})
+		}
+		if err != nil {
+			res[i].Error = err.Error()
+		}
+	}
+	return res, nil
+}
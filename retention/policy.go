@@ -0,0 +1,181 @@
+package retention
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/internal/yamljson"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// Action Действие правила хранения с записью разговора
+type Action string
+
+const (
+	KEEP   Action = "keep"   // Сохранить запись, например при удержании по судебному запросу
+	DELETE Action = "delete" // Удалить запись
+)
+
+// Policy Политика хранения записей разговоров. Правила проверяются по порядку, действует первое подходящее.
+// Запись, которой не подошло ни одно правило, сохраняется.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule Правило хранения. Запись подходит под правило, если выполнены все заданные условия.
+// Незаданные условия не проверяются
+type Rule struct {
+	Name          string                `json:"name"`                    // Название правила для отчета и журнала
+	Action        Action                `json:"action"`                  // Действие: keep или delete
+	OlderThanDays int                   `json:"olderThanDays,omitempty"` // Запись старше указанного количества дней
+	Abonents      []string              `json:"abonents,omitempty"`      // Идентификаторы, мобильные или добавочные номера абонентов
+	Departments   []string              `json:"departments,omitempty"`   // Отделы абонентов без учета регистра
+	Direction     *beelineapi.Direction `json:"direction,omitempty"`     // Направление вызова: INBOUND или OUTBOUND
+	MinDuration   int                   `json:"minDuration,omitempty"`   // Длительность разговора не меньше, секунд
+	MaxDuration   int                   `json:"maxDuration,omitempty"`   // Длительность разговора меньше, секунд
+	CommentTags   []string              `json:"commentTags,omitempty"`   // Комментарий записи содержит любую из меток без учета регистра, например #hold
+}
+
+// Parse Разбирает политику хранения в формате YAML или JSON.
+// Неизвестные поля считаются ошибкой, чтобы опечатка в условии не приводила к удалению лишних записей.
+func Parse(data []byte) (Policy, error) {
+	p := Policy{}
+	if err := yamljson.Decode(data, &p, true); err != nil {
+		return p, beelineapi.Wrap("Ошибка при разборе политики хранения. ", err)
+	}
+	return p, p.Check()
+}
+
+// Load Читает политику хранения из файла в формате YAML или JSON
+func Load(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, beelineapi.Wrap("Ошибка при чтении политики хранения. ", err)
+	}
+	return Parse(data)
+}
+
+// Check Проверяет правила политики
+func (p Policy) Check() error {
+	for i, r := range p.Rules {
+		name := r.Name
+		if name == "" {
+			name = "№" + strconv.Itoa(i+1)
+		}
+		if r.Action != KEEP && r.Action != DELETE {
+			return beelineapi.WrapError{Msg: "Правило " + name + ". Недопустимое действие " + strconv.Quote(string(r.Action)) + ", ожидалось keep или delete"}
+		}
+		if r.OlderThanDays < 0 || r.MinDuration < 0 || r.MaxDuration < 0 {
+			return beelineapi.WrapError{Msg: "Правило " + name + ". Возраст и длительность не могут быть отрицательными"}
+		}
+		if r.Direction != nil && !r.Direction.Valid() {
+			return beelineapi.WrapError{Msg: "Правило " + name + ". Недопустимое направление вызова"}
+		}
+		// Правило удаления без условий удалило бы все записи, это почти наверняка ошибка в политике
+		if r.Action == DELETE && r.OlderThanDays == 0 && len(r.Abonents) == 0 && len(r.Departments) == 0 &&
+			r.Direction == nil && r.MinDuration == 0 && r.MaxDuration == 0 && len(r.CommentTags) == 0 {
+			return beelineapi.WrapError{Msg: "Правило " + name + " удаляет все записи. Укажите хотя бы одно условие"}
+		}
+	}
+	return nil
+}
+
+// Evaluate Возвращает первое правило, подходящее для записи r на момент now, или false, если такого нет
+func (p Policy) Evaluate(r records.CallRecord, now time.Time) (Rule, bool) {
+	for _, rule := range p.Rules {
+		if rule.Match(r, now) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// undated Проверяет, что запись r без даты разговора подошла бы под правило с условием по возрасту,
+// если бы дата была известна
+func (p Policy) undated(r records.CallRecord) bool {
+	if !noDate(r) {
+		return false
+	}
+	for _, rule := range p.Rules {
+		if rule.OlderThanDays > 0 && rule.matchFilters(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// noDate Проверяет, что дата разговора не передана порталом или равна нулю
+func noDate(r records.CallRecord) bool {
+	return r.Date.IsZero() || r.Date.UnixMilli() == 0
+}
+
+// Match Проверяет, что запись r на момент now подходит под правило.
+// Запись без даты разговора не подходит под условие по возрасту.
+func (rule Rule) Match(r records.CallRecord, now time.Time) bool {
+	if rule.OlderThanDays > 0 && (noDate(r) || !r.Date.Before(now.AddDate(0, 0, -rule.OlderThanDays))) {
+		return false
+	}
+	return rule.matchFilters(r)
+}
+
+// matchFilters Проверяет условия правила, кроме условия по возрасту
+func (rule Rule) matchFilters(r records.CallRecord) bool {
+	if len(rule.Abonents) > 0 && !matchAbonent(rule.Abonents, r) {
+		return false
+	}
+	if len(rule.Departments) > 0 && !containsFold(rule.Departments, r.Abonent.Department) {
+		return false
+	}
+	if rule.Direction != nil && *rule.Direction != r.Direction {
+		return false
+	}
+	if rule.MinDuration > 0 && r.Duration < rule.MinDuration*1000 {
+		return false
+	}
+	if rule.MaxDuration > 0 && r.Duration >= rule.MaxDuration*1000 {
+		return false
+	}
+	if len(rule.CommentTags) > 0 {
+		comment := strings.ToLower(r.Comment)
+		found := false
+		for _, tag := range rule.CommentTags {
+			if tag != "" && strings.Contains(comment, strings.ToLower(tag)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAbonent Проверяет, что абонент записи указан в списке идентификатором, мобильным или добавочным номером
+func matchAbonent(list []string, r records.CallRecord) bool {
+	for _, key := range list {
+		switch {
+		case key == "":
+		case key == r.Abonent.UserId, key == r.Abonent.Extension:
+			return true
+		default:
+			if p, err := beelineapi.NormalizePhone(key); err == nil && p == r.Abonent.Phone {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsFold Проверяет, что s есть в списке без учета регистра
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
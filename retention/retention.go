@@ -0,0 +1,265 @@
+// Package retention удаляет записи разговоров облачной АТС Билайн по политике хранения:
+// например, записи старше заданного количества дней, кроме отмеченных меткой удержания в комментарии.
+// Перед удалением записи можно сохранить в архив, каждое удаление записывается в журнал аудита,
+// а в режиме DryRun выполняется только отчет без удаления.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/records"
+)
+
+// Archiver Архив записей разговоров, в который запись сохраняется перед удалением
+type Archiver interface {
+	// Archive Сохраняет запись rec с файлом file и возвращает ее расположение в архиве
+	Archive(rec records.CallRecord, file records.RecordFile) (string, error)
+}
+
+// DirArchiver Архив в каталоге: файл записи <Dir>/<год-месяц>/<id>.<mp3|wav> и информация о ней <id>.json.
+// Расширение файла определяется по его формату, файл нераспознанного формата сохраняется с расширением .bin.
+// Размер и контрольная сумма каждого файла записываются в манифест архива <Dir>/manifest.jsonl,
+// по которому архив можно проверить функцией VerifyArchive
+type DirArchiver struct {
+	Dir string
+}
+
+// Archive Сохраняет запись и информацию о ней в каталог и добавляет файл в манифест.
+// Если размер полученного файла не совпадает с rec.FileSize, файл удаляется и возвращается records.IntegrityError
+func (a DirArchiver) Archive(rec records.CallRecord, file records.RecordFile) (string, error) {
+	month := rec.Date.UTC().Format("2006-01")
+	dir := filepath.Join(a.Dir, month)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", beelineapi.Wrap("Ошибка при создании каталога архива. ", err)
+	}
+	name := filepath.Join(dir, safeName(rec.Id))
+	info, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", beelineapi.Wrap("Ошибка при сохранении информации о записи в архив. ", err)
+	}
+	if err := os.WriteFile(name+".json", info, 0644); err != nil {
+		return "", beelineapi.Wrap("Ошибка при сохранении информации о записи в архив. ", err)
+	}
+	ext := file.Format.Ext()
+	if ext == "" {
+		ext = ".bin"
+	}
+	d, err := writeFile(name+ext, file.Reader())
+	if err == nil {
+		err = d.Check(rec.Id, records.Digest{Size: int64(rec.FileSize)})
+	}
+	if err != nil {
+		os.Remove(name + ext)
+		os.Remove(name + ".json")
+		return "", err
+	}
+	entry := ManifestEntry{
+		RecordId:   rec.Id,
+		File:       month + "/" + safeName(rec.Id) + ext,
+		Size:       d.Size,
+		SHA256:     d.SHA256,
+		ArchivedAt: time.Now().UTC(),
+	}
+	if err := appendManifest(a.Dir, entry); err != nil {
+		return "", err
+	}
+	return name + ext, nil
+}
+
+// writeFile Записывает данные r в файл path и возвращает их размер и контрольную сумму
+func writeFile(path string, r io.Reader) (records.Digest, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return records.Digest{}, beelineapi.Wrap("Ошибка при сохранении файла записи в архив. ", err)
+	}
+	hr := records.NewHashingReader(r)
+	if _, err := io.Copy(f, hr); err != nil {
+		f.Close()
+		return records.Digest{}, beelineapi.Wrap("Ошибка при сохранении файла записи в архив. ", err)
+	}
+	if err := f.Close(); err != nil {
+		return records.Digest{}, beelineapi.Wrap("Ошибка при сохранении файла записи в архив. ", err)
+	}
+	return hr.Digest(), nil
+}
+
+// safeName Заменяет в идентификаторе записи символы, недопустимые в имени файла
+func safeName(id string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, id)
+}
+
+// Decision Решение по записи разговора, подошедшей под правило политики
+type Decision struct {
+	Record  records.CallRecord `json:"record"`
+	Rule    string             `json:"rule"`              // Название правила
+	Action  Action             `json:"action"`            // Действие правила
+	Archive string             `json:"archive,omitempty"` // Расположение записи в архиве
+	Deleted bool               `json:"deleted"`           // Запись удалена
+	Error   string             `json:"error,omitempty"`   // Ошибка архивирования или удаления
+}
+
+// Report Результат применения политики хранения
+type Report struct {
+	DryRun    bool       `json:"dryRun"`
+	Checked   int        `json:"checked"`   // Количество проверенных записей
+	Decisions []Decision `json:"decisions"` // Решения по записям, подошедшим под правила, в порядке записей
+	Skipped   []string   `json:"skipped"`   // Записи без даты разговора, пропущенные правилами по возрасту
+}
+
+// Deleted Возвращает количество удаленных записей
+func (r Report) Deleted() int {
+	n := 0
+	for _, d := range r.Decisions {
+		if d.Deleted {
+			n++
+		}
+	}
+	return n
+}
+
+// Err Возвращает ошибку, если какую-либо запись не удалось заархивировать или удалить
+func (r Report) Err() error {
+	failed := []string{}
+	for _, d := range r.Decisions {
+		if d.Error != "" {
+			failed = append(failed, d.Record.Id+": "+d.Error)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return beelineapi.WrapError{Msg: "Не удалось удалить записи разговоров:\n" + strings.Join(failed, "\n")}
+}
+
+// AuditEntry Запись журнала аудита об удалении записи разговора
+type AuditEntry struct {
+	Time       time.Time `json:"time"`              // Время удаления
+	RecordId   string    `json:"recordId"`          // Идентификатор записи
+	RecordDate time.Time `json:"recordDate"`        // Дата разговора
+	Abonent    string    `json:"abonent"`           // Идентификатор абонента
+	Phone      string    `json:"phone"`             // Номер собеседника
+	Rule       string    `json:"rule"`              // Правило, по которому запись удалена
+	Archive    string    `json:"archive,omitempty"` // Расположение записи в архиве
+	Result     string    `json:"result"`            // deleted или error
+	Error      string    `json:"error,omitempty"`   // Текст ошибки
+}
+
+// Engine Применяет политику хранения к записям разговоров. Создается функцией New.
+type Engine struct {
+	Policy   Policy
+	DryRun   bool      // Только построить отчет, ничего не архивируя и не удаляя
+	Archiver Archiver  // Архив для записей перед удалением, nil - удалять без архивирования
+	Audit    io.Writer // Журнал аудита удалений в формате JSON Lines, nil - без журнала
+
+	s *records.Service
+}
+
+// New Возвращает обработчик политики хранения p для клиента c
+func New(c *beelineapi.APIClient, p Policy) *Engine {
+	return &Engine{Policy: p, s: records.New(c)}
+}
+
+// Run Проверяет все записи разговоров по политике и удаляет подошедшие под правила удаления.
+// Запись, которую не удалось заархивировать, не удаляется. Ошибки отдельных записей
+// возвращаются в отчете (см. Report.Err), а ошибка выполнения - при ошибке получения списка записей,
+// записи в журнал аудита или отмене ctx.
+func (e *Engine) Run(ctx context.Context) (Report, error) {
+	rep := Report{DryRun: e.DryRun, Decisions: []Decision{}, Skipped: []string{}}
+	if err := e.Policy.Check(); err != nil {
+		return rep, err
+	}
+	// Записи собираются полностью до удаления, чтобы удаление не влияло на постраничный обход
+	list := []records.CallRecord{}
+	err := e.s.ForEachRecordAfter("", func(r records.CallRecord) error {
+		list = append(list, r)
+		return ctx.Err()
+	})
+	if err != nil {
+		return rep, beelineapi.Wrap("Ошибка при получении списка записей разговоров. ", err)
+	}
+	now := time.Now()
+	for _, r := range list {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		rep.Checked++
+		rule, ok := e.Policy.Evaluate(r, now)
+		if !ok {
+			if e.Policy.undated(r) {
+				rep.Skipped = append(rep.Skipped, r.Id)
+			}
+			continue
+		}
+		d := Decision{Record: r, Rule: rule.Name, Action: rule.Action}
+		if rule.Action == DELETE && !e.DryRun {
+			e.delete(&d)
+			if err := e.audit(d); err != nil {
+				rep.Decisions = append(rep.Decisions, d)
+				return rep, err
+			}
+		}
+		rep.Decisions = append(rep.Decisions, d)
+	}
+	return rep, nil
+}
+
+// delete Архивирует и удаляет запись решения d
+func (e *Engine) delete(d *Decision) {
+	if e.Archiver != nil {
+		rf, err := e.s.DownloadRecord(d.Record.Id)
+		if err != nil {
+			d.Error = err.Error()
+			return
+		}
+		if d.Archive, err = e.Archiver.Archive(d.Record, rf); err != nil {
+			d.Error = err.Error()
+			return
+		}
+	}
+	if err := e.s.DeleteRecord(d.Record.Id); err != nil {
+		d.Error = err.Error()
+		return
+	}
+	d.Deleted = true
+}
+
+// audit Записывает в журнал аудита результат удаления записи
+func (e *Engine) audit(d Decision) error {
+	if e.Audit == nil {
+		return nil
+	}
+	entry := AuditEntry{
+		Time:       time.Now(),
+		RecordId:   d.Record.Id,
+		RecordDate: d.Record.Date.Time,
+		Abonent:    d.Record.Abonent.UserId,
+		Phone:      d.Record.Phone,
+		Rule:       d.Rule,
+		Archive:    d.Archive,
+		Result:     "deleted",
+		Error:      d.Error,
+	}
+	if d.Error != "" {
+		entry.Result = "error"
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return beelineapi.Wrap("Ошибка при записи в журнал аудита. ", err)
+	}
+	if _, err := e.Audit.Write(append(b, '\n')); err != nil {
+		return beelineapi.Wrap("Ошибка при записи в журнал аудита. ", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package retention_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/records"
+	"github.com/taigasys/beeline-portal-api/retention"
+)
+
+const policyYAML = `
+rules:
+  - name: legal-hold
+    action: keep
+    commentTags: ["#hold"]
+  - name: short
+    action: delete
+    maxDuration: 5
+    direction: OUTBOUND
+  - name: old
+    action: delete
+    olderThanDays: 90
+`
+
+// newServer Возвращает имитатор с записями разговоров разного возраста
+func newServer(t *testing.T) *beelinetest.Server {
+	s := beelinetest.NewServer("token")
+	t.Cleanup(s.Close)
+	daysAgo := func(n int) beelineapi.UnixMilli {
+		return beelineapi.UnixMilli{Time: time.Now().AddDate(0, 0, -n)}
+	}
+	a := abonents.Abonent{UserId: "u1", Department: "Продажи"}
+	s.AddRecord(records.CallRecord{Id: "1", Date: daysAgo(200), Duration: 60000, Abonent: a}, "", []byte("old"))
+	s.AddRecord(records.CallRecord{Id: "2", Date: daysAgo(200), Duration: 60000, Comment: "Спор #HOLD", Abonent: a}, "", []byte("hold"))
+	s.AddRecord(records.CallRecord{Id: "3", Date: daysAgo(10), Duration: 60000, Abonent: a}, "", []byte("new"))
+	s.AddRecord(records.CallRecord{Id: "4", Date: daysAgo(1), Duration: 2000, Direction: beelineapi.OUTBOUND, Abonent: a}, "", []byte("short"))
+	return s
+}
+
+// decisions Возвращает решения отчета в виде id:правило
+func decisions(rep retention.Report) []string {
+	res := []string{}
+	for _, d := range rep.Decisions {
+		res = append(res, d.Record.Id+":"+d.Rule)
+	}
+	return res
+}
+
+// TestDryRun Тест на отчет без удаления записей
+func TestDryRun(t *testing.T) {
+	s := newServer(t)
+	p, err := retention.Parse([]byte(policyYAML))
+	if err != nil {
+		t.Fatalf("Не удалось разобрать политику: %s", err)
+	}
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	e := retention.New(client, p)
+	e.DryRun = true
+	rep, err := e.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Не удалось применить политику: %s", err)
+	}
+	if got := strings.Join(decisions(rep), ","); got != "1:old,2:legal-hold,4:short" || rep.Checked != 4 {
+		t.Fatalf("Неверный отчет: %s, проверено %d", got, rep.Checked)
+	}
+	if rep.Deleted() != 0 || len(s.Records()) != 4 {
+		t.Fatalf("В режиме DryRun записи не должны удаляться")
+	}
+}
+
+// TestApply Тест на архивирование и удаление записей с журналом аудита
+func TestApply(t *testing.T) {
+	s := newServer(t)
+	p, _ := retention.Parse([]byte(policyYAML))
+	dir := t.TempDir()
+	var audit bytes.Buffer
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	e := retention.New(client, p)
+	e.Archiver = retention.DirArchiver{Dir: dir}
+	e.Audit = &audit
+
+	rep, err := e.Run(context.Background())
+	if err != nil || rep.Err() != nil {
+		t.Fatalf("Не удалось применить политику: %v %v", err, rep.Err())
+	}
+	if rep.Deleted() != 2 {
+		t.Fatalf("Неверное количество удаленных записей: %d", rep.Deleted())
+	}
+	left := []string{}
+	for _, r := range s.Records() {
+		left = append(left, r.Id)
+	}
+	if strings.Join(left, ",") != "2,3" {
+		t.Fatalf("Неверные оставшиеся записи: %v", left)
+	}
+	archived, err := os.ReadFile(rep.Decisions[0].Archive)
+	if err != nil || string(archived) != "old" {
+		t.Fatalf("Запись не сохранена в архив: %q %v", archived, err)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(rep.Decisions[0].Archive, ".bin") + ".json"); err != nil {
+		t.Fatalf("Информация о записи не сохранена в архив: %s", err)
+	}
+	if !strings.HasPrefix(rep.Decisions[0].Archive, filepath.Clean(dir)) {
+		t.Fatalf("Запись сохранена вне каталога архива: %s", rep.Decisions[0].Archive)
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("В журнале аудита должно быть 2 удаления:\n%s", audit.String())
+	}
+	var entry retention.AuditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Неверная запись журнала аудита: %s", err)
+	}
+	if entry.RecordId != "1" || entry.Rule != "old" || entry.Result != "deleted" || entry.Abonent != "u1" {
+		t.Fatalf("Неверная запись журнала аудита: %+v", entry)
+	}
+}
+
+// TestVerifyArchive Тест на проверку архива по манифесту
+func TestVerifyArchive(t *testing.T) {
+	s := newServer(t)
+	p, _ := retention.Parse([]byte(policyYAML))
+	dir := t.TempDir()
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	e := retention.New(client, p)
+	e.Archiver = retention.DirArchiver{Dir: dir}
+	rep, err := e.Run(context.Background())
+	if err != nil || rep.Err() != nil {
+		t.Fatalf("Не удалось применить политику: %v %v", err, rep.Err())
+	}
+
+	res, err := retention.VerifyArchive(dir)
+	if err != nil {
+		t.Fatalf("Не удалось проверить архив: %s", err)
+	}
+	if len(res) != 2 || res[0].RecordId != "1" || res[0].Size != 3 || res[0].Error != "" || res[1].Error != "" {
+		t.Fatalf("Неверный результат проверки архива: %+v", res)
+	}
+	if err := os.WriteFile(rep.Decisions[0].Archive, []byte("OLD"), 0644); err != nil {
+		t.Fatalf("Не удалось изменить файл архива: %s", err)
+	}
+	os.Remove(rep.Decisions[2].Archive)
+	res, _ = retention.VerifyArchive(dir)
+	if !strings.Contains(res[0].Error, "Контрольная сумма") || res[1].Error == "" {
+		t.Fatalf("Измененный и удаленный файлы должны не пройти проверку: %+v", res)
+	}
+}
+
+// TestArchiveFormat Тест на сохранение файла записи с расширением по его формату
+func TestArchiveFormat(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	old := beelineapi.UnixMilli{Time: time.Now().AddDate(-1, 0, 0)}
+	s.AddRecord(records.CallRecord{Id: "1", Date: old}, "", []byte("RIFF\x00\x00\x00\x00WAVEfmt "))
+	s.AddRecord(records.CallRecord{Id: "2", Date: old}, "", []byte("ID3"))
+	p, _ := retention.Parse([]byte(policyYAML))
+	dir := t.TempDir()
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	e := retention.New(client, p)
+	e.Archiver = retention.DirArchiver{Dir: dir}
+	rep, err := e.Run(context.Background())
+	if err != nil || rep.Err() != nil {
+		t.Fatalf("Не удалось применить политику: %v %v", err, rep.Err())
+	}
+	if filepath.Ext(rep.Decisions[0].Archive) != ".wav" || filepath.Ext(rep.Decisions[1].Archive) != ".mp3" {
+		t.Fatalf("Неверные расширения файлов архива: %+v", rep.Decisions)
+	}
+	entries, err := retention.ReadManifest(dir)
+	if err != nil || len(entries) != 2 || filepath.Ext(entries[0].File) != ".wav" || filepath.Ext(entries[1].File) != ".mp3" {
+		t.Fatalf("Неверные файлы в манифесте: %+v %v", entries, err)
+	}
+}
+
+// TestNonNumericId Тест на обход записей с нечисловыми идентификаторами
+func TestNonNumericId(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	old := beelineapi.UnixMilli{Time: time.Now().AddDate(-1, 0, 0)}
+	s.AddRecord(records.CallRecord{Id: "1", Date: old}, "", nil)
+	s.AddRecord(records.CallRecord{Id: "call-2", Date: old}, "", nil)
+	p, _ := retention.Parse([]byte(policyYAML))
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	rep, err := retention.New(client, p).Run(context.Background())
+	if err != nil || rep.Err() != nil {
+		t.Fatalf("Не удалось применить политику: %v %v", err, rep.Err())
+	}
+	if rep.Checked != 2 || rep.Deleted() != 2 || len(s.Records()) != 0 {
+		t.Fatalf("Неверный отчет: %+v", rep)
+	}
+}
+
+// TestUndated Тест на пропуск записей без даты разговора правилами по возрасту
+func TestUndated(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddRecord(records.CallRecord{Id: "1", Duration: 60000}, "", nil)
+	s.AddRecord(records.CallRecord{Id: "2", Date: beelineapi.UnixMilli{Time: time.UnixMilli(0)}, Duration: 60000}, "", nil)
+	s.AddRecord(records.CallRecord{Id: "3", Duration: 2000, Direction: beelineapi.OUTBOUND}, "", nil)
+	p, _ := retention.Parse([]byte(policyYAML))
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	rep, err := retention.New(client, p).Run(context.Background())
+	if err != nil || rep.Err() != nil {
+		t.Fatalf("Не удалось применить политику: %v %v", err, rep.Err())
+	}
+	// Запись 3 удаляется правилом без условия по возрасту
+	if strings.Join(decisions(rep), ",") != "3:short" || strings.Join(rep.Skipped, ",") != "1,2" {
+		t.Fatalf("Неверный отчет: решения %v, пропущены %v", decisions(rep), rep.Skipped)
+	}
+	if len(s.Records()) != 2 {
+		t.Fatalf("Записи без даты не должны удаляться: %+v", s.Records())
+	}
+}
+
+// TestArchiveSizeMismatch Тест на отказ от удаления записи, файл которой получен не полностью
+func TestArchiveSizeMismatch(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	old := beelineapi.UnixMilli{Time: time.Now().AddDate(-1, 0, 0)}
+	s.AddRecord(records.CallRecord{Id: "1", Date: old, FileSize: 1000}, "", []byte("short"))
+	p, _ := retention.Parse([]byte(policyYAML))
+	dir := t.TempDir()
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	e := retention.New(client, p)
+	e.Archiver = retention.DirArchiver{Dir: dir}
+	rep, err := e.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Не удалось применить политику: %s", err)
+	}
+	if rep.Err() == nil || rep.Deleted() != 0 || len(s.Records()) != 1 {
+		t.Fatalf("Запись с неполным файлом не должна удаляться: %+v", rep)
+	}
+	if _, err := retention.ReadManifest(dir); err == nil {
+		t.Fatalf("Неполный файл не должен попадать в манифест")
+	}
+}
+
+// TestDeleteError Тест на запись ошибки удаления в отчет и журнал аудита
+func TestDeleteError(t *testing.T) {
+	s := newServer(t)
+	p, _ := retention.Parse([]byte(policyYAML))
+	s.InjectError("DELETE", "/v2/records/1", 500, beelineapi.APIError{ErrorCode: "Internal"}, 0)
+	var audit bytes.Buffer
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	e := retention.New(client, p)
+	e.Audit = &audit
+	rep, err := e.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Ошибка удаления записи не должна прерывать обработку: %s", err)
+	}
+	if rep.Err() == nil || rep.Deleted() != 1 || !strings.Contains(audit.String(), `"result":"error"`) {
+		t.Fatalf("Ошибка удаления не отражена в отчете и журнале: %+v\n%s", rep, audit.String())
+	}
+}
+
+// TestPolicyCheck Тест на проверку политики хранения
+func TestPolicyCheck(t *testing.T) {
+	tests := map[string]string{
+		"action":   "rules: [{name: x, action: drop, olderThanDays: 1}]",
+		"all":      "rules: [{name: x, action: delete}]",
+		"negative": "rules: [{name: x, action: delete, olderThanDays: -1}]",
+		"unknown":  "rules: [{name: x, action: delete, olderThan: 1}]",
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := retention.Parse([]byte(doc)); err == nil {
+				t.Fatalf("Ожидалась ошибка для политики %s", doc)
+			}
+		})
+	}
+}
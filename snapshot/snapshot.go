@@ -0,0 +1,249 @@
+// Package snapshot сохраняет настройки абонентов облачной АТС Билайн в снимок формата JSON
+// и восстанавливает их из снимка. В снимок входят базовая и выборочная переадресация,
+// правила и статус выборочного приема звонков, статус записи разговоров и статус агента call-центра.
+// Восстановление повторяет вызовы установки каждой настройки, поэтому снимок можно применить
+// к абоненту независимо от того, как менялись его настройки после снятия снимка.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/bulk"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+)
+
+// Version Версия формата снимка. Снимки более новой версии не читаются.
+const Version = 1
+
+// Snapshot Снимок настроек абонентов
+type Snapshot struct {
+	Version   int       `json:"version"`   // Версия формата снимка
+	CreatedAt time.Time `json:"createdAt"` // Время снятия снимка
+	Abonents  []Abonent `json:"abonents"`  // Настройки абонентов
+}
+
+// Abonent Настройки абонента
+type Abonent struct {
+	Abonent             abonents.Abonent                 `json:"abonent"`             // Абонент на момент снятия снимка
+	Recording           beelineapi.ServiceStatus         `json:"recording"`           // Статус записи разговоров: ON или OFF
+	AgentStatus         beelineapi.AgentStatus           `json:"agentStatus"`         // Статус агента call-центра: ONLINE, OFFLINE или BREAK
+	Forwarding          forwarding.BasicRedirectResponse `json:"forwarding"`          // Базовая переадресация
+	SelectiveForwarding forwarding.CfsStatusResponse     `json:"selectiveForwarding"` // Выборочная переадресация
+	Bwl                 bwl.BwlStatusResponse            `json:"bwl"`                 // Выборочный прием звонков
+}
+
+// Find Возвращает настройки абонента по идентификатору
+func (s Snapshot) Find(userId string) (Abonent, bool) {
+	for _, a := range s.Abonents {
+		if a.Abonent.UserId == userId {
+			return a, true
+		}
+	}
+	return Abonent{}, false
+}
+
+// Write Записывает снимок в формате JSON
+func (s Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return beelineapi.Wrap("Ошибка при записи снимка настроек. ", err)
+	}
+	return nil
+}
+
+// Read Читает снимок в формате JSON и проверяет его версию
+func Read(r io.Reader) (Snapshot, error) {
+	s := Snapshot{}
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return s, beelineapi.Wrap("Ошибка при чтении снимка настроек. ", err)
+	}
+	if s.Version < 1 || s.Version > Version {
+		return s, beelineapi.WrapError{Msg: fmt.Sprintf("Неподдерживаемая версия снимка настроек %d, ожидалась %d", s.Version, Version)}
+	}
+	return s, nil
+}
+
+// Service Снятие и восстановление снимков настроек абонентов
+type Service struct {
+	abonents   *abonents.Service
+	forwarding *forwarding.Service
+	bwl        *bwl.Service
+}
+
+// New Возвращает операции со снимками настроек для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{abonents: abonents.New(c), forwarding: forwarding.New(c), bwl: bwl.New(c)}
+}
+
+// Take Снимает настройки абонентов ids или всех абонентов, если ids не указаны.
+// При отмене ctx снятие прерывается и возвращается ошибка ctx.
+// ids - Идентификаторы, мобильные или добавочные номера абонентов
+func (s *Service) Take(ctx context.Context, ids ...string) (Snapshot, error) {
+	snap := Snapshot{Version: Version, CreatedAt: time.Now().UTC(), Abonents: []Abonent{}}
+	list := []abonents.Abonent{}
+	if len(ids) == 0 {
+		var err error
+		if list, err = s.abonents.GetAbonents(); err != nil {
+			return snap, err
+		}
+	}
+	for _, id := range ids {
+		a, err := s.abonents.GetAbonent(id)
+		if err != nil {
+			return snap, beelineapi.Wrap("Абонент "+id+". ", err)
+		}
+		list = append(list, a)
+	}
+	for _, a := range list {
+		if err := ctx.Err(); err != nil {
+			return snap, err
+		}
+		st, err := s.take(a)
+		if err != nil {
+			return snap, beelineapi.Wrap("Абонент "+a.UserId+". ", err)
+		}
+		snap.Abonents = append(snap.Abonents, st)
+	}
+	return snap, nil
+}
+
+// take Снимает настройки абонента a
+func (s *Service) take(a abonents.Abonent) (Abonent, error) {
+	st := Abonent{Abonent: a}
+	var err error
+	if st.Recording, err = s.abonents.GetRecordingStatus(a.UserId); err != nil {
+		return st, err
+	}
+	if st.AgentStatus, err = s.abonents.GetAgentStatus(a.UserId); err != nil {
+		return st, err
+	}
+	if st.Forwarding, err = s.forwarding.GetBasicRedirectStatus(a.UserId); err != nil {
+		return st, err
+	}
+	if st.SelectiveForwarding, err = s.forwarding.GetSelectiveCallRules(a.UserId); err != nil {
+		return st, err
+	}
+	if st.Bwl, err = s.bwl.IncCallRules(a.UserId); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// Result Результат восстановления настроек абонента
+type Result = bulk.Result
+
+// Report Отчет о восстановлении настроек в порядке абонентов в снимке
+type Report = bulk.Report
+
+// Restore Восстанавливает настройки абонентов из снимка по очереди.
+// Ошибка восстановления одного абонента не прерывает восстановление остальных и содержится в отчете.
+// При отмене ctx настройки оставшихся абонентов не восстанавливаются и завершаются ошибкой ctx.
+func (s *Service) Restore(ctx context.Context, snap Snapshot) Report {
+	rep := Report{Results: make([]Result, len(snap.Abonents))}
+	for i, a := range snap.Abonents {
+		if err := ctx.Err(); err != nil {
+			rep.Results[i] = bulk.NewResult(a.Abonent, err)
+			continue
+		}
+		rep.Results[i] = bulk.NewResult(a.Abonent, s.restore(a))
+	}
+	return rep
+}
+
+// restore Восстанавливает настройки абонента. Правила выборочной переадресации и выборочного приема звонков
+// заменяются правилами из снимка, поэтому их идентификаторы после восстановления меняются.
+// Услуги отключаются до изменения правил, а включаются - после.
+func (s *Service) restore(a Abonent) error {
+	id := a.Abonent.UserId
+	steps := []func() error{
+		func() error {
+			if a.Recording == beelineapi.ON {
+				return s.abonents.TurnOnRecording(id)
+			}
+			return s.abonents.TurnOffRecording(id)
+		},
+		func() error { return s.abonents.SetAgentStatus(id, a.AgentStatus) },
+		func() error {
+			if a.Forwarding.Status == beelineapi.ON {
+				return s.forwarding.TurnOnBasicRedirect(id, a.Forwarding.Forward)
+			}
+			return s.forwarding.TurnOffBasicRedirect(id)
+		},
+		func() error { return s.restoreSelective(id, a.SelectiveForwarding) },
+		func() error { return s.restoreBwl(id, a.Bwl) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreSelective Восстанавливает выборочную переадресацию абонента id
+func (s *Service) restoreSelective(id string, want forwarding.CfsStatusResponse) error {
+	cur, err := s.forwarding.GetSelectiveCallRules(id)
+	if err != nil {
+		return err
+	}
+	if !want.IsCfsServiceEnabled && cur.IsCfsServiceEnabled {
+		if err := s.forwarding.TurnOffSelectiveRedirect(id); err != nil {
+			return err
+		}
+	}
+	for _, rule := range cur.RuleList {
+		if err := s.forwarding.DeleteSelectiveCallRule(id, rule.Id); err != nil {
+			return err
+		}
+	}
+	for _, rule := range want.RuleList {
+		upd := forwarding.CfsRuleUpdate{Name: rule.Name, ForwardToPhone: rule.ForwardToPhone, Schedule: rule.Schedule, PhoneList: rule.PhoneList}
+		if _, err := s.forwarding.AddSelectiveCallRule(id, upd); err != nil {
+			return err
+		}
+	}
+	if want.IsCfsServiceEnabled && !cur.IsCfsServiceEnabled {
+		return s.forwarding.TurnOnSelectiveRedirect(id)
+	}
+	return nil
+}
+
+// restoreBwl Восстанавливает выборочный прием звонков абонента id
+func (s *Service) restoreBwl(id string, want bwl.BwlStatusResponse) error {
+	cur, err := s.bwl.IncCallRules(id)
+	if err != nil {
+		return err
+	}
+	if want.Status == bwl.OFF && cur.Status != bwl.OFF {
+		if err := s.bwl.TurnOffSelectiveReceiveRule(id); err != nil {
+			return err
+		}
+	}
+	for _, rule := range cur.Rules() {
+		if err := s.bwl.DeleteSelectiveReceiveRule(id, rule.Id); err != nil {
+			return err
+		}
+	}
+	for _, rule := range want.Rules() {
+		add := bwl.BwlRuleAdd{Type: rule.Type, Rule: bwl.BwlRuleUpdate{Name: rule.Name, Schedule: rule.Schedule, PhoneList: rule.PhoneList}}
+		if _, err := s.bwl.AddIncCallRule(id, add); err != nil {
+			return err
+		}
+	}
+	if want.Status != bwl.OFF && want.Status != cur.Status {
+		t := beelineapi.BLACK_LIST
+		if want.Status == bwl.WHITE_LIST_ON {
+			t = beelineapi.WHITE_LIST
+		}
+		return s.bwl.TurnOnSelectiveCallReceive(id, t)
+	}
+	return nil
+}
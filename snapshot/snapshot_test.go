@@ -0,0 +1,137 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/bwl"
+	"github.com/taigasys/beeline-portal-api/forwarding"
+	"github.com/taigasys/beeline-portal-api/snapshot"
+)
+
+// configure Задает абоненту u1 все настройки, входящие в снимок
+func configure(t *testing.T, c *beelineapi.APIClient) {
+	ab, f, b := abonents.New(c), forwarding.New(c), bwl.New(c)
+	steps := []error{
+		ab.TurnOnRecording("u1"),
+		ab.SetAgentStatus("u1", beelineapi.BREAK),
+		f.TurnOnBasicRedirect("u1", forwarding.BasicRedirect{ForwardNotAnswerPhone: "9000000003", ForwardNotAnswerTimeout: 5}),
+		f.TurnOnSelectiveRedirect("u1"),
+		b.TurnOnSelectiveCallReceive("u1", beelineapi.WHITE_LIST),
+	}
+	_, err := f.AddSelectiveCallRule("u1", forwarding.CfsRuleUpdate{Name: "night", ForwardToPhone: "9000000005", Schedule: beelineapi.NON_WORKING_TIME_AND_HOLIDAYS})
+	steps = append(steps, err)
+	_, err = b.AddIncCallRule("u1", bwl.BwlRuleAdd{Type: beelineapi.WHITE_LIST, Rule: bwl.BwlRuleUpdate{Name: "office", PhoneList: []string{"9000000001"}}})
+	steps = append(steps, err)
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("Не удалось задать настройки абонента: %s", err)
+		}
+	}
+}
+
+// reset Сбрасывает настройки абонента u1 и добавляет лишние правила
+func reset(t *testing.T, c *beelineapi.APIClient) {
+	ab, f, b := abonents.New(c), forwarding.New(c), bwl.New(c)
+	steps := []error{
+		ab.TurnOffRecording("u1"),
+		ab.SetAgentStatus("u1", beelineapi.ONLINE),
+		f.TurnOffBasicRedirect("u1"),
+		f.TurnOffSelectiveRedirect("u1"),
+		b.TurnOffSelectiveReceiveRule("u1"),
+	}
+	_, err := f.AddSelectiveCallRule("u1", forwarding.CfsRuleUpdate{Name: "extra", ForwardToPhone: "9000000009"})
+	steps = append(steps, err)
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("Не удалось сбросить настройки абонента: %s", err)
+		}
+	}
+}
+
+// TestRestore Тест на снятие снимка настроек и восстановление из него
+func TestRestore(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	s.AddAbonent(abonents.Abonent{UserId: "u2"})
+	c, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	configure(t, c)
+	svc := snapshot.New(c)
+
+	snap, err := svc.Take(context.Background())
+	if err != nil {
+		t.Fatalf("Не удалось снять снимок настроек: %s", err)
+	}
+	if len(snap.Abonents) != 2 || snap.Version != snapshot.Version {
+		t.Fatalf("Неверный снимок настроек: %+v", snap)
+	}
+	var buf bytes.Buffer
+	if err := snap.Write(&buf); err != nil {
+		t.Fatalf("Не удалось записать снимок: %s", err)
+	}
+	if snap, err = snapshot.Read(&buf); err != nil {
+		t.Fatalf("Не удалось прочитать снимок: %s", err)
+	}
+
+	reset(t, c)
+	if rep := svc.Restore(context.Background(), snap); rep.Err() != nil {
+		t.Fatalf("Не удалось восстановить настройки: %s", rep.Err())
+	}
+	got, err := svc.Take(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("Не удалось снять снимок настроек: %s", err)
+	}
+	a := got.Abonents[0]
+	want, _ := snap.Find("u1")
+	if a.Recording != beelineapi.ON || a.AgentStatus != beelineapi.BREAK || a.Forwarding != want.Forwarding {
+		t.Fatalf("Статусы не восстановлены: %+v", a)
+	}
+	if !a.SelectiveForwarding.IsCfsServiceEnabled || len(a.SelectiveForwarding.RuleList) != 1 || a.SelectiveForwarding.RuleList[0].Name != "night" {
+		t.Fatalf("Выборочная переадресация не восстановлена: %+v", a.SelectiveForwarding)
+	}
+	if a.Bwl.Status != bwl.WHITE_LIST_ON || len(a.Bwl.WhiteList) != 1 || a.Bwl.WhiteList[0].PhoneList[0] != "9000000001" {
+		t.Fatalf("Выборочный прием звонков не восстановлен: %+v", a.Bwl)
+	}
+}
+
+// TestRestoreError Тест на отчет об ошибках восстановления
+func TestRestoreError(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := snapshot.New(client)
+	snap, err := svc.Take(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("Не удалось снять снимок настроек: %s", err)
+	}
+	snap.Abonents = append(snap.Abonents, snapshot.Abonent{Abonent: abonents.Abonent{UserId: "gone"}})
+	rep := svc.Restore(context.Background(), snap)
+	if len(rep.Failed()) != 1 || rep.Failed()[0].Abonent.UserId != "gone" || !strings.Contains(rep.Err().Error(), "1 из 2") {
+		t.Fatalf("Неверный отчет о восстановлении: %+v %v", rep, rep.Err())
+	}
+	if _, err := svc.Take(context.Background(), "gone"); err == nil {
+		t.Fatal("Ожидалась ошибка для неизвестного абонента")
+	}
+}
+
+// TestReadVersion Тест на проверку версии снимка
+func TestReadVersion(t *testing.T) {
+	for _, doc := range []string{`{"abonents": []}`, `{"version": 99, "abonents": []}`, `[]`} {
+		if _, err := snapshot.Read(strings.NewReader(doc)); err == nil {
+			t.Fatalf("Ожидалась ошибка для снимка %s", doc)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package beelineapi
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource Источник ключа безопасности X-MPBX-API-AUTH-TOKEN.
+// Клиент API запрашивает ключ перед каждым запросом, поэтому ключ можно менять без перезапуска сервиса.
+type TokenSource interface {
+	// Token Возвращает текущий ключ безопасности
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenRefresher Источник ключа, который может перечитать ключ принудительно.
+// Клиент API вызывает Refresh, если сервер ответил 401 на запрос с текущим ключом.
+type TokenRefresher interface {
+	TokenSource
+	// Refresh Перечитывает ключ безопасности
+	Refresh(ctx context.Context) error
+}
+
+// StaticToken Ключ безопасности, который не меняется
+type StaticToken string
+
+// Token Возвращает ключ
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	if t == "" {
+		return "", WrapError{Msg: "Не указан ключ безопасности"}
+	}
+	return string(t), nil
+}
+
+// EnvToken Ключ безопасности из переменной окружения с указанным именем, которая читается перед каждым запросом
+type EnvToken string
+
+// Token Возвращает значение переменной окружения
+func (t EnvToken) Token(ctx context.Context) (string, error) {
+	token := os.Getenv(string(t))
+	if token == "" {
+		return "", WrapError{Msg: "Не задана переменная окружения " + string(t) + " с ключом безопасности"}
+	}
+	return token, nil
+}
+
+// TokenFunc Позволяет использовать функцию как TokenSource, например для получения ключа из хранилища секретов
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Token Вызывает f
+func (f TokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// FileToken Ключ безопасности из файла. Файл перечитывается, когда меняется его время изменения или размер,
+// поэтому для смены ключа достаточно перезаписать файл. Пробельные символы в начале и конце файла отбрасываются.
+// Создается функцией NewFileToken.
+type FileToken struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileToken Возвращает источник ключа безопасности из файла path
+func NewFileToken(path string) *FileToken {
+	return &FileToken{path: path}
+}
+
+// Token Возвращает ключ из файла, перечитывая файл, если он изменился
+func (f *FileToken) Token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return "", Wrap("Ошибка при чтении файла с ключом безопасности. ", err)
+	}
+	if f.token != "" && fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return f.token, nil
+	}
+	if err := f.load(fi); err != nil {
+		return "", err
+	}
+	return f.token, nil
+}
+
+// Refresh Перечитывает файл, даже если его время изменения и размер не поменялись
+func (f *FileToken) Refresh(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return Wrap("Ошибка при чтении файла с ключом безопасности. ", err)
+	}
+	return f.load(fi)
+}
+
+// load Читает ключ из файла с информацией fi
+func (f *FileToken) load(fi os.FileInfo) error {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return Wrap("Ошибка при чтении файла с ключом безопасности. ", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return WrapError{Msg: "Файл " + f.path + " с ключом безопасности пуст"}
+	}
+	f.token, f.modTime, f.size = token, fi.ModTime(), fi.Size()
+	return nil
+}
+
+// WithTokenSource Задает источник ключа безопасности, который запрашивается перед каждым запросом.
+// Если сервер ответил 401, клиент один раз перечитывает ключ (TokenRefresher.Refresh, если источник его поддерживает)
+// и повторяет запрос, если ключ изменился. При заданном источнике ключ token в NewClient можно не указывать.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *APIClient) error {
+		if ts == nil {
+			return WrapError{Msg: "Не указан источник ключа безопасности"}
+		}
+		c.tokens = ts
+		return nil
+	}
+}
+
+// tokenState Последний ключ безопасности, полученный из источника, для скрытия в журнале
+type tokenState struct {
+	mu   sync.Mutex
+	last string
+}
+
+// token Возвращает ключ безопасности для очередного запроса
+func (c *APIClient) token(ctx context.Context) (string, error) {
+	if c.tokens == nil {
+		return c.Token, nil
+	}
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return "", Wrap("Ошибка при получении ключа безопасности. ", err)
+	}
+	c.auth.mu.Lock()
+	c.auth.last = token
+	c.auth.mu.Unlock()
+	return token, nil
+}
+
+// refreshToken Перечитывает ключ после ответа 401 на запрос с ключом used
+// и возвращает новый ключ, если он отличается от used
+func (c *APIClient) refreshToken(ctx context.Context, used string) (string, bool) {
+	if c.tokens == nil {
+		return "", false
+	}
+	if r, ok := c.tokens.(TokenRefresher); ok {
+		if err := r.Refresh(ctx); err != nil {
+			c.logf("Не удалось перечитать ключ безопасности: %s", err)
+			return "", false
+		}
+	}
+	token, err := c.token(ctx)
+	if err != nil || token == used {
+		return "", false
+	}
+	return token, true
+}
+
+// secrets Возвращает ключи безопасности, которые необходимо скрывать в журнале
+func (c *APIClient) secrets() []string {
+	res := []string{}
+	if c.Token != "" {
+		res = append(res, c.Token)
+	}
+	if c.auth != nil {
+		c.auth.mu.Lock()
+		if c.auth.last != "" && c.auth.last != c.Token {
+			res = append(res, c.auth.last)
+		}
+		c.auth.mu.Unlock()
+	}
+	return res
+}
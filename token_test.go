@@ -0,0 +1,112 @@
+package beelineapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// tokenServer Возвращает сервер, принимающий только ключ безопасности из *valid, и список полученных ключей
+func tokenServer(t *testing.T, valid *string) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		token := r.Header.Get("X-MPBX-API-AUTH-TOKEN")
+		got = append(got, token)
+		if token != *valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+// TestTokenSource Тест на получение ключа безопасности перед каждым запросом
+func TestTokenSource(t *testing.T) {
+	valid := "t1"
+	srv, got := tokenServer(t, &valid)
+	current := "t1"
+	c, err := NewClient("", WithBaseURL(srv.URL), WithTokenSource(TokenFunc(func(ctx context.Context) (string, error) {
+		return current, nil
+	})))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	if _, err := c.Request("GET", "abonents", nil); err != nil {
+		t.Fatalf("Запрос не выполнен: %s", err)
+	}
+	current, valid = "t2", "t2"
+	if _, err := c.Request("GET", "abonents", nil); err != nil {
+		t.Fatalf("Запрос с новым ключом не выполнен: %s", err)
+	}
+	if len(*got) != 2 || (*got)[0] != "t1" || (*got)[1] != "t2" {
+		t.Fatalf("Неверные ключи в запросах: %v", *got)
+	}
+	if _, err := NewClient("", WithTokenSource(nil)); err == nil {
+		t.Fatalf("Ожидалась ошибка для пустого источника ключа")
+	}
+}
+
+// TestTokenRefresh Тест на повтор запроса с перечитанным ключом после ответа 401
+func TestTokenRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	write := func(token string) {
+		if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			t.Fatalf("Не удалось записать файл с ключом: %s", err)
+		}
+	}
+	write("old")
+	valid := "old"
+	srv, got := tokenServer(t, &valid)
+	c, err := NewClient("", WithBaseURL(srv.URL), WithTokenSource(NewFileToken(path)))
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+
+	t.Run("rotated", func(t *testing.T) {
+		if _, err := c.Request("GET", "abonents", nil); err != nil {
+			t.Fatalf("Запрос не выполнен: %s", err)
+		}
+		// Ключ той же длины, записанный в ту же секунду, не меняет размер файла и может не поменять время изменения,
+		// поэтому новый ключ будет прочитан только после ответа 401
+		write("new")
+		valid = "new"
+		*got = nil
+		if _, err := c.Request("POST", "abonents/u1/call", nil); err != nil {
+			t.Fatalf("Запрос после смены ключа не выполнен: %s", err)
+		}
+		if last := (*got)[len(*got)-1]; last != "new" {
+			t.Fatalf("Запрос должен быть повторен с новым ключом: %v", *got)
+		}
+	})
+	t.Run("revoked", func(t *testing.T) {
+		valid = "other"
+		*got = nil
+		_, err := c.Request("GET", "abonents", nil)
+		if se, ok := err.(StatusError); !ok || se.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Ожидалась ошибка 401, получено %v", err)
+		}
+		if len(*got) != 1 {
+			t.Fatalf("Запрос с неизменившимся ключом не должен повторяться: %v", *got)
+		}
+	})
+}
+
+// TestEnvToken Тест на ключ безопасности из переменной окружения
+func TestEnvToken(t *testing.T) {
+	t.Setenv("BEELINE_TEST_TOKEN", "env")
+	if token, err := EnvToken("BEELINE_TEST_TOKEN").Token(context.Background()); err != nil || token != "env" {
+		t.Fatalf("Неверный ключ из переменной окружения: %q %v", token, err)
+	}
+	if _, err := EnvToken("BEELINE_TEST_MISSING").Token(context.Background()); err == nil {
+		t.Fatalf("Ожидалась ошибка для незаданной переменной окружения")
+	}
+}
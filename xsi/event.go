@@ -0,0 +1,56 @@
+package xsi
+
+import (
+	"encoding/xml"
+	"strings"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// Event Событие Xsi-Events, которое портал отправляет на URL приложения из подписки
+type Event struct {
+	EventId        string // Идентификатор события
+	SequenceNumber int    // Порядковый номер события в подписке
+	UserId         string // Идентификатор абонента
+	SubscriptionId string // Идентификатор подписки, по которой отправлено событие
+	TargetId       string // Идентификатор объекта подписки
+	EventType      string // Тип события без префикса пространства имен, например CallReceivedEvent
+	Raw            []byte // Исходный XML события
+}
+
+// xmlEvent Разметка XML события Xsi-Events. Элементы сопоставляются по имени без учета пространства имен
+type xmlEvent struct {
+	EventId        string `xml:"eventID"`
+	SequenceNumber int    `xml:"sequenceNumber"`
+	UserId         string `xml:"userId"`
+	SubscriptionId string `xml:"subscriptionId"`
+	TargetId       string `xml:"targetId"`
+	EventData      struct {
+		Type string `xml:"type,attr"`
+	} `xml:"eventData"`
+}
+
+// ParseEvent Разбирает событие Xsi-Events в формате XML
+// data - Тело запроса портала к URL приложения
+func ParseEvent(data []byte) (Event, error) {
+	var x xmlEvent
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return Event{}, beelineapi.Wrap("Ошибка при разборе события Xsi-Events. ", err)
+	}
+	if x.SubscriptionId == "" {
+		return Event{}, beelineapi.WrapError{Msg: "В событии Xsi-Events не указан идентификатор подписки"}
+	}
+	eventType := x.EventData.Type
+	if i := strings.LastIndex(eventType, ":"); i >= 0 {
+		eventType = eventType[i+1:]
+	}
+	return Event{
+		EventId:        x.EventId,
+		SequenceNumber: x.SequenceNumber,
+		UserId:         x.UserId,
+		SubscriptionId: x.SubscriptionId,
+		TargetId:       x.TargetId,
+		EventType:      eventType,
+		Raw:            data,
+	}, nil
+}
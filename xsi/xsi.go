@@ -0,0 +1,74 @@
+// Package xsi содержит операции с подпиской на Xsi-Events облачной АТС Билайн.
+// Подписка может быть использована для интеграции со сторонними системами, которым необходим контроль над звонками абонентов облачной АТС в реальном времени.
+// API использует механизм подписки на события, ассоциированные с тем или иным абонентом, номером или всем клиентом.
+// Например, Абонент облачной АТС принимает вызов, сторонняя CRM система получает обновления о текущем статусе вызова (ringing, established, completed).
+package xsi
+
+import (
+	"net/url"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+)
+
+// SubscriptionRequest Запрос для подписки на события
+type SubscriptionRequest struct {
+	Pattern          string                      `json:"pattern"`          //Идентификатор, входящий или добавочный номер абонента или номера
+	Expires          int                         `json:"expires"`          //Длительность подписки
+	SubscriptionType beelineapi.SubscriptionType `json:"subscriptionType"` // Тип подписки = [BASIC_CALL (Базовая информация о вызове), ADVANCED_CALL (Расширеная информация о вызове)]
+	Url              string                      `json:"url"`
+}
+
+// SubscriptionResult Результат подписки на события
+type SubscriptionResult struct {
+	SubscriptionId string `json:"subscriptionId"` //Идентификатор подписки
+	Expires        int    `json:"expires"`        //Длительность подписки
+}
+
+// SubscriptionInfo Информация о подписке на события
+type SubscriptionInfo struct {
+	SubscriptionId   string                      `json:"subscriptionId"`   //Идентификатор подписки
+	TargetType       beelineapi.TargetType       `json:"targetType"`       //Тип объекта, для которого сформирована подписка
+	TargetId         string                      `json:"targetId"`         //Идентификатор объекта, для которого сформирована подписка
+	SubscriptionType beelineapi.SubscriptionType `json:"subscriptionType"` //Тип подписки = [BASIC_CALL (Базовая информация о вызове), ADVANCED_CALL (Расширеная информация о вызове)]
+	Expires          int                         `json:"expires"`          //Длительность подписки
+	Url              string                      `json:"url"`              //URL приложения
+}
+
+// Service Операции с подпиской на Xsi-Events
+type Service struct {
+	c *beelineapi.APIClient
+}
+
+// New Возвращает операции с подпиской на Xsi-Events для клиента c
+func New(c *beelineapi.APIClient) *Service {
+	return &Service{c: c}
+}
+
+// XSIEventSubscription Формирует подписку на Xsi-Events
+// req - Запрос для подписки на события
+func (s *Service) XSIEventSubscription(req SubscriptionRequest) (SubscriptionResult, error) {
+	res := SubscriptionResult{}
+	if err := s.c.RequestJSON("POST", "subscription", req, &res); err != nil {
+		return res, beelineapi.Wrap("Ошибка при подписке на Xsi-Events. ", err)
+	}
+	return res, nil
+}
+
+// GetXSIEventSubscriptionInfo Возвращает информацию о подписке на Xsi-Events
+// id - Идентификатор подписки
+func (s *Service) GetXSIEventSubscriptionInfo(id string) (SubscriptionInfo, error) {
+	info := SubscriptionInfo{}
+	if err := s.c.RequestJSON("GET", "subscription/"+url.PathEscape(id), nil, &info); err != nil {
+		return info, beelineapi.Wrap("Ошибка при получении информации о подписке на Xsi-Events. ", err)
+	}
+	return info, nil
+}
+
+// TurnOffXSIEventSubscription Отключает подписку на Xsi-Events
+// id - Идентификатор отключаемой подписки
+func (s *Service) TurnOffXSIEventSubscription(id string) error {
+	if _, err := s.c.Request("DELETE", "subscription/"+url.PathEscape(id), nil); err != nil {
+		return beelineapi.Wrap("Ошибка при отключении подписки на Xsi-Events. ", err)
+	}
+	return nil
+}
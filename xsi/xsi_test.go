@@ -0,0 +1,87 @@
+package xsi_test
+
+import (
+	"testing"
+
+	beelineapi "github.com/taigasys/beeline-portal-api"
+	"github.com/taigasys/beeline-portal-api/abonents"
+	"github.com/taigasys/beeline-portal-api/beelinetest"
+	"github.com/taigasys/beeline-portal-api/xsi"
+)
+
+// TestSubscription Тест на подписку на Xsi-Events
+func TestSubscription(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Extension: "101"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := xsi.New(client)
+
+	res, err := svc.XSIEventSubscription(xsi.SubscriptionRequest{Pattern: "101", Expires: 3600, Url: "https://crm.example/events"})
+	if err != nil {
+		t.Fatalf("Не удалось подписаться на события: %s", err)
+	}
+	info, err := svc.GetXSIEventSubscriptionInfo(res.SubscriptionId)
+	if err != nil || info.TargetType != beelineapi.ABONENT || info.TargetId != "u1" || info.Expires != 3600 {
+		t.Fatalf("Неверная информация о подписке: %v %+v", err, info)
+	}
+	if err := svc.TurnOffXSIEventSubscription(res.SubscriptionId); err != nil {
+		t.Fatalf("Не удалось отключить подписку: %s", err)
+	}
+	if _, err := svc.GetXSIEventSubscriptionInfo(res.SubscriptionId); !beelineapi.IsNotFound(err) {
+		t.Fatalf("Ожидалась ошибка 404, получено %v", err)
+	}
+}
+
+// TestEndpoints Тест на успешный ответ и ошибку сервера для каждой операции с подписками Xsi-Events
+func TestEndpoints(t *testing.T) {
+	s := beelinetest.NewServer("token")
+	defer s.Close()
+	s.AddAbonent(abonents.Abonent{UserId: "u1", Extension: "101"})
+	client, err := s.Client()
+	if err != nil {
+		t.Fatalf("Не удалось создать клиента: %s", err)
+	}
+	svc := xsi.New(client)
+	req := xsi.SubscriptionRequest{Pattern: "101", Expires: 3600, Url: "https://crm.example/events"}
+	res, err := svc.XSIEventSubscription(req)
+	if err != nil {
+		t.Fatalf("Не удалось подписаться на события: %s", err)
+	}
+
+	if err := s.CheckEndpoints([]beelinetest.Endpoint{
+		{Name: "XSIEventSubscription", Method: "POST", Path: "/subscription", Call: func() error { _, err := svc.XSIEventSubscription(req); return err }},
+		{Name: "GetXSIEventSubscriptionInfo", Method: "GET", Path: "/subscription/" + res.SubscriptionId, Call: func() error {
+			_, err := svc.GetXSIEventSubscriptionInfo(res.SubscriptionId)
+			return err
+		}},
+		{Name: "TurnOffXSIEventSubscription", Method: "DELETE", Path: "/subscription/" + res.SubscriptionId, Call: func() error {
+			return svc.TurnOffXSIEventSubscription(res.SubscriptionId)
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestParseEvent Тест на разбор события Xsi-Events
+func TestParseEvent(t *testing.T) {
+	data := []byte(`<xsi:Event xmlns:xsi="http://schema.broadsoft.com/xsi" xmlns:xsi1="http://www.w3.org/2001/XMLSchema-instance">
+<xsi:eventID>e1</xsi:eventID><xsi:sequenceNumber>7</xsi:sequenceNumber><xsi:userId>u1</xsi:userId>
+<xsi:subscriptionId>s1</xsi:subscriptionId><xsi:targetId>101</xsi:targetId>
+<xsi:eventData xsi1:type="xsi:CallAnsweredEvent"></xsi:eventData></xsi:Event>`)
+	ev, err := xsi.ParseEvent(data)
+	if err != nil {
+		t.Fatalf("Не удалось разобрать событие: %s", err)
+	}
+	if ev.EventId != "e1" || ev.SequenceNumber != 7 || ev.UserId != "u1" || ev.SubscriptionId != "s1" || ev.TargetId != "101" || ev.EventType != "CallAnsweredEvent" {
+		t.Fatalf("Неверно разобрано событие: %+v", ev)
+	}
+	for _, bad := range []string{"not xml", "<xsi:Event xmlns:xsi=\"x\"></xsi:Event>"} {
+		if _, err := xsi.ParseEvent([]byte(bad)); err == nil {
+			t.Fatalf("Ожидалась ошибка разбора события %q", bad)
+		}
+	}
+}